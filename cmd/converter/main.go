@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 
 	"github.com/mourice12/hl7-to-fhir/internal/converter"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"github.com/mourice12/hl7-to-fhir/internal/mapping"
+	"github.com/mourice12/hl7-to-fhir/internal/profile"
+	"github.com/mourice12/hl7-to-fhir/pkg/convert"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
 func main() {
@@ -15,8 +20,23 @@ func main() {
 
 	inputFile := flag.String("input", "", "input HL7FilePath")
 	outputFile := flag.String("output", "", "Output FHIR JSON File Path")
+	idStrategy := flag.String("id-strategy", "hash", "Resource ID strategy: hash, uuid or source")
+	bundleType := flag.String("bundle-type", "transaction", "Bundle type: transaction or message")
+	includeSource := flag.Bool("include-source", false, "Store the raw HL7 message as a Binary referenced by the Provenance")
+	mappingDir := flag.String("mappings", "", "Directory of JSON mapping files applied over the defaults, or \"default\" for the built-in mappings")
+	profileDir := flag.String("profiles", "", "Directory of per-sender profile JSON files")
+	profileName := flag.String("profile", "", "Profile to use instead of selecting one by MSH-3/MSH-4")
+	outcomeFile := flag.String("outcome", "", "Write the conversion issues as an OperationOutcome to this file")
+	lineageFile := flag.String("lineage", "", "Write a side-by-side report of each FHIR element and the HL7 field it came from to this file")
+	mode := flag.String("mode", converter.ModeLenient, "Conversion mode: lenient converts best-effort, strict fails on any issue")
+	version := flag.Bool("version", false, "Print the version and exit")
 	flag.Parse()
 
+	if *version {
+		fmt.Println(convert.Version)
+		return
+	}
+
 	//validate input
 	if *inputFile == "" {
 		fmt.Println("Usage: converter input <file.hl7> [-output <file.json]")
@@ -37,18 +57,79 @@ func main() {
 		os.Exit(1)
 	}
 
-	//Convert to bundle instead of just patient
-	bundle, err := converter.ConvertToBundle(msg)
+	//Resource ID strategy
+	opts := converter.DefaultConvertOptions()
+	opts.IDs, err = converter.IDStrategyByName(*idStrategy)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts.BundleType = *bundleType
+	opts.IncludeSourceMessage = *includeSource
+
+	//Declarative mappings
+	switch *mappingDir {
+	case "":
+	case "default":
+		opts.Mappings, err = mapping.Defaults()
+	default:
+		opts.Mappings, err = mapping.LoadDirOverDefaults(*mappingDir)
+	}
 	if err != nil {
-		fmt.Printf("Error converting: %v\n", err)
+		fmt.Printf("Error loading mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	//Per-sender profiles
+	if *profileDir != "" {
+		opts.Profiles, err = profile.LoadDir(*profileDir)
+		if err != nil {
+			fmt.Printf("Error loading profiles: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	opts.Profile = *profileName
+	opts.Mode = *mode
+
+	//Convert to bundle instead of just patient, recording where each element came from for the lineage report
+	var bundle *fhir.Bundle
+	var lineage []converter.Lineage
+	var issues []converter.Issue
+	var convertErr error
+	if *lineageFile != "" {
+		bundle, lineage, issues, convertErr = converter.ConvertToBundleWithLineage(msg, opts)
+	} else {
+		bundle, issues, convertErr = converter.ConvertToBundleWithIssues(msg, opts)
+	}
+
+	//Conversion issues go to stderr, and to an OperationOutcome file on request, also when strict mode fails
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "%s %s %s: %s\n", issue.Severity, issue.Code, issue.Location, issue.Message)
+	}
+	if *outcomeFile != "" {
+		outcome, err := json.MarshalIndent(converter.ToOperationOutcome(issues), "", " ")
+		if err == nil {
+			err = os.WriteFile(*outcomeFile, outcome, 0644)
+		}
+		if err != nil {
+			fmt.Printf("Error writing outcome: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if convertErr != nil {
+		fmt.Printf("Error converting: %v\n", convertErr)
 		os.Exit(1)
 	}
-	//convert to FHIR
-	//patient, err := converter.ConvertToPatient(msg)
-	//if err != nil {
-	//	fmt.Printf("Error converting JSON: %v\n", err)
-	///	os.Exit(1)
-	//}
+
+	//Lineage report
+	if *lineageFile != "" {
+		var report bytes.Buffer
+		converter.WriteLineageReport(&report, lineage)
+		if err := os.WriteFile(*lineageFile, report.Bytes(), 0644); err != nil {
+			fmt.Printf("Error writing lineage: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	//Marshal JSON
 	jsonBytes, err := json.MarshalIndent(bundle, "", " ")
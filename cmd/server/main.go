@@ -1,23 +1,132 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/mourice12/hl7-to-fhir/internal/converter"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"github.com/mourice12/hl7-to-fhir/internal/mapping"
+	"github.com/mourice12/hl7-to-fhir/internal/mllp"
+	"github.com/mourice12/hl7-to-fhir/internal/profile"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
+// options are the conversion settings for this deployment
+var options = converter.DefaultConvertOptions()
+
+// mappings holds the declarative mappings in use, swapped on reload
+var mappings atomic.Pointer[mapping.Set]
+
+// limits bound the messages the server accepts
+var limits = hl7.DefaultLimits()
+
+// timeout bounds how long one message may take to parse and convert
+var timeout = 10 * time.Second
+
 func main() {
+	idStrategy := flag.String("id-strategy", "hash", "Resource ID strategy: hash, uuid or source")
+	bundleType := flag.String("bundle-type", "transaction", "Bundle type: transaction or message")
+	includeSource := flag.Bool("include-source", false, "Store the raw HL7 message as a Binary referenced by the Provenance")
+	mappingDir := flag.String("mappings", "", "Directory of JSON mapping files applied over the defaults, or \"default\" for the built-in mappings")
+	reloadInterval := flag.Duration("mappings-reload", 5*time.Second, "How often to check the mapping directory for changes")
+	profileDir := flag.String("profiles", "", "Directory of per-sender profile JSON files")
+	mllpAddr := flag.String("mllp", "", "Address for the MLLP listener, e.g. :2575; empty disables it")
+	mllpOut := flag.String("mllp-out", "", "Directory the MLLP listener writes converted bundles to")
+	mode := flag.String("mode", converter.ModeLenient, "Default conversion mode: lenient or strict; ?mode= overrides it per request")
+	flag.IntVar(&limits.MaxMessageSize, "max-size", limits.MaxMessageSize, "Maximum message size in bytes; 0 disables the limit")
+	flag.IntVar(&limits.MaxSegments, "max-segments", limits.MaxSegments, "Maximum segments per message; 0 disables the limit")
+	flag.IntVar(&limits.MaxFieldLength, "max-field-length", limits.MaxFieldLength, "Maximum field length in bytes; 0 disables the limit")
+	flag.IntVar(&limits.MaxRepetitions, "max-repetitions", limits.MaxRepetitions, "Maximum repetitions per field; 0 disables the limit")
+	flag.DurationVar(&timeout, "timeout", timeout, "Maximum time to read, parse and convert one message")
+	flag.Parse()
+
+	strategy, err := converter.IDStrategyByName(*idStrategy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	options.IDs = strategy
+	options.BundleType = *bundleType
+	options.IncludeSourceMessage = *includeSource
+	options.Mode = *mode
+	if *mode != converter.ModeLenient && *mode != converter.ModeStrict {
+		log.Fatalf("unknown conversion mode %q", *mode)
+	}
+
+	//Declarative mappings, reloaded when the files change
+	switch *mappingDir {
+	case "":
+	case "default":
+		set, err := mapping.Defaults()
+		if err != nil {
+			log.Fatal(err)
+		}
+		mappings.Store(set)
+	default:
+		set, err := mapping.LoadDirOverDefaults(*mappingDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mappings.Store(set)
+		mapping.Watch(*mappingDir, *reloadInterval, func(set *mapping.Set, err error) {
+			if err != nil {
+				log.Printf("Keeping previous mappings: %v", err)
+				return
+			}
+			mappings.Store(set)
+			log.Printf("Reloaded mappings from %s", *mappingDir)
+		})
+	}
+
+	//Per-sender profiles
+	if *profileDir != "" {
+		profiles, err := profile.LoadDir(*profileDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, p := range profiles.Profiles {
+			if _, err := converter.IDStrategyByName(p.IDStrategy); p.IDStrategy != "" && err != nil {
+				log.Fatalf("profile %s: %v", p.Name, err)
+			}
+		}
+		options.Profiles = profiles
+		log.Printf("Loaded %d profiles from %s", len(profiles.Profiles), *profileDir)
+	}
+
+	//MLLP listener
+	if *mllpAddr != "" {
+		server := &mllp.Server{
+			Addr:           *mllpAddr,
+			Handler:        mllpHandler(*mllpOut),
+			MaxMessageSize: limits.MaxMessageSize,
+			ReadTimeout:    timeout,
+		}
+		go func() {
+			log.Fatal(server.ListenAndServe())
+		}()
+		fmt.Printf("MLLP listener starting on %s\n", *mllpAddr)
+	}
+
 	http.HandleFunc("/convert", handleConvert)
 	http.HandleFunc("/health", handleHealth)
 
 	port := ":8000"
+	server := &http.Server{Addr: port, ReadHeaderTimeout: timeout, ReadTimeout: timeout}
 	fmt.Printf("Server starting on %s\n", port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	log.Fatal(server.ListenAndServe())
 }
 
 // handleConvert processes hl7 to FHIR conversion
@@ -28,9 +137,19 @@ func handleConvert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Read the HL7 Message
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
 
+	//Read the HL7 Message
+	if limits.MaxMessageSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(limits.MaxMessageSize))
+	}
 	body, err := io.ReadAll(r.Body)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, fmt.Sprintf("%v: limit %d bytes", hl7.ErrTooLarge, maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error reading request body", http.StatusBadRequest)
 		return
@@ -38,25 +157,153 @@ func handleConvert(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	//parse HL7
-	msg, err := hl7.Parse(string(body))
+	msg, err := hl7.ParseContext(ctx, string(body), limits)
 	if err != nil {
-		http.Error(w, "Error parsing HL7: "+err.Error(), http.StatusBadRequest)
+		if !writeLimitError(w, err) {
+			http.Error(w, "Error parsing HL7: "+err.Error(), http.StatusBadRequest)
+		}
 		return
 	}
 
-	//Convert to FHIR bundle
-	bundle, err := converter.ConvertToBundle(msg)
+	//Convert to FHIR bundle, with the profile named by ?profile= and the mode named by ?mode= if any
+	bundle, issues, err := convert(ctx, msg, r.URL.Query().Get("profile"), r.URL.Query().Get("mode"))
+	var strictErr *converter.StrictError
+	if errors.As(err, &strictErr) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.Header().Set("X-Conversion-Issues", strconv.Itoa(len(issues)))
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(converter.ToOperationOutcome(strictErr.Issues))
+		return
+	}
+	if errors.Is(err, converter.ErrUnsupportedMessageType) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, converter.ErrUnknownProfile) || errors.Is(err, converter.ErrUnknownMode) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if writeLimitError(w, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error Converting: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	//Issue count in a header; ?outcome=true adds the OperationOutcome as a second part
+	w.Header().Set("X-Conversion-Issues", strconv.Itoa(len(issues)))
+	if r.URL.Query().Get("outcome") == "true" {
+		writeMultipart(w, bundle, converter.ToOperationOutcome(issues))
+		return
+	}
+
 	//return JSON response
 	w.Header().Set("Content-Type", "application/fhir+json")
 	json.NewEncoder(w).Encode(bundle)
 
 }
 
+// writeLimitError answers a message over the limits with 413 and one that ran out of time with 503;
+// it reports whether err was one of those, or a cancellation by the client
+func writeLimitError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, hl7.ErrTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, hl7.ErrTimeout):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, context.Canceled):
+		log.Printf("Request cancelled: %v", err)
+	default:
+		return false
+	}
+	return true
+}
+
+// writeMultipart writes FHIR resources as the parts of a multipart/mixed response
+func writeMultipart(w http.ResponseWriter, resources ...interface{}) {
+	writer := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+
+	for _, resource := range resources {
+		part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/fhir+json"}})
+		if err != nil {
+			log.Printf("Error writing response: %v", err)
+			return
+		}
+		json.NewEncoder(part).Encode(resource)
+	}
+	writer.Close()
+}
+
+// convert converts a message with the deployment options, the current mappings, an optional forced profile
+// and an optional mode overriding the deployment's
+func convert(ctx context.Context, msg *hl7.Message, profileName, mode string) (*fhir.Bundle, []converter.Issue, error) {
+	opts := options
+	opts.Mappings = mappings.Load()
+	opts.Profile = profileName
+	if mode != "" {
+		opts.Mode = mode
+	}
+	return converter.ConvertToBundleContext(ctx, msg, opts)
+}
+
+// mllpHandler converts messages received over MLLP, writing each bundle to outDir when set
+func mllpHandler(outDir string) mllp.Handler {
+	return func(raw string) string {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		msg, err := hl7.ParseContext(ctx, raw, limits)
+		if errors.Is(err, hl7.ErrTimeout) {
+			log.Printf("MLLP: %v", err)
+			return mllp.Ack(nil, mllp.AckError, err.Error())
+		}
+		if err != nil {
+			log.Printf("MLLP: error parsing HL7: %v", err)
+			return mllp.Ack(nil, mllp.AckReject, "Error parsing HL7: "+err.Error())
+		}
+
+		bundle, issues, err := convert(ctx, msg, "", "")
+		var strictErr *converter.StrictError
+		if errors.As(err, &strictErr) {
+			log.Printf("MLLP: %v", err)
+			return mllp.Ack(msg, mllp.AckError, err.Error())
+		}
+		if errors.Is(err, converter.ErrUnsupportedMessageType) {
+			return mllp.Ack(msg, mllp.AckReject, err.Error())
+		}
+		if errors.Is(err, hl7.ErrTimeout) {
+			log.Printf("MLLP: %v", err)
+			return mllp.Ack(msg, mllp.AckError, err.Error())
+		}
+		if err != nil {
+			log.Printf("MLLP: error converting: %v", err)
+			return mllp.Ack(msg, mllp.AckError, "Error Converting: "+err.Error())
+		}
+
+		if outDir != "" {
+			name := filepath.Base(msg.GetSegment("MSH").GetField(10).GetCompontent(1))
+			if name == "." || name == string(filepath.Separator) {
+				name = time.Now().UTC().Format("20060102150405.000000000")
+			}
+			data, err := json.MarshalIndent(bundle, "", " ")
+			if err == nil {
+				err = os.WriteFile(filepath.Join(outDir, name+".json"), data, 0644)
+			}
+			if err != nil {
+				log.Printf("MLLP: error writing bundle: %v", err)
+				return mllp.Ack(msg, mllp.AckError, "Error writing bundle: "+err.Error())
+			}
+		}
+
+		if len(issues) > 0 {
+			log.Printf("MLLP: converted with %d issues", len(issues))
+		}
+		return mllp.Ack(msg, mllp.AckAccept, "")
+	}
+}
+
 // handleHealth returns server status
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
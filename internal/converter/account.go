@@ -0,0 +1,69 @@
+package converter
+
+import (
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConvertToAccount converts the PID-18 patient account number to a FHIR Account
+func ConvertToAccount(cc *ConversionContext) (*fhir.Account, error) {
+	msg := cc.Message
+	pid := msg.GetSegment("PID")
+	if pid == nil {
+		return nil, nil
+	}
+
+	accountField := pid.GetField(18)
+	number := accountField.GetCompontent(1)
+	if number == "" {
+		return nil, nil
+	}
+
+	identifier := fhir.Identifier{
+		Value: number,
+		Type: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{
+				System: "http://terminology.hl7.org/CodeSystem/v2-0203",
+				Code:   "AN",
+			}},
+		},
+	}
+
+	//PID-18.4 Assigning Authority
+	applyAssigningAuthority(&identifier, accountField.Repetitions[0], 4)
+
+	account := &fhir.Account{
+		ResourceType: "Account",
+		ID:           accountID(pid),
+		Identifier:   []fhir.Identifier{identifier},
+		Status:       "active",
+	}
+	if subject := cc.PatientReference(); subject != nil {
+		account.Subject = []fhir.Reference{*subject}
+	}
+	cc.RecordSegment(account, pid)
+	return account, nil
+}
+
+// accountID builds the Account ID from PID-18, or returns "" when there is no account number
+func accountID(pid *hl7.Segment) string {
+	number := pid.GetField(18).GetCompontent(1)
+	if number == "" {
+		return ""
+	}
+	return "account-" + number
+}
+
+// accountReferences returns a reference to the PID-18 Account, if any
+func accountReferences(msg *hl7.Message) []fhir.Reference {
+	pid := msg.GetSegment("PID")
+	if pid == nil {
+		return nil
+	}
+
+	id := accountID(pid)
+	if id == "" {
+		return nil
+	}
+	return []fhir.Reference{{Reference: "Account/" + id}}
+}
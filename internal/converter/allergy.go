@@ -1,12 +1,13 @@
 package converter
 
 import (
-	"github.com/mourice12/hl7-to-fhir/internal/fhir"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
 // ConvertToAllergies converts AL1 segments to FHIR AllergyIntolerance
-func ConvertToAllergies(msg *hl7.Message, patientID string) ([]*fhir.AllergyIntolerance, error) {
+func ConvertToAllergies(cc *ConversionContext) ([]*fhir.AllergyIntolerance, error) {
+	msg := cc.Message
 	var allergies []*fhir.AllergyIntolerance
 
 	allSegments := msg.GetSegments("AL1")
@@ -16,9 +17,7 @@ func ConvertToAllergies(msg *hl7.Message, patientID string) ([]*fhir.AllergyInto
 			ResourceType: "AllergyIntolerance",
 			ID:           "allergy-" + al1.GetField(1).GetCompontent(1),
 			Type:         "allergy",
-			Patient: &fhir.Reference{
-				Reference: "Patient/" + patientID,
-			},
+			Patient:      cc.PatientReference(),
 			ClinicalStatus: &fhir.CodeableConcept{
 				Coding: []fhir.Coding{{
 					System: "http://terminology.hl7.org/CodeSystem/allergyintolerance-clinical",
@@ -29,20 +28,25 @@ func ConvertToAllergies(msg *hl7.Message, patientID string) ([]*fhir.AllergyInto
 
 		//AL1-2 Allergy Type
 		allergy.Category = mapAllergyCategory(al1.GetField(2).GetCompontent(1))
+		cc.RecordSource(allergy, "category", al1, 2)
 
 		//AL1-3 Allergen Code
 		allergy.Code = buildAllergenCode(al1)
+		cc.RecordSource(allergy, "code", al1, 3)
 
 		//AL5 Reactions
 		allergy.Reaction = buildReactions(al1)
+		cc.RecordSource(allergy, "reaction", al1, 5)
 
 		//AL1-6 Identification date
 
 		identDate := al1.GetField(6).GetCompontent(1)
 		if identDate != "" {
-			allergy.RecordedDate = formatDateTime(identDate)
+			allergy.RecordedDate = checkedDateTime(cc, al1, 6, identDate)
+			cc.RecordSource(allergy, "recordedDate", al1, 6)
 		}
 
+		cc.RecordSegment(allergy, al1)
 		allergies = append(allergies, allergy)
 	}
 
@@ -0,0 +1,276 @@
+package converter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConvertToAppointment converts SIU SCH/AIS/AIG/AIL/AIP segments to a FHIR Appointment
+func ConvertToAppointment(cc *ConversionContext) (*fhir.Appointment, []*fhir.Practitioner, []*fhir.Location, error) {
+	msg := cc.Message
+	sch := msg.GetSegment("SCH")
+	if sch == nil {
+		return nil, nil, nil, nil
+	}
+
+	var practitioners []*fhir.Practitioner
+	var locations []*fhir.Location
+
+	_, trigger := messageType(msg)
+
+	appointment := &fhir.Appointment{
+		ResourceType: "Appointment",
+		ID:           appointmentID(sch),
+		Status:       mapAppointmentStatus(trigger, sch),
+	}
+	if patient := cc.PatientReference(); patient != nil {
+		appointment.Participant = append(appointment.Participant, fhir.AppointmentParticipant{
+			Actor:  patient,
+			Status: "accepted",
+		})
+	}
+
+	//SCH-1 Placer Appointment ID, SCH-2 Filler Appointment ID
+	if placer := buildOrderIdentifier(sch.GetField(1), "PLAC"); placer != nil {
+		appointment.Identifier = append(appointment.Identifier, *placer)
+	}
+	if filler := buildOrderIdentifier(sch.GetField(2), "FILL"); filler != nil {
+		appointment.Identifier = append(appointment.Identifier, *filler)
+	}
+
+	//SCH-7 Appointment Reason
+	if reason := buildCodeableConcept(cc, sch.GetField(7)); reason != nil {
+		appointment.ReasonCode = []fhir.CodeableConcept{*reason}
+	}
+
+	//SCH-8 Appointment Type
+	appointment.AppointmentType = buildCodeableConcept(cc, sch.GetField(8))
+
+	//SCH-11 Appointment Timing Quantity (start is component 4, end is component 5)
+	timing := sch.GetField(11)
+	start := timing.GetCompontent(4)
+	end := timing.GetCompontent(5)
+
+	//SCH-9/10 Appointment Duration and Units
+	duration := durationMinutes(sch.GetField(9).GetCompontent(1), sch.GetField(10).GetCompontent(1))
+
+	//AIS Service: code, start and duration when SCH-11 is empty
+	for _, ais := range msg.GetSegments("AIS") {
+		if serviceType := buildCodeableConcept(cc, ais.GetField(3)); serviceType != nil {
+			appointment.ServiceType = append(appointment.ServiceType, *serviceType)
+		}
+		if start == "" {
+			start = ais.GetField(4).GetCompontent(1)
+		}
+		if duration == 0 {
+			duration = durationMinutes(ais.GetField(7).GetCompontent(1), ais.GetField(8).GetCompontent(1))
+		}
+	}
+
+	if end == "" && start != "" && duration > 0 {
+		end = addMinutes(start, duration)
+	}
+	appointment.Start = fhir.FormatInstant(start)
+	appointment.End = fhir.FormatInstant(end)
+	appointment.MinutesDuration = duration
+
+	//AIP Personnel Resources
+	for _, aip := range msg.GetSegments("AIP") {
+		field := aip.GetField(3)
+		if field == nil || len(field.Repetitions) == 0 {
+			continue
+		}
+		practitioner := buildPractitioner(field.Repetitions[0])
+		if practitioner == nil {
+			continue
+		}
+
+		practitioners = addPractitioner(practitioners, practitioner)
+		appointment.Participant = append(appointment.Participant, fhir.AppointmentParticipant{
+			Type:   codeableConceptList(buildCodeableConcept(cc, aip.GetField(4))),
+			Actor:  &fhir.Reference{Reference: "Practitioner/" + practitioner.ID, Display: practitionerDisplay(practitioner)},
+			Status: mapParticipantStatus(aip.GetField(12).GetCompontent(1)),
+		})
+	}
+
+	//AIL Location Resources
+	for _, ail := range msg.GetSegments("AIL") {
+		field := ail.GetField(3)
+		if field == nil || len(field.Repetitions) == 0 {
+			continue
+		}
+		location := buildLocationResource(field.Repetitions[0])
+		if location == nil {
+			continue
+		}
+
+		//AIL-4 Location Type
+		location.Type = codeableConceptList(buildCodeableConcept(cc, ail.GetField(4)))
+
+		locations = addLocation(locations, location)
+		appointment.Participant = append(appointment.Participant, fhir.AppointmentParticipant{
+			Actor:  &fhir.Reference{Reference: "Location/" + location.ID, Display: location.Name},
+			Status: mapParticipantStatus(ail.GetField(12).GetCompontent(1)),
+		})
+	}
+
+	//AIG General Resources, which have no FHIR resource of their own
+	for _, aig := range msg.GetSegments("AIG") {
+		resource := buildCodeableConcept(cc, aig.GetField(3))
+		if resource == nil {
+			continue
+		}
+		appointment.Participant = append(appointment.Participant, fhir.AppointmentParticipant{
+			Type:   codeableConceptList(buildCodeableConcept(cc, aig.GetField(4))),
+			Actor:  &fhir.Reference{Display: resource.Text},
+			Status: mapParticipantStatus(aig.GetField(14).GetCompontent(1)),
+		})
+	}
+
+	cc.RecordSegment(appointment, sch)
+	return appointment, practitioners, locations, nil
+}
+
+// ConvertToSlots builds a Schedule and a Slot for every Practitioner and Location taking part in the appointment
+func ConvertToSlots(appointment *fhir.Appointment) ([]*fhir.Schedule, []*fhir.Slot) {
+	var schedules []*fhir.Schedule
+	var slots []*fhir.Slot
+
+	if appointment.Start == "" || appointment.End == "" {
+		return nil, nil
+	}
+
+	slotStatus := "busy"
+	switch appointment.Status {
+	case "cancelled", "noshow", "entered-in-error":
+		slotStatus = "free"
+	}
+
+	for _, participant := range appointment.Participant {
+		if participant.Actor == nil {
+			continue
+		}
+		reference := participant.Actor.Reference
+		if !strings.HasPrefix(reference, "Practitioner/") && !strings.HasPrefix(reference, "Location/") {
+			continue
+		}
+
+		actorID := reference[strings.Index(reference, "/")+1:]
+		schedule := &fhir.Schedule{
+			ResourceType: "Schedule",
+			ID:           "schedule-" + actorID,
+			Active:       true,
+			Actor:        []fhir.Reference{*participant.Actor},
+		}
+		slot := &fhir.Slot{
+			ResourceType: "Slot",
+			ID:           "slot-" + appointment.ID + "-" + actorID,
+			Schedule:     &fhir.Reference{Reference: "Schedule/" + schedule.ID},
+			Status:       slotStatus,
+			Start:        appointment.Start,
+			End:          appointment.End,
+		}
+
+		schedules = append(schedules, schedule)
+		slots = append(slots, slot)
+		appointment.Slot = append(appointment.Slot, fhir.Reference{Reference: "Slot/" + slot.ID})
+	}
+
+	return schedules, slots
+}
+
+// appointmentID builds the Appointment ID from SCH-1, falling back to SCH-2
+func appointmentID(sch *hl7.Segment) string {
+	id := sch.GetField(1).GetCompontent(1)
+	if id == "" {
+		id = sch.GetField(2).GetCompontent(1)
+	}
+	return "appointment-" + id
+}
+
+// mapAppointmentStatus converts the SIU trigger event, falling back to SCH-25 filler status
+func mapAppointmentStatus(trigger string, sch *hl7.Segment) string {
+	switch trigger {
+	case "S12", "S13", "S14": // New, rescheduled, modified
+		return "booked"
+	case "S15", "S16": // Cancelled, discontinued
+		return "cancelled"
+	case "S17": // Deleted
+		return "entered-in-error"
+	case "S26": // Patient did not show up
+		return "noshow"
+	}
+
+	switch sch.GetField(25).GetCompontent(1) {
+	case "Booked", "Overbook":
+		return "booked"
+	case "Cancelled", "Dc":
+		return "cancelled"
+	case "Complete":
+		return "fulfilled"
+	case "Noshow":
+		return "noshow"
+	case "Pending":
+		return "pending"
+	case "Waitlist":
+		return "waitlist"
+	case "Started":
+		return "arrived"
+	case "Deleted":
+		return "entered-in-error"
+	default:
+		return "booked"
+	}
+}
+
+// mapParticipantStatus converts an AIx filler status code to a participant status
+func mapParticipantStatus(status string) string {
+	switch status {
+	case "Cancelled", "Deleted", "Dc":
+		return "declined"
+	case "Pending", "Waitlist":
+		return "tentative"
+	default:
+		return "accepted"
+	}
+}
+
+// durationMinutes converts a duration and its units (M, H, D, or an ISO+ code like min) to minutes
+func durationMinutes(value, units string) int {
+	amount, err := strconv.Atoi(value)
+	if err != nil || amount <= 0 {
+		return 0
+	}
+
+	switch strings.ToUpper(units) {
+	case "H", "HR", "HOUR":
+		return amount * 60
+	case "D", "DAY":
+		return amount * 24 * 60
+	case "S", "SEC":
+		return amount / 60
+	default:
+		return amount
+	}
+}
+
+// addMinutes adds minutes to an HL7 datetime (YYYYMMDDHHMM[SS])
+func addMinutes(hl7DateTime string, minutes int) string {
+	t, err := time.Parse("200601021504", hl7DateTime[:min(len(hl7DateTime), 12)])
+	if err != nil {
+		return ""
+	}
+	return t.Add(time.Duration(minutes) * time.Minute).Format("20060102150405")
+}
+
+// codeableConceptList wraps an optional CodeableConcept in a slice
+func codeableConceptList(concept *fhir.CodeableConcept) []fhir.CodeableConcept {
+	if concept == nil {
+		return nil
+	}
+	return []fhir.CodeableConcept{*concept}
+}
@@ -0,0 +1,89 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToAppointment(t *testing.T) {
+	cc := newSampleContext(t, "sample-siu.hl7")
+	appointment, practitioners, locations, err := ConvertToAppointment(cc)
+	if err != nil {
+		t.Fatalf("ConvertToAppointment() returned error: %v", err)
+	}
+	if appointment == nil || len(practitioners) != 1 || len(locations) != 1 {
+		t.Fatalf("Expected an appointment, 1 practitioner and 1 location, got %v, %d and %d", appointment, len(practitioners), len(locations))
+	}
+	if len(appointment.Identifier) != 2 || len(appointment.ReasonCode) != 1 || appointment.AppointmentType == nil ||
+		len(appointment.ServiceType) != 1 || len(appointment.Participant) != 3 || len(locations[0].Type) != 1 {
+		t.Fatalf("Expected every sample field to be converted, got %+v", appointment)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id from SCH-1", appointment.ID, "appointment-APT1001"},
+		{"status from the trigger event", appointment.Status, "booked"},
+		{"SCH-1 placer identifier", appointment.Identifier[0].Value, "APT1001"},
+		{"SCH-2 filler identifier", appointment.Identifier[1].Value, "FAP2002"},
+		{"SCH-2 assigner", appointment.Identifier[1].Assigner.Display, "SCHED"},
+		{"SCH-7 reason", appointment.ReasonCode[0].Coding[0].Code, "FOLLOWUP"},
+		{"SCH-8 type", appointment.AppointmentType.Coding[0].System, "http://terminology.hl7.org/CodeSystem/v2-0277"},
+		{"SCH-11 start", appointment.Start, "2023-11-20T09:30:00"},
+		{"SCH-11 end", appointment.End, "2023-11-20T10:00:00"},
+		{"AIS-3 service type", appointment.ServiceType[0].Coding[0].Code, "99213"},
+		{"patient participant", appointment.Participant[0].Actor.Reference, "Patient/583295"},
+		{"AIP-3 practitioner", appointment.Participant[1].Actor.Reference, "Practitioner/practitioner-1234567"},
+		{"AIP-4 role", appointment.Participant[1].Type[0].Coding[0].Code, "ATND"},
+		{"AIL-3 location", appointment.Participant[2].Actor.Reference, "Location/location-MAINCAMPUS-CLINIC-EXAM3"},
+		{"AIL-3 location name", locations[0].Name, "CLINIC Room EXAM3"},
+		{"AIL-4 location type", locations[0].Type[0].Coding[0].Code, "EXAM"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, test.got)
+		}
+	}
+	if appointment.MinutesDuration != 30 {
+		t.Errorf("Expected a 30 minute appointment, got %d", appointment.MinutesDuration)
+	}
+
+	schedules, slots := ConvertToSlots(appointment)
+	if len(schedules) != 2 || len(slots) != 2 || len(appointment.Slot) != 2 {
+		t.Fatalf("Expected a schedule and slot for the practitioner and the location, got %d and %d", len(schedules), len(slots))
+	}
+	if slots[0].ID != "slot-appointment-APT1001-practitioner-1234567" || slots[0].Status != "busy" ||
+		slots[0].Start != appointment.Start || slots[0].Schedule.Reference != "Schedule/"+schedules[0].ID {
+		t.Errorf("Unexpected practitioner slot %+v", slots[0])
+	}
+}
+
+func TestMapAppointmentStatus(t *testing.T) {
+	tests := []struct {
+		trigger string
+		sch     string
+		want    string
+	}{
+		{"S12", "SCH|1", "booked"},
+		{"S15", "SCH|1", "cancelled"},
+		{"S17", "SCH|1", "entered-in-error"},
+		{"S26", "SCH|1", "noshow"},
+		{"S14", "SCH|1||||||||||||||||||||||||Cancelled", "booked"},
+		{"S23", "SCH|1||||||||||||||||||||||||Complete", "fulfilled"},
+		{"S23", "SCH|1||||||||||||||||||||||||Waitlist", "waitlist"},
+		{"S23", "SCH|1", "booked"},
+	}
+
+	for _, test := range tests {
+		msg, err := hl7.Parse("MSH|^~\\&|SCHED|CLINIC|EMR|HOSPITAL|20231115080000||SIU^" + test.trigger + "|MSG001|P|2.5\r" + test.sch)
+		if err != nil {
+			t.Fatalf("Parse() returned error: %v", err)
+		}
+		if got := mapAppointmentStatus(test.trigger, msg.GetSegment("SCH")); got != test.want {
+			t.Errorf("%s %s: expected %s, got %s", test.trigger, test.sch, test.want, got)
+		}
+	}
+}
@@ -1,87 +1,155 @@
 package converter
 
 import (
-	"github.com/mourice12/hl7-to-fhir/internal/fhir"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"context"
+	"fmt"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
 // ConvertToBundle converts HL7 message to FHIR Bundle
 func ConvertToBundle(msg *hl7.Message) (*fhir.Bundle, error) {
-	bundle := fhir.NewBundle()
+	return ConvertToBundleWithOptions(msg, DefaultConvertOptions())
+}
+
+// ConvertToBundleWithOptions converts HL7 message to FHIR Bundle using the given options
+func ConvertToBundleWithOptions(msg *hl7.Message, opts ConvertOptions) (*fhir.Bundle, error) {
+	bundle, _, err := ConvertToBundleWithIssues(msg, opts)
+	return bundle, err
+}
+
+// ConvertToBundleWithIssues converts like ConvertToBundleWithOptions and also returns the issues found on the way;
+// in strict mode any warning or error fails the conversion with a *StrictError
+func ConvertToBundleWithIssues(msg *hl7.Message, opts ConvertOptions) (*fhir.Bundle, []Issue, error) {
+	return ConvertToBundleContext(context.Background(), msg, opts)
+}
+
+// ConvertToBundleContext converts like ConvertToBundleWithIssues, stopping with hl7.ErrTimeout or the context's
+// error when ctx is done before the conversion finishes
+func ConvertToBundleContext(ctx context.Context, msg *hl7.Message, opts ConvertOptions) (*fhir.Bundle, []Issue, error) {
+	_, bundle, issues, err := convertMessage(ctx, msg, opts)
+	return bundle, issues, err
+}
 
-	//Convert Patient
-	patient, err := ConvertToPatient(msg)
+// convertMessage converts like ConvertToBundleContext and also returns the context the conversion recorded in
+func convertMessage(ctx context.Context, msg *hl7.Message, opts ConvertOptions) (*ConversionContext, *fhir.Bundle, []Issue, error) {
+	if err := validateMode(opts.Mode); err != nil {
+		return nil, nil, nil, err
+	}
+
+	cc := NewConversionContext(ctx, msg, opts)
+	bundle, err := convertBundle(cc)
+	issues := cc.Issues()
+	if err == nil && opts.Mode == ModeStrict {
+		if failures := strictFailures(issues); len(failures) > 0 {
+			return cc, nil, issues, &StrictError{Issues: failures}
+		}
+	}
+	return cc, bundle, issues, err
+}
+
+// convertBundle runs the conversion pipeline
+func convertBundle(cc *ConversionContext) (*fhir.Bundle, error) {
+	msg, opts := cc.Message, cc.Options
+	if opts.BundleType != "" && opts.BundleType != BundleTransaction && opts.BundleType != BundleMessage {
+		return nil, fmt.Errorf("unknown bundle type %q", opts.BundleType)
+	}
+
+	//Per-sender profile
+	selected, err := selectProfile(msg, opts)
 	if err != nil {
 		return nil, err
 	}
-	if patient != nil {
-		bundle.AddEntry("Patient", patient.ID, patient)
+	opts, err = withProfile(opts, selected)
+	if err != nil {
+		return nil, err
 	}
-	//Convert Encounter
-	if patient != nil {
-		encounter, err := ConvertToEncounter(msg, patient.ID)
-		if err != nil {
-			return nil, err
-		}
-		if encounter != nil {
-			bundle.AddEntry("Encounter", encounter.ID, encounter)
-		}
+	cc.Options = opts
+
+	registry := opts.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	handler, err := registry.Lookup(msg)
+	if err != nil {
+		return nil, err
 	}
 
-	//Convert Conditions
-	if patient != nil {
-		conditions, err := ConvertToConditions(msg, patient.ID)
-		if err != nil {
-			return nil, err
-		}
+	//Report what the conversion needs but lacks, and what it will leave out
+	checkRequiredSegments(cc)
+	checkConvertedSegments(cc, registry, opts.Mappings)
 
-		for _, condition := range conditions {
-			bundle.AddEntry("Condition", condition.ID, condition)
-		}
+	if err := cc.Err(); err != nil {
+		return nil, err
+	}
+	if err := handler(cc); err != nil {
+		return nil, err
+	}
+	bundle := cc.Bundle
+	if err := cc.Err(); err != nil {
+		return nil, err
 	}
 
-	//Convert Allergies
-	if patient != nil {
-		allergies, err := ConvertToAllergies(msg, patient.ID)
-		if err != nil {
-			return nil, err
-		}
+	//Declarative mappings replace the built-in conversion of the types they build
+	if err := applyMappings(cc); err != nil {
+		return nil, err
+	}
 
-		for _, allergy := range allergies {
-			bundle.AddEntry("AllergyIntolerance", allergy.ID, allergy)
-		}
+	//Custom segments extend the converted resources or add their own
+	if err := applySegmentHooks(cc, registry); err != nil {
+		return nil, err
+	}
+	if err := cc.Err(); err != nil {
+		return nil, err
 	}
 
-	//Convert Observations
-	//Convert Observations
-	var observations []*fhir.Oberservation
-	if patient != nil {
-		observations, err = ConvertToObservations(msg, patient.ID)
-		if err != nil {
-			return nil, err
-		}
+	//Profile resource types, identifier systems and local codes
+	applyProfile(bundle, msg, selected)
 
-		for _, obs := range observations {
-			bundle.AddEntry("Observation", obs.ID, obs)
-		}
-	}
+	//Resource ids from the configured strategy
+	applyIDStrategy(bundle, msg, opts.IDs)
+
+	//UUID fullUrls and references that resolve inside the bundle
+	assignFullURLs(cc)
 
-	//Convert Diagnostic Reports
-	if patient != nil {
-		report, err := ConvertToDiagnosticReport(msg, patient.ID)
-		if err != nil {
+	//Message bundles start with a MessageHeader, transactions need a request for every entry
+	if opts.BundleType == BundleMessage {
+		if err := toMessageBundle(cc); err != nil {
 			return nil, err
 		}
-		if report != nil {
-			for _, obs := range observations {
-				report.Result = append(report.Result, fhir.Reference{
-					Reference: "Observation/" + obs.ID,
-				})
-			}
-			bundle.AddEntry("DiagnosticReport", report.ID, report)
-		}
+	} else {
+		applyEntryRequests(bundle, msg, opts)
+	}
+
+	//Profile timezone for times sent without an offset
+	applyTimezone(bundle, selected)
+
+	//Provenance of everything converted from this message
+	if err := addProvenance(cc); err != nil {
+		return nil, err
 	}
 
 	return bundle, nil
 
 }
+
+// messageControlID returns MSH-10 Message Control ID
+func messageControlID(msg *hl7.Message) string {
+	msh := msg.GetSegment("MSH")
+	if msh == nil {
+		return ""
+	}
+	return msh.GetField(10).GetCompontent(1)
+}
+
+// messageType returns the message code and trigger event from MSH-9
+func messageType(msg *hl7.Message) (string, string) {
+	msh := msg.GetSegment("MSH")
+	if msh == nil {
+		return "", ""
+	}
+
+	field := msh.GetField(9)
+	return field.GetCompontent(1), field.GetCompontent(2)
+}
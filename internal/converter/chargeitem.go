@@ -0,0 +1,154 @@
+package converter
+
+import (
+	"strconv"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConvertToChargeItems converts FT1 charge and credit transactions to FHIR ChargeItems
+func ConvertToChargeItems(cc *ConversionContext) ([]*fhir.ChargeItem, []*fhir.Practitioner, error) {
+	msg := cc.Message
+	var chargeItems []*fhir.ChargeItem
+	var practitioners []*fhir.Practitioner
+
+	accounts := accountReferences(msg)
+
+	ft1Segments := msg.GetSegments("FT1")
+	for _, ft1 := range ft1Segments {
+		//FT1-6 Transaction Type: payments and adjustments are not charges
+		status := mapTransactionType(ft1.GetField(6).GetCompontent(1))
+		if status == "" {
+			continue
+		}
+
+		chargeItem := &fhir.ChargeItem{
+			ResourceType: "ChargeItem",
+			ID:           chargeItemID(ft1),
+			Status:       status,
+			Subject:      cc.PatientReference(),
+			Account:      accounts,
+			Context:      cc.EncounterReference(),
+		}
+
+		//FT1-2 Transaction ID
+		transactionID := ft1.GetField(2).GetCompontent(1)
+		if transactionID != "" {
+			chargeItem.Identifier = []fhir.Identifier{{Value: transactionID}}
+		}
+
+		//FT1-7 Transaction Code, with FT1-25 Procedure Code as an additional coding
+		chargeItem.Code = buildChargeCode(cc, ft1)
+
+		//FT1-4 Transaction Date (service date range)
+		dateField := ft1.GetField(4)
+		start := dateField.GetCompontent(1)
+		end := dateField.GetCompontent(2)
+		if end != "" {
+			chargeItem.OccurrencePeriod = &fhir.Period{
+				Start: checkedDateTime(cc, ft1, 4, start),
+				End:   checkedDateTime(cc, ft1, 4, end),
+			}
+		} else if start != "" {
+			chargeItem.OccurrenceDateTime = checkedDateTime(cc, ft1, 4, start)
+		}
+
+		//FT1-5 Transaction Posting Date
+		posted := ft1.GetField(5).GetCompontent(1)
+		if posted != "" {
+			chargeItem.EnteredDate = checkedDateTime(cc, ft1, 5, posted)
+		}
+
+		//FT1-10 Transaction Quantity
+		if quantity, ok := checkedNumber(cc, ft1, 10, ft1.GetField(10).GetCompontent(1)); ok {
+			chargeItem.Quantity = &fhir.Quantity{Value: &quantity}
+		}
+
+		//FT1-11 Extended Amount, falling back to FT1-12 Unit Amount
+		chargeItem.PriceOverride = buildMoney(cc, ft1.GetField(11))
+		if chargeItem.PriceOverride == nil {
+			chargeItem.PriceOverride = buildMoney(cc, ft1.GetField(12))
+		}
+
+		//FT1-20 Performed By
+		if field := ft1.GetField(20); field != nil {
+			for _, rep := range field.Repetitions {
+				practitioner := buildPractitioner(rep)
+				if practitioner == nil {
+					continue
+				}
+
+				practitioners = addPractitioner(practitioners, practitioner)
+				chargeItem.Performer = append(chargeItem.Performer, fhir.ChargeItemPerformer{
+					Actor: &fhir.Reference{
+						Reference: "Practitioner/" + practitioner.ID,
+						Display:   practitionerDisplay(practitioner),
+					},
+				})
+			}
+		}
+
+		cc.RecordSegment(chargeItem, ft1)
+		chargeItems = append(chargeItems, chargeItem)
+	}
+
+	return chargeItems, practitioners, nil
+}
+
+// chargeItemID builds the ChargeItem ID from FT1-2, falling back to FT1-1
+func chargeItemID(ft1 *hl7.Segment) string {
+	transactionID := ft1.GetField(2).GetCompontent(1)
+	if transactionID != "" {
+		return "chargeitem-" + transactionID
+	}
+	return "chargeitem-" + ft1.GetField(1).GetCompontent(1)
+}
+
+// buildChargeCode combines FT1-7 Transaction Code and FT1-25 Procedure Code
+func buildChargeCode(cc *ConversionContext, ft1 *hl7.Segment) *fhir.CodeableConcept {
+	code := buildCodeableConcept(cc, ft1.GetField(7))
+
+	procedure := buildCodeableConcept(cc, ft1.GetField(25))
+	if procedure == nil {
+		return code
+	}
+	if code == nil {
+		return procedure
+	}
+
+	code.Coding = append(code.Coding, procedure.Coding...)
+	if code.Text == "" {
+		code.Text = procedure.Text
+	}
+	return code
+}
+
+// buildMoney converts a CP field (amount&currency) to FHIR Money
+func buildMoney(cc *ConversionContext, field *hl7.Field) *fhir.Money {
+	amount := field.GetSubcomponent(1, 1)
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		if amount != "" {
+			reportFieldIssue(cc, field, SeverityWarning, IssueInvalid, "amount %q is not a number and was dropped", amount)
+		}
+		return nil
+	}
+
+	return &fhir.Money{
+		Value:    value,
+		Currency: field.GetSubcomponent(1, 2),
+	}
+}
+
+// mapTransactionType converts FT1-6 to a ChargeItem status, or "" for non-charge transactions
+func mapTransactionType(transactionType string) string {
+	switch transactionType {
+	case "CG", "": // Charge
+		return "billable"
+	case "CD": // Credit reverses an earlier charge
+		return "aborted"
+	default: // PY Payment, AJ Adjustment
+		return ""
+	}
+}
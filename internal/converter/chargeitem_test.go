@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToChargeItems(t *testing.T) {
+	cc := newSampleContext(t, "sample-dft.hl7")
+	encounter, err := ConvertToEncounter(cc)
+	if err != nil || encounter == nil {
+		t.Fatalf("ConvertToEncounter() returned %v, %v", encounter, err)
+	}
+	cc.AddResource(encounter)
+
+	chargeItems, practitioners, err := ConvertToChargeItems(cc)
+	if err != nil {
+		t.Fatalf("ConvertToChargeItems() returned error: %v", err)
+	}
+	if len(chargeItems) != 1 || len(practitioners) != 1 {
+		t.Fatalf("Expected 1 charge item and 1 practitioner, got %d and %d", len(chargeItems), len(practitioners))
+	}
+
+	chargeItem := chargeItems[0]
+	if len(chargeItem.Identifier) != 1 || chargeItem.Code == nil || len(chargeItem.Code.Coding) != 2 || chargeItem.Quantity == nil ||
+		chargeItem.PriceOverride == nil || len(chargeItem.Performer) != 1 || len(chargeItem.Account) != 1 || chargeItem.Context == nil {
+		t.Fatalf("Expected every sample field to be converted, got %+v", chargeItem)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id from FT1-2", chargeItem.ID, "chargeitem-TXN5001"},
+		{"status from FT1-6", chargeItem.Status, "billable"},
+		{"subject", chargeItem.Subject.Reference, "Patient/583295"},
+		{"context", chargeItem.Context.Reference, "Encounter/" + encounter.ID},
+		{"account from PID-18", chargeItem.Account[0].Reference, "Account/account-PATID001"},
+		{"FT1-2 identifier", chargeItem.Identifier[0].Value, "TXN5001"},
+		{"FT1-7 transaction code", chargeItem.Code.Coding[0].Code, "300.12"},
+		{"FT1-25 procedure code", chargeItem.Code.Coding[1].Code, "85025"},
+		{"FT1-25 CPT system", chargeItem.Code.Coding[1].System, "http://www.ama-assn.org/go/cpt"},
+		{"FT1-4 occurrence", chargeItem.OccurrenceDateTime, "2023-11-15T10:30:00"},
+		{"FT1-5 entered date", chargeItem.EnteredDate, "2023-11-16T12:00:00"},
+		{"FT1-11 currency", chargeItem.PriceOverride.Currency, "USD"},
+		{"FT1-20 performer", chargeItem.Performer[0].Actor.Reference, "Practitioner/practitioner-1234567"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, test.got)
+		}
+	}
+	if *chargeItem.Quantity.Value != 1 || chargeItem.PriceOverride.Value != 45 {
+		t.Errorf("Expected 1 item at 45.00, got %v at %v", *chargeItem.Quantity.Value, chargeItem.PriceOverride.Value)
+	}
+}
+
+func TestConvertToChargeItemsTransactionTypes(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|BILLING|HOSPITAL|FIN|HOSPITAL|20231116120000||DFT^P03|MSG001|P|2.5\r" +
+		"PID|1||583295^^^ADT1^MR||DOE^JOHN||19800115|M\r" +
+		"FT1|1|TXN1||20231115|20231116|CD|300.12^CBC^CDM|||1||45.00&USD\r" +
+		"FT1|2|TXN2||20231115|20231116|PY|PAY^Payment^CDM|||1|45.00&USD\r" +
+		"FT1|3|||20231115^20231116|20231116|CG|300.12^CBC^CDM|||1|abc")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	cc := newTestContext(msg)
+	chargeItems, _, err := ConvertToChargeItems(cc)
+	if err != nil {
+		t.Fatalf("ConvertToChargeItems() returned error: %v", err)
+	}
+	if len(chargeItems) != 2 {
+		t.Fatalf("Expected the payment to be skipped, got %d charge items", len(chargeItems))
+	}
+
+	credit, charge := chargeItems[0], chargeItems[1]
+	if credit.Status != "aborted" || credit.PriceOverride == nil || credit.PriceOverride.Value != 45 {
+		t.Errorf("Expected an aborted credit priced from FT1-12, got %+v", credit)
+	}
+	if charge.ID != "chargeitem-3" || charge.OccurrencePeriod == nil || charge.OccurrencePeriod.End != "2023-11-16" {
+		t.Errorf("Expected an FT1-1 id and an FT1-4 period, got %+v", charge)
+	}
+	if charge.PriceOverride != nil || len(strictFailures(cc.Issues())) != 1 {
+		t.Errorf("Expected the invalid amount to be dropped and reported, got %+v and %v", charge.PriceOverride, cc.Issues())
+	}
+}
+
+func TestConvertToAccount(t *testing.T) {
+	cc := newSampleContext(t, "sample-dft.hl7")
+	account, err := ConvertToAccount(cc)
+	if err != nil || account == nil {
+		t.Fatalf("ConvertToAccount() returned %v, %v", account, err)
+	}
+
+	if account.ID != "account-PATID001" || account.Status != "active" {
+		t.Errorf("Expected an active account-PATID001, got %s %s", account.ID, account.Status)
+	}
+	if len(account.Identifier) != 1 || account.Identifier[0].Value != "PATID001" || account.Identifier[0].System != "" ||
+		account.Identifier[0].Assigner == nil || account.Identifier[0].Assigner.Display != "HOSP" ||
+		account.Identifier[0].Type.Coding[0].Code != "AN" {
+		t.Errorf("Unexpected PID-18 identifier %+v", account.Identifier)
+	}
+	if len(account.Subject) != 1 || account.Subject[0].Reference != "Patient/583295" {
+		t.Errorf("Expected the patient as subject, got %+v", account.Subject)
+	}
+}
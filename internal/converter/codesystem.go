@@ -0,0 +1,94 @@
+package converter
+
+import (
+	"regexp"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// hl7Table matches the coding system names of HL7 tables, e.g. HL70488
+var hl7Table = regexp.MustCompile(`^HL7(\d{4})$`)
+
+// mapCodeSystem converts an HL7 coding system name (table 0396) to a FHIR system URL
+func mapCodeSystem(hl7System string) string {
+	//HL7 tables that stand for external code systems
+	switch hl7System {
+	case "HL70292":
+		return "http://hl7.org/fhir/sid/cvx"
+	case "HL70227":
+		return "http://hl7.org/fhir/sid/mvx"
+	}
+
+	//HL7nnnn: the HL7 table's code system in the HL7 terminology
+	if match := hl7Table.FindStringSubmatch(hl7System); match != nil {
+		return "http://terminology.hl7.org/CodeSystem/v2-" + match[1]
+	}
+
+	switch hl7System {
+	case "LN", "L":
+		return "http://loinc.org"
+	case "SCT", "SNM", "SNM3":
+		return "http://snomed.info/sct"
+	case "C4", "CPT", "C5":
+		return "http://www.ama-assn.org/go/cpt"
+	case "HCPCS", "HPC":
+		return "https://www.cms.gov/Medicare/Coding/HCPCSReleaseCodeSets"
+	case "I10", "ICD10", "I10C":
+		return "http://hl7.org/fhir/sid/icd-10-cm"
+	case "I10P", "ICD10PCS":
+		return "http://www.cms.gov/Medicare/Coding/ICD10"
+	case "I9", "I9C", "ICD9":
+		return "http://hl7.org/fhir/sid/icd-9-cm"
+	case "CVX":
+		return "http://hl7.org/fhir/sid/cvx"
+	case "MVX":
+		return "http://hl7.org/fhir/sid/mvx"
+	case "NDC":
+		return "http://hl7.org/fhir/sid/ndc"
+	case "RXNORM", "RXN":
+		return "http://www.nlm.nih.gov/research/umls/rxnorm"
+	case "UCUM":
+		return "http://unitsofmeasure.org"
+	case "CDCPHINVS":
+		return "urn:oid:2.16.840.1.114222.4.5.274"
+	default:
+		return ""
+	}
+}
+
+// buildCodeableConcept converts a CE/CWE field (code^text^system) to a FHIR CodeableConcept
+func buildCodeableConcept(cc *ConversionContext, field *hl7.Field) *fhir.CodeableConcept {
+	if field == nil || len(field.Repetitions) == 0 {
+		return nil
+	}
+
+	//CWE-3 Name of Coding System without a FHIR system URL
+	if system := field.GetCompontent(3); system != "" && mapCodeSystem(system) == "" {
+		reportFieldIssue(cc, field, SeverityInformation, IssueCodeInvalid, "coding system %q has no FHIR system and was left out", system)
+	}
+	return buildCodeableConceptFromRep(field.Repetitions[0])
+}
+
+// buildCodeableConceptFromRep converts a single CE/CWE repetition to a FHIR CodeableConcept
+func buildCodeableConceptFromRep(rep hl7.Repetition) *fhir.CodeableConcept {
+	code := getComponentValue(rep, 1)
+	display := getComponentValue(rep, 2)
+	system := getComponentValue(rep, 3)
+
+	if code == "" {
+		if display == "" {
+			return nil
+		}
+		return &fhir.CodeableConcept{Text: display}
+	}
+
+	return &fhir.CodeableConcept{
+		Coding: []fhir.Coding{{
+			System:  mapCodeSystem(system),
+			Code:    code,
+			Display: display,
+		}},
+		Text: display,
+	}
+}
@@ -0,0 +1,21 @@
+package converter
+
+import "testing"
+
+func TestMapCodeSystem(t *testing.T) {
+	tests := map[string]string{
+		"LN":      "http://loinc.org",
+		"HL70488": "http://terminology.hl7.org/CodeSystem/v2-0488",
+		"HL70292": "http://hl7.org/fhir/sid/cvx",
+		"HL70227": "http://hl7.org/fhir/sid/mvx",
+		"HL70064": "http://terminology.hl7.org/CodeSystem/v2-0064",
+		"HL7488":  "",
+		"99LAB":   "",
+		"CDM":     "",
+	}
+	for system, want := range tests {
+		if got := mapCodeSystem(system); got != want {
+			t.Errorf("mapCodeSystem(%q) = %q, want %q", system, got, want)
+		}
+	}
+}
@@ -1,12 +1,13 @@
 package converter
 
 import (
-	"github.com/mourice12/hl7-to-fhir/internal/fhir"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
 // ConvertToConditions converts DG1 segments to FHIR Conditions
-func ConvertToConditions(msg *hl7.Message, patientID string) ([]*fhir.Condition, error) {
+func ConvertToConditions(cc *ConversionContext) ([]*fhir.Condition, error) {
+	msg := cc.Message
 	var conditions []*fhir.Condition
 
 	dg1Segments := msg.GetSegments("DG1")
@@ -14,23 +15,25 @@ func ConvertToConditions(msg *hl7.Message, patientID string) ([]*fhir.Condition,
 		condition := &fhir.Condition{
 			ResourceType: "Condition",
 			ID:           "condition-" + dg1.GetField(1).GetCompontent(1),
-			Subject: &fhir.Reference{
-				Reference: "Patient/" + patientID,
-			},
+			Subject:      cc.PatientReference(),
 		}
 
 		//DG1-3: Diagnosis Code
 		condition.Code = buildDiagnosisCode(dg1)
+		cc.RecordSource(condition, "code", dg1, 3)
 
 		//DG1-5 Diagnosis Date Time
 		diagDate := dg1.GetField(5).GetCompontent(1)
 		if diagDate != "" {
-			condition.RecordedDate = formatDateTime(diagDate)
+			condition.RecordedDate = checkedDateTime(cc, dg1, 5, diagDate)
+			cc.RecordSource(condition, "recordedDate", dg1, 5)
 		}
 
 		//DG1-6 Diagnosis Type
 		condition.ClinicalStatus = mapDiagnosisType(dg1.GetField(6).GetCompontent(1))
+		cc.RecordSource(condition, "clinicalStatus", dg1, 6)
 
+		cc.RecordSegment(condition, dg1)
 		conditions = append(conditions, condition)
 	}
 	return conditions, nil
@@ -0,0 +1,218 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConversionContext is the state of converting one message: the message, the options, the resources
+// created so far and the issues found. It is passed to every Convert* function, handler and segment hook.
+type ConversionContext struct {
+	Message *hl7.Message
+	Options ConvertOptions
+	Bundle  *fhir.Bundle // the resources created so far
+
+	ctx      context.Context
+	mu       sync.Mutex
+	issues   []Issue
+	segments map[fhir.Resource]*hl7.Segment
+	sources  map[fhir.Resource][]elementSource
+}
+
+// NewConversionContext starts the conversion of msg with an empty bundle; ctx bounds how long it may take
+func NewConversionContext(ctx context.Context, msg *hl7.Message, opts ConvertOptions) *ConversionContext {
+	return &ConversionContext{Message: msg, Options: opts, Bundle: fhir.NewBundle(), ctx: ctx}
+}
+
+// Context returns the context the conversion runs under, for handlers that do their own I/O
+func (cc *ConversionContext) Context() context.Context {
+	return cc.ctx
+}
+
+// Err returns hl7.ErrTimeout once the conversion's deadline has passed, or the context's error when it was cancelled
+func (cc *ConversionContext) Err() error {
+	return hl7.ContextError(cc.ctx)
+}
+
+// AddResource adds a resource to the bundle
+func (cc *ConversionContext) AddResource(resource fhir.Resource) error {
+	if resource == nil || resource.GetResourceType() == "" {
+		return fmt.Errorf("resource %T has no resourceType", resource)
+	}
+	cc.Bundle.AddEntry(resource.GetResourceType(), resource.GetID(), resource)
+	return nil
+}
+
+// Resources returns the resources of a type created so far, in bundle order
+func (cc *ConversionContext) Resources(resourceType string) []fhir.Resource {
+	var resources []fhir.Resource
+	for _, entry := range cc.Bundle.Entry {
+		if entry.Resource != nil && entry.Resource.GetResourceType() == resourceType {
+			resources = append(resources, entry.Resource)
+		}
+	}
+	return resources
+}
+
+// FindByIdentifier returns the resource of a type with the given identifier; an empty system matches any
+func (cc *ConversionContext) FindByIdentifier(resourceType, system, value string) fhir.Resource {
+	for _, entry := range cc.Bundle.Entry {
+		if entry.Resource == nil || entry.Resource.GetResourceType() != resourceType {
+			continue
+		}
+		for _, identifier := range entry.Resource.GetIdentifiers() {
+			if identifier.Value == value && (system == "" || identifier.System == system) {
+				return entry.Resource
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve returns the resource a reference points to, as Type/id or as a bundle fullUrl
+func (cc *ConversionContext) Resolve(reference string) fhir.Resource {
+	for _, entry := range cc.Bundle.Entry {
+		if entry.FullURL == reference {
+			return entry.Resource
+		}
+		if entry.Resource != nil && entry.Resource.GetResourceType()+"/"+entry.Resource.GetID() == reference {
+			return entry.Resource
+		}
+	}
+	return nil
+}
+
+// Reference builds a reference to a resource by type and id
+func (cc *ConversionContext) Reference(resourceType, id string) *fhir.Reference {
+	return &fhir.Reference{Reference: resourceType + "/" + id}
+}
+
+// RecordSegment notes the segment occurrence a resource was built from, so a mapping of the same segment replaces it
+func (cc *ConversionContext) RecordSegment(resource fhir.Resource, segment *hl7.Segment) {
+	if resource == nil || segment == nil {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.segments == nil {
+		cc.segments = map[fhir.Resource]*hl7.Segment{}
+	}
+	cc.segments[resource] = segment
+}
+
+// SourceSegment returns the segment occurrence a resource was built from, or nil when it was not recorded
+func (cc *ConversionContext) SourceSegment(resource fhir.Resource) *hl7.Segment {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.segments[resource]
+}
+
+// RecordSource notes the segment field a resource element, such as code or valueQuantity.unit, was converted from,
+// for the lineage report; an element may be recorded with several fields
+func (cc *ConversionContext) RecordSource(resource fhir.Resource, element string, segment *hl7.Segment, field int) {
+	if resource == nil || segment == nil {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.sources == nil {
+		cc.sources = map[fhir.Resource][]elementSource{}
+	}
+	cc.sources[resource] = append(cc.sources[resource], elementSource{element: element, segment: segment, field: field})
+}
+
+// recordedSources returns the element sources recorded for a resource
+func (cc *ConversionContext) recordedSources(resource fhir.Resource) []elementSource {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.sources[resource]
+}
+
+// Patient returns the patient the message is about, or nil before it is converted
+func (cc *ConversionContext) Patient() *fhir.Patient {
+	for _, resource := range cc.Resources("Patient") {
+		if patient, ok := resource.(*fhir.Patient); ok {
+			return patient
+		}
+	}
+	return nil
+}
+
+// Encounter returns the converted encounter, or nil when there is none
+func (cc *ConversionContext) Encounter() *fhir.Encounter {
+	for _, resource := range cc.Resources("Encounter") {
+		if encounter, ok := resource.(*fhir.Encounter); ok {
+			return encounter
+		}
+	}
+	return nil
+}
+
+// PatientID returns the id of the patient, or "" before it is converted
+func (cc *ConversionContext) PatientID() string {
+	if patient := cc.Patient(); patient != nil {
+		return patient.ID
+	}
+	return ""
+}
+
+// PatientReference returns a reference to the patient the message is about, or nil when it has no id
+func (cc *ConversionContext) PatientReference() *fhir.Reference {
+	id := cc.PatientID()
+	if id == "" {
+		return nil
+	}
+	return cc.Reference("Patient", id)
+}
+
+// EncounterReference returns a reference to the converted encounter, or nil when there is none or it has no id
+func (cc *ConversionContext) EncounterReference() *fhir.Reference {
+	encounter := cc.Encounter()
+	if encounter == nil || encounter.ID == "" {
+		return nil
+	}
+	return cc.Reference("Encounter", encounter.ID)
+}
+
+// Report records an issue at an HL7 location, e.g. OBX[3]-5
+func (cc *ConversionContext) Report(location, severity, code, format string, args ...interface{}) {
+	cc.addIssue(Issue{
+		Severity: severity,
+		Code:     code,
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// ReportSegment records an issue about a field of a segment of the message; field 0 refers to the whole segment
+func (cc *ConversionContext) ReportSegment(segment *hl7.Segment, field int, severity, code, format string, args ...interface{}) {
+	if segment == nil {
+		return
+	}
+	location, ok := segmentLocation(cc.Message, segment)
+	if !ok {
+		return
+	}
+	if field > 0 {
+		location += "-" + fmt.Sprint(field)
+	}
+	cc.Report(location, severity, code, format, args...)
+}
+
+// Issues returns the issues found so far
+func (cc *ConversionContext) Issues() []Issue {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return append([]Issue(nil), cc.issues...)
+}
+
+// addIssue appends an issue to the context
+func (cc *ConversionContext) addIssue(issue Issue) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.issues = append(cc.issues, issue)
+}
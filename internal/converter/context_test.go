@@ -0,0 +1,190 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// newTestContext returns a context for msg that already holds the given resources
+func newTestContext(msg *hl7.Message, resources ...fhir.Resource) *ConversionContext {
+	cc := NewConversionContext(context.Background(), msg, DefaultConvertOptions())
+	for _, resource := range resources {
+		cc.AddResource(resource)
+	}
+	return cc
+}
+
+// newSampleContext parses a message from testdata and converts its patient first, as the handlers do
+func newSampleContext(t *testing.T, name string) *ConversionContext {
+	t.Helper()
+	cc := newTestContext(parseSampleFile(t, filepath.Join("..", "..", "testdata", name)))
+	patient, err := ConvertToPatient(cc)
+	if err != nil || patient == nil {
+		t.Fatalf("ConvertToPatient() returned %v, %v", patient, err)
+	}
+	cc.AddResource(patient)
+	return cc
+}
+
+func TestConversionContext(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231115||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"PV1|1|I|ICU^0101^01||||||||||||||||V100\r" +
+		"DG1|1||J18.9^Pneumonia^I10")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	cc := newTestContext(msg)
+	if cc.Patient() != nil || cc.EncounterReference() != nil {
+		t.Fatalf("Expected an empty context")
+	}
+
+	patient, _ := ConvertToPatient(cc)
+	cc.AddResource(patient)
+	encounter, _ := ConvertToEncounter(cc)
+	cc.AddResource(encounter)
+
+	//Later converters reference the resources already in the context
+	conditions, err := ConvertToConditions(cc)
+	if err != nil {
+		t.Fatalf("ConvertToConditions() returned error: %v", err)
+	}
+	if len(conditions) != 1 || conditions[0].Subject.Reference != "Patient/"+patient.ID {
+		t.Errorf("Expected the condition to reference Patient/%s, got %+v", patient.ID, conditions)
+	}
+	if ref := cc.EncounterReference(); ref == nil || ref.Reference != "Encounter/"+encounter.ID {
+		t.Errorf("Expected an encounter reference, got %v", ref)
+	}
+
+	if found := cc.FindByIdentifier("Patient", "", "12345"); found != patient {
+		t.Errorf("Expected to find the patient by identifier, got %v", found)
+	}
+	if resolved := cc.Resolve("Encounter/" + encounter.ID); resolved != encounter {
+		t.Errorf("Expected Encounter/%s to resolve, got %v", encounter.ID, resolved)
+	}
+	if resolved := cc.Resolve("Encounter/unknown"); resolved != nil {
+		t.Errorf("Expected an unknown reference not to resolve, got %v", resolved)
+	}
+
+	cc.ReportSegment(msg.GetSegment("DG1"), 3, SeverityWarning, IssueValue, "checked by %s", "a handler")
+	issues := cc.Issues()
+	if len(issues) != 1 || issues[0].Location != "DG1[1]-3" || issues[0].Message != "checked by a handler" {
+		t.Errorf("Expected one issue at DG1[1]-3, got %v", issues)
+	}
+}
+
+func TestConvertWithoutEncounterID(t *testing.T) {
+	//PV1-19 is empty, so the Encounter has no id to reference
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231115||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"PV1|1|I|ICU^0101^01\r" +
+		"PR1|1||0DTJ4ZZ^Resection of appendix^I10P||20231115103000")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	cc := newTestContext(msg, &fhir.Patient{ResourceType: "Patient", ID: "12345"}, &fhir.Encounter{ResourceType: "Encounter"})
+	if ref := cc.EncounterReference(); ref != nil {
+		t.Errorf("Expected no reference to an encounter without an id, got %v", ref)
+	}
+
+	bundle, err := ConvertToBundle(msg)
+	if err != nil {
+		t.Fatalf("ConvertToBundle() returned error: %v", err)
+	}
+	for _, entry := range bundle.Entry {
+		if procedure, ok := entry.Resource.(*fhir.Procedure); ok && procedure.Encounter != nil {
+			t.Errorf("Expected the procedure to have no encounter, got %v", procedure.Encounter)
+		}
+	}
+}
+
+func TestConvertRepeatedObservationSetIDs(t *testing.T) {
+	//both OBR groups number their results from OBX|1, so the Observations share a set-ID-based id
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"OBR|1|ORD1||CBC^Complete Blood Count^LN\r" +
+		"OBX|1|NM|718-7^Hemoglobin^LN||14.2|g/dL|||||F\r" +
+		"OBR|2|ORD2||BMP^Basic Metabolic Panel^LN\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|||||F")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	bundle, err := ConvertToBundle(msg)
+	if err != nil {
+		t.Fatalf("ConvertToBundle() returned error: %v", err)
+	}
+
+	var codes []string
+	fullURLs := make(map[string]bool)
+	for _, entry := range bundle.Entry {
+		if observation, ok := entry.Resource.(*fhir.Oberservation); ok {
+			codes = append(codes, observation.Code.Coding[0].Code)
+			fullURLs[entry.FullURL] = true
+		}
+	}
+	if len(codes) != 2 || codes[0] != "718-7" || codes[1] != "2345-7" {
+		t.Fatalf("Expected Observations 718-7 and 2345-7, got %v", codes)
+	}
+	if len(fullURLs) != 2 {
+		t.Errorf("Expected distinct fullUrls for both Observations, got %v", fullURLs)
+	}
+}
+
+func TestExternalHandlerUsesContext(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231115||ZPM^Z01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	registry := NewRegistry()
+	registry.Register("ZPM", "", "", func(cc *ConversionContext) error {
+		patient, err := ConvertToPatient(cc)
+		if err != nil {
+			return err
+		}
+		if err := cc.AddResource(patient); err != nil {
+			return err
+		}
+		return cc.AddResource(&fhir.Condition{ResourceType: "Condition", ID: "condition-1", Subject: cc.PatientReference()})
+	})
+
+	opts := DefaultConvertOptions()
+	opts.Registry = registry
+	bundle, err := ConvertToBundleWithOptions(msg, opts)
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithOptions() returned error: %v", err)
+	}
+	if unresolved := bundle.UnresolvedReferences(); len(unresolved) != 0 {
+		t.Errorf("Expected the handler's references to resolve, got %v", unresolved)
+	}
+}
+
+func TestConvertToBundleContextDeadline(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231115||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if _, _, err := ConvertToBundleContext(ctx, msg, DefaultConvertOptions()); !errors.Is(err, hl7.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := ConvertToBundleContext(ctx, msg, DefaultConvertOptions()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
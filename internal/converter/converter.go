@@ -1,22 +1,36 @@
 package converter
 
 import (
-	"github.com/mourice12/hl7-to-fhir/internal/fhir"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
 // ConvertPatuebt converts an HL7 message to a FHIR patient
-func ConvertToPatient(msg *hl7.Message) (*fhir.Patient, error) {
+func ConvertToPatient(cc *ConversionContext) (*fhir.Patient, error) {
+	msg := cc.Message
 	pid := msg.GetSegment("PID")
 	if pid == nil {
 		return nil, nil
 	}
 
+	patient := buildPatient(cc, pid)
+
+	//Without PID-3 the Patient still needs an id for the other resources to reference
+	if patient.ID == "" {
+		cc.ReportSegment(pid, 3, SeverityWarning, IssueRequired, "PID-3 has no patient identifier; the Patient gets an id scoped to the message")
+		patient.ID = "patient-" + messageControlID(msg)
+	}
+	cc.RecordSegment(patient, pid)
+	return patient, nil
+}
+
+// buildPatient converts a PID segment to a FHIR Patient
+func buildPatient(cc *ConversionContext, pid *hl7.Segment) *fhir.Patient {
 	patient := &fhir.Patient{
 		ResourceType: "Patient",
 		ID:           pid.GetField(3).GetCompontent(1),
 		Gender:       mapGender(pid.GetField(8).GetCompontent(1)),
-		BirthDate:    formatDate(pid.GetField(7).GetCompontent(1)),
+		BirthDate:    checkedDate(cc, pid, 7, pid.GetField(7).GetCompontent(1)),
 	}
 
 	//build Identifiers
@@ -32,7 +46,17 @@ func ConvertToPatient(msg *hl7.Message) (*fhir.Patient, error) {
 	//Build address
 	patient.Address = buildAddresses(pid)
 
-	return patient, nil
+	//Where the elements came from, for the lineage report
+	cc.RecordSource(patient, "id", pid, 3)
+	cc.RecordSource(patient, "identifier", pid, 3)
+	cc.RecordSource(patient, "name", pid, 5)
+	cc.RecordSource(patient, "birthDate", pid, 7)
+	cc.RecordSource(patient, "gender", pid, 8)
+	cc.RecordSource(patient, "address", pid, 11)
+	cc.RecordSource(patient, "telecom", pid, 13)
+	cc.RecordSource(patient, "telecom", pid, 14)
+
+	return patient
 }
 
 // mapGender converts HL7 gender codes to fhir
@@ -49,21 +73,16 @@ func mapGender(hl7Gender string) string {
 	}
 }
 
-// formatDate converts HL7 date (YYYYMMDD) to FHIR date (YYYY-MM-DD)
-func formatDate(hl7Date string) string {
-	if len(hl7Date) < 8 {
-		return ""
-	}
-	return hl7Date[0:4] + "-" + hl7Date[4:6] + "-" + hl7Date[6:8]
-
-}
-
 //buildNames extracts names from PID
 
 func buildNames(pid *hl7.Segment) []fhir.HumanName {
+	return buildXPNNames(pid.GetField(5))
+}
+
+// buildXPNNames converts a repeating XPN field such as PID-5 or MRG-7 to HumanNames
+func buildXPNNames(nameField *hl7.Field) []fhir.HumanName {
 	names := []fhir.HumanName{}
 
-	nameField := pid.GetField(5)
 	if nameField == nil {
 		return names
 	}
@@ -182,9 +201,26 @@ func getComponentValue(rep hl7.Repetition, index int) string {
 
 // buildIdentifiers extracts patient identifiers from PID-3
 func buildIdentifiers(pid *hl7.Segment) []fhir.Identifier {
+	return buildCXIdentifiers(pid.GetField(3))
+}
+
+// applyAssigningAuthority reads the HD assigning authority (namespace&universal ID&type) in a component of a CX or
+// XCN repetition the way buildEIIdentifier reads an EI: only an ISO universal ID becomes the system, and the
+// namespace is kept as the assigner
+func applyAssigningAuthority(identifier *fhir.Identifier, rep hl7.Repetition, component int) {
+	if component < 1 || component > len(rep.Components) {
+		return
+	}
+	hd := rep.Components[component-1]
+	if authority := buildEIIdentifier(identifier.Value, hd.GetCompontent(1), hd.GetCompontent(2), hd.GetCompontent(3)); authority != nil {
+		identifier.System, identifier.Assigner = authority.System, authority.Assigner
+	}
+}
+
+// buildCXIdentifiers converts a repeating CX field such as PID-3 or MRG-1 to Identifiers
+func buildCXIdentifiers(idField *hl7.Field) []fhir.Identifier {
 	identifiers := []fhir.Identifier{}
 
-	idField := pid.GetField(3)
 	if idField == nil {
 		return identifiers
 	}
@@ -197,10 +233,7 @@ func buildIdentifiers(pid *hl7.Segment) []fhir.Identifier {
 		}
 
 		// PID-3.4 is the assigning authority
-		authority := getComponentValue(rep, 4)
-		if authority != "" {
-			id.System = "urn:oid:" + authority
-		}
+		applyAssigningAuthority(&id, rep, 4)
 
 		// PID-3.5 is the identifier type code
 		typeCode := getComponentValue(rep, 5)
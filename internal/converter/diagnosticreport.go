@@ -1,15 +1,15 @@
 package converter
 
 import (
-	"fmt"
 	"time"
 
-	"github.com/mourice12/hl7-to-fhir/internal/fhir"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
 // ConvertToDiagnosticReports converts OBR segment to FHIR DiagnosticReport
-func ConvertToDiagnosticReport(msg *hl7.Message, patientID string) (*fhir.DiagnosticReport, error) {
+func ConvertToDiagnosticReport(cc *ConversionContext) (*fhir.DiagnosticReport, error) {
+	msg := cc.Message
 	obrSegment := msg.GetSegment("OBR")
 	if obrSegment == nil {
 		return nil, nil
@@ -20,10 +20,34 @@ func ConvertToDiagnosticReport(msg *hl7.Message, patientID string) (*fhir.Diagno
 		ID:           getOBRID(obrSegment),
 		Status:       mapOBRStatus(obrSegment),
 		Code:         getOBRCode(obrSegment),
-		Subject:      &fhir.Reference{Reference: "Patient/" + patientID},
+		Subject:      cc.PatientReference(),
 		Issued:       getOBRDateTime(obrSegment),
 	}
+	cc.RecordSource(report, "status", obrSegment, 25)
+	cc.RecordSource(report, "code", obrSegment, 4)
+	cc.RecordSource(report, "issued", obrSegment, 7)
+
+	//ORC-2/3 or OBR-2/3 placer and filler order numbers
+	orc := orderControls(msg, "OBR")[obrSegment]
+	report.Identifier = buildServiceRequestIdentifiers(obrSegment, orc)
+	for _, segment := range []*hl7.Segment{orc, obrSegment} {
+		cc.RecordSource(report, "identifier", segment, 2)
+		cc.RecordSource(report, "identifier", segment, 3)
+	}
+
+	//Link to the order the report fulfils
+	report.BasedOn = []fhir.Reference{{
+		Reference: "ServiceRequest/" + serviceRequestID(obrSegment, orc),
+	}}
+
+	//SPM or OBR-15 specimens of this OBR
+	for _, group := range specimenGroups(msg) {
+		if group.Head == obrSegment {
+			report.Specimen = specimenReferences(group)
+		}
+	}
 
+	cc.RecordSegment(report, obrSegment)
 	return report, nil
 }
 
@@ -123,21 +147,12 @@ func getOBRDateTime(seg *hl7.Segment) string {
 		return time.Now().Format(time.RFC3339)
 	}
 
-	//Parse HL7 datetime
+	//Parse HL7 datetime, keeping its offset
+	if instant := fhir.FormatInstant(dateTime); instant != "" {
+		return instant
+	}
 	if len(dateTime) >= 8 {
-		// Simple conversion: YYYYMMDD -> YYYY-MM-DD
-		year := dateTime[0:4]
-		month := dateTime[4:6]
-		day := dateTime[6:8]
-
-		if len(dateTime) >= 14 {
-			hour := dateTime[8:10]
-			minute := dateTime[10:12]
-			second := dateTime[12:14]
-			return fmt.Sprintf("%s-%s-%sT%s:%s:%sZ", year, month, day, hour, minute, second)
-		}
-
-		return fmt.Sprintf("%s-%s-%sT00:00:00Z", year, month, day)
+		return fhir.FormatInstant(dateTime[0:8] + "0000")
 	}
 
 	return time.Now().Format(time.RFC3339)
@@ -0,0 +1,361 @@
+package converter
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// maxInlineAttachmentSize is the largest decoded ED payload kept inline; larger payloads become Binary entries
+const maxInlineAttachmentSize = 64 * 1024
+
+// ConvertToDocumentReferences converts MDM (TXA + OBX) and ORU text or ED reports to FHIR DocumentReferences
+func ConvertToDocumentReferences(cc *ConversionContext) ([]*fhir.DocumentReference, []*fhir.Binary, []*fhir.Practitioner, error) {
+	msg := cc.Message
+	var documents []*fhir.DocumentReference
+	var binaries []*fhir.Binary
+	var practitioners []*fhir.Practitioner
+
+	subject := cc.PatientReference()
+	var context *fhir.DocumentReferenceContext
+	if encounter := cc.EncounterReference(); encounter != nil {
+		context = &fhir.DocumentReferenceContext{
+			Encounter: []fhir.Reference{*encounter},
+		}
+	}
+
+	//MDM: one document described by TXA, with its body in the OBX segments
+	if txa := msg.GetSegment("TXA"); txa != nil {
+		document, authors := buildDocumentFromTXA(cc, txa)
+		for _, author := range authors {
+			practitioners = addPractitioner(practitioners, author)
+		}
+
+		content, documentBinaries := buildDocumentContent(document.ID, msg.GetSegments("OBX"), msg.Delimiters)
+		document.Content = content
+		document.Subject = subject
+		document.Context = context
+
+		cc.RecordSegment(document, txa)
+		documents = append(documents, document)
+		binaries = append(binaries, documentBinaries...)
+		return documents, binaries, practitioners, nil
+	}
+
+	//ORU: one document per OBR whose results are narrative text or encapsulated data
+	orders := orderControls(msg, "OBR")
+	for _, group := range msg.GetGroups("OBR", "OBX") {
+		obxSegments := group.GetSegments("OBX")
+		if !isDocumentGroup(obxSegments) {
+			continue
+		}
+
+		obr := group.Head
+		document := &fhir.DocumentReference{
+			ResourceType: "DocumentReference",
+			ID:           "document-" + strings.TrimPrefix(serviceRequestID(obr, orders[obr]), "servicerequest-"),
+			Status:       "current",
+			DocStatus:    mapDocumentStatusFromOBR(obr),
+			Type:         getOBRCode(obr),
+			Subject:      subject,
+			Date:         checkedInstant(cc, obr, 7, obr.GetField(7).GetCompontent(1)),
+			Context:      context,
+		}
+
+		//OBR-32 Principal Result Interpreter
+		if author := buildPractitionerFromCNN(obr.GetField(32)); author != nil {
+			practitioners = addPractitioner(practitioners, author)
+			document.Author = []fhir.Reference{{
+				Reference: "Practitioner/" + author.ID,
+				Display:   practitionerDisplay(author),
+			}}
+		}
+
+		content, documentBinaries := buildDocumentContent(document.ID, obxSegments, msg.Delimiters)
+		document.Content = content
+
+		cc.RecordSegment(document, obr)
+		documents = append(documents, document)
+		binaries = append(binaries, documentBinaries...)
+	}
+
+	return documents, binaries, practitioners, nil
+}
+
+// buildDocumentFromTXA converts TXA header fields and returns the authors it references
+func buildDocumentFromTXA(cc *ConversionContext, txa *hl7.Segment) (*fhir.DocumentReference, []*fhir.Practitioner) {
+	var authors []*fhir.Practitioner
+
+	document := &fhir.DocumentReference{
+		ResourceType: "DocumentReference",
+		ID:           "document-" + txa.GetField(1).GetCompontent(1),
+		Status:       mapDocumentAvailability(txa.GetField(19).GetCompontent(1)),
+		DocStatus:    mapDocumentCompletion(txa.GetField(17).GetCompontent(1)),
+	}
+
+	//TXA-12 Unique Document Number (EI)
+	numberField := txa.GetField(12)
+	if number := buildEIIdentifier(numberField.GetCompontent(1), numberField.GetCompontent(2), numberField.GetCompontent(3), numberField.GetCompontent(4)); number != nil {
+		document.MasterIdentifier = number
+		document.ID = "document-" + number.Value
+	}
+
+	//TXA-2 Document Type (HL7 table 0270)
+	docType := txa.GetField(2).GetCompontent(1)
+	if docType != "" {
+		document.Type = &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{
+				System:  "http://terminology.hl7.org/CodeSystem/v2-0270",
+				Code:    docType,
+				Display: documentTypeDisplay(docType),
+			}},
+		}
+	}
+
+	//TXA-4 Activity DateTime
+	document.Date = checkedInstant(cc, txa, 4, txa.GetField(4).GetCompontent(1))
+
+	//TXA-9 Originator Code/Name
+	if field := txa.GetField(9); field != nil {
+		for _, rep := range field.Repetitions {
+			author := buildPractitioner(rep)
+			if author == nil {
+				continue
+			}
+			authors = append(authors, author)
+			document.Author = append(document.Author, fhir.Reference{
+				Reference: "Practitioner/" + author.ID,
+				Display:   practitionerDisplay(author),
+			})
+		}
+	}
+
+	//TXA-22 Authentication Person, Time Stamp
+	if field := txa.GetField(22); field != nil && len(field.Repetitions) > 0 {
+		if authenticator := buildPractitioner(field.Repetitions[0]); authenticator != nil {
+			authors = append(authors, authenticator)
+			document.Authenticator = &fhir.Reference{
+				Reference: "Practitioner/" + authenticator.ID,
+				Display:   practitionerDisplay(authenticator),
+			}
+		}
+	}
+
+	return document, authors
+}
+
+// buildDocumentContent joins TX/FT/ST OBX lines into one text attachment and decodes each ED OBX
+func buildDocumentContent(documentID string, obxSegments []*hl7.Segment, delim hl7.Delimiters) ([]fhir.DocumentReferenceContent, []*fhir.Binary) {
+	var content []fhir.DocumentReferenceContent
+	var binaries []*fhir.Binary
+	var lines []string
+
+	for _, obx := range obxSegments {
+		valueField := obx.GetField(5)
+		if valueField == nil {
+			continue
+		}
+
+		switch obx.GetField(2).GetCompontent(1) {
+		case "TX", "FT", "ST":
+			//Each repetition of OBX-5 is a line of text
+			for _, rep := range valueField.Repetitions {
+				lines = append(lines, hl7.Unescape(getComponentValue(rep, 1), delim))
+			}
+		case "ED":
+			data, contentType, ok := decodeEncapsulatedData(valueField, delim)
+			if !ok {
+				continue
+			}
+
+			attachment := buildAttachment(contentType, data)
+			if len(data) > maxInlineAttachmentSize {
+				binary := &fhir.Binary{
+					ResourceType: "Binary",
+					ID:           "binary-" + strings.TrimPrefix(documentID, "document-") + "-" + strconv.Itoa(len(binaries)+1),
+					ContentType:  contentType,
+					Data:         attachment.Data,
+				}
+				binaries = append(binaries, binary)
+				attachment.Data = ""
+				attachment.URL = "Binary/" + binary.ID
+			}
+			content = append(content, fhir.DocumentReferenceContent{Attachment: attachment})
+		}
+	}
+
+	if len(lines) > 0 {
+		text := buildAttachment("text/plain", []byte(strings.Join(lines, "\n")))
+		content = append([]fhir.DocumentReferenceContent{{Attachment: text}}, content...)
+	}
+
+	return content, binaries
+}
+
+// decodeEncapsulatedData decodes an ED value (source^type^subtype^encoding^data)
+func decodeEncapsulatedData(field *hl7.Field, delim hl7.Delimiters) ([]byte, string, bool) {
+	dataType := field.GetCompontent(2)
+	subtype := field.GetCompontent(3)
+	encoding := field.GetCompontent(4)
+	raw := field.GetCompontent(5)
+
+	if raw == "" {
+		return nil, "", false
+	}
+
+	var data []byte
+	var err error
+	switch strings.ToUpper(encoding) {
+	case "BASE64":
+		//Base64 payloads are often wrapped or padded inconsistently
+		cleaned := strings.Join(strings.Fields(raw), "")
+		data, err = base64.StdEncoding.DecodeString(cleaned)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(cleaned, "="))
+		}
+	case "HEX":
+		data, err = hex.DecodeString(raw)
+	default: // A: plain ASCII
+		data = []byte(hl7.Unescape(raw, delim))
+	}
+	if err != nil {
+		return nil, "", false
+	}
+
+	return data, mapEDContentType(dataType, subtype), true
+}
+
+// mapEDContentType converts ED type of data (HL7 table 0191) and subtype to a MIME type
+func mapEDContentType(dataType, subtype string) string {
+	mainType := "application"
+	switch strings.ToUpper(dataType) {
+	case "IM", "IMAGE":
+		mainType = "image"
+	case "TX", "TEXT":
+		mainType = "text"
+	case "AU", "AUDIO":
+		mainType = "audio"
+	}
+
+	if subtype == "" {
+		if mainType == "text" {
+			return "text/plain"
+		}
+		return "application/octet-stream"
+	}
+	if strings.Contains(subtype, "/") {
+		return strings.ToLower(subtype)
+	}
+	return mainType + "/" + strings.ToLower(subtype)
+}
+
+// buildAttachment builds an inline attachment with size and SHA-1 hash
+func buildAttachment(contentType string, data []byte) fhir.Attachment {
+	hash := sha1.Sum(data)
+	return fhir.Attachment{
+		ContentType: contentType,
+		Data:        base64.StdEncoding.EncodeToString(data),
+		Size:        len(data),
+		Hash:        base64.StdEncoding.EncodeToString(hash[:]),
+	}
+}
+
+// isDocumentGroup reports whether an OBR's results are a document: any ED, or only narrative text
+func isDocumentGroup(obxSegments []*hl7.Segment) bool {
+	if len(obxSegments) == 0 {
+		return false
+	}
+
+	narrative := true
+	for _, obx := range obxSegments {
+		switch obx.GetField(2).GetCompontent(1) {
+		case "ED":
+			return true
+		case "TX", "FT":
+		default:
+			narrative = false
+		}
+	}
+	return narrative
+}
+
+// mapDocumentCompletion converts TXA-17 document completion status to docStatus
+func mapDocumentCompletion(status string) string {
+	switch status {
+	case "AU", "LA": // Authenticated, legally authenticated
+		return "final"
+	case "DI", "DO", "IP", "IN", "PA": // Dictated, documented, in progress, incomplete, pre-authenticated
+		return "preliminary"
+	default:
+		return ""
+	}
+}
+
+// mapDocumentAvailability converts TXA-19 document availability status to status
+func mapDocumentAvailability(status string) string {
+	switch status {
+	case "OB", "RE": // Obsolete, replaced
+		return "superseded"
+	case "CA": // Canceled
+		return "entered-in-error"
+	default:
+		return "current"
+	}
+}
+
+// mapDocumentStatusFromOBR converts OBR-25 result status to docStatus
+func mapDocumentStatusFromOBR(obr *hl7.Segment) string {
+	switch obr.GetField(25).GetCompontent(1) {
+	case "F":
+		return "final"
+	case "C":
+		return "amended"
+	case "P", "I":
+		return "preliminary"
+	default:
+		return ""
+	}
+}
+
+// documentTypeDisplay returns the display text for HL7 table 0270 document types
+func documentTypeDisplay(code string) string {
+	displays := map[string]string{
+		"AR": "Autopsy report",
+		"CD": "Cardiodiagnostics",
+		"CN": "Consultation",
+		"DI": "Diagnostic imaging",
+		"DS": "Discharge summary",
+		"ED": "Emergency department report",
+		"HP": "History and physical examination",
+		"OP": "Operative report",
+		"PC": "Psychiatric consultation",
+		"PH": "Psychiatric history and physical examination",
+		"PN": "Procedure note",
+		"PR": "Progress note",
+		"SP": "Surgical pathology",
+		"TS": "Transfer summary",
+	}
+	return displays[code]
+}
+
+// buildPractitionerFromCNN converts a CNN field (ID&Family&Given) to a Practitioner
+func buildPractitionerFromCNN(field *hl7.Field) *fhir.Practitioner {
+	id := field.GetSubcomponent(1, 1)
+	family := field.GetSubcomponent(1, 2)
+	if id == "" && family == "" {
+		return nil
+	}
+
+	rep := hl7.Repetition{Components: []hl7.Component{
+		{Subcomponents: []string{id}},
+		{Subcomponents: []string{family}},
+		{Subcomponents: []string{field.GetSubcomponent(1, 3)}},
+		{Subcomponents: []string{field.GetSubcomponent(1, 4)}},
+	}}
+	return buildPractitioner(rep)
+}
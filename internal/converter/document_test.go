@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestConvertToDocumentReferences(t *testing.T) {
+	cc := newSampleContext(t, "sample-mdm.hl7")
+	encounter, err := ConvertToEncounter(cc)
+	if err != nil || encounter == nil {
+		t.Fatalf("ConvertToEncounter() returned %v, %v", encounter, err)
+	}
+	cc.AddResource(encounter)
+
+	documents, binaries, practitioners, err := ConvertToDocumentReferences(cc)
+	if err != nil {
+		t.Fatalf("ConvertToDocumentReferences() returned error: %v", err)
+	}
+	if len(documents) != 1 || len(binaries) != 0 || len(practitioners) != 2 {
+		t.Fatalf("Expected 1 document, no binaries and 2 practitioners, got %d, %d and %d", len(documents), len(binaries), len(practitioners))
+	}
+
+	document := documents[0]
+	if document.MasterIdentifier == nil || document.Type == nil || len(document.Author) != 1 || document.Authenticator == nil ||
+		document.Context == nil || len(document.Context.Encounter) != 1 || len(document.Content) != 2 {
+		t.Fatalf("Expected every sample field to be converted, got %+v", document)
+	}
+
+	text, err := base64.StdEncoding.DecodeString(document.Content[0].Attachment.Data)
+	if err != nil {
+		t.Fatalf("Expected base64 text content: %v", err)
+	}
+	pdf, err := base64.StdEncoding.DecodeString(document.Content[1].Attachment.Data)
+	if err != nil {
+		t.Fatalf("Expected base64 PDF content: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id from TXA-12", document.ID, "document-DOC-88231"},
+		{"TXA-12 master identifier", document.MasterIdentifier.Value, "DOC-88231"},
+		{"TXA-12 assigner", document.MasterIdentifier.Assigner.Display, "TRANS"},
+		{"TXA-19 status", document.Status, "current"},
+		{"TXA-17 doc status", document.DocStatus, "final"},
+		{"TXA-2 type", document.Type.Coding[0].Code, "DS"},
+		{"TXA-2 display", document.Type.Coding[0].Display, "Discharge summary"},
+		{"TXA-4 date", document.Date, "2023-11-16T08:30:00"},
+		{"TXA-9 author", document.Author[0].Reference, "Practitioner/practitioner-1234567"},
+		{"TXA-22 authenticator", document.Authenticator.Reference, "Practitioner/practitioner-9876543"},
+		{"subject", document.Subject.Reference, "Patient/583295"},
+		{"encounter", document.Context.Encounter[0].Reference, "Encounter/" + encounter.ID},
+		{"TX content type", document.Content[0].Attachment.ContentType, "text/plain"},
+		{"TX lines with \\.br\\ unescaped", string(text), "Patient admitted with community acquired pneumonia.\nTreated with IV antibiotics\nDischarged in stable condition."},
+		{"ED content type", document.Content[1].Attachment.ContentType, "application/pdf"},
+		{"ED data", string(pdf), "%PDF-1.4 test document"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, test.got)
+		}
+	}
+}
+
+func TestMapDocumentStatus(t *testing.T) {
+	completion := map[string]string{"AU": "final", "LA": "final", "DI": "preliminary", "IP": "preliminary", "": ""}
+	for code, want := range completion {
+		if got := mapDocumentCompletion(code); got != want {
+			t.Errorf("mapDocumentCompletion(%q) = %q, want %q", code, got, want)
+		}
+	}
+
+	availability := map[string]string{"AV": "current", "OB": "superseded", "RE": "superseded", "CA": "entered-in-error"}
+	for code, want := range availability {
+		if got := mapDocumentAvailability(code); got != want {
+			t.Errorf("mapDocumentAvailability(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
@@ -1,12 +1,13 @@
 package converter
 
 import (
-	"github.com/mourice12/hl7-to-fhir/internal/fhir"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
 // ConvertToEncounter converts PV1 segment to FHIR encounter
-func ConvertToEncounter(msg *hl7.Message, patientID string) (*fhir.Encounter, error) {
+func ConvertToEncounter(cc *ConversionContext) (*fhir.Encounter, error) {
+	msg := cc.Message
 	pv1 := msg.GetSegment("PV1")
 	if pv1 == nil {
 		return nil, nil
@@ -16,20 +17,26 @@ func ConvertToEncounter(msg *hl7.Message, patientID string) (*fhir.Encounter, er
 		ResourceType: "Encounter",
 		ID:           pv1.GetField(19).GetCompontent(1),
 		Status:       "finished",
-		Subject: &fhir.Reference{
-			Reference: "Patient/" + patientID,
-		},
+		Subject:      cc.PatientReference(),
 	}
 
+	cc.RecordSource(encounter, "id", pv1, 19)
+
+	//PID-18 Patient Account Number
+	encounter.Account = accountReferences(msg)
+
 	//PV1-2 Patient Class
 	patientClass := pv1.GetField(2).GetCompontent(1)
 	encounter.Class = mapPatientClass(patientClass)
+	cc.RecordSource(encounter, "class.code", pv1, 2)
 
 	//PV1-3 Assigned Location
 	location := buildLocation(pv1)
 	if location != nil {
 		encounter.Location = []fhir.EncounterLocation{*location}
 	}
+	cc.RecordSource(encounter, "location", pv1, 3)
+	cc.RecordSource(encounter, "location", pv1, 6)
 
 	//PV1-7 Attending Doctor
 
@@ -37,6 +44,7 @@ func ConvertToEncounter(msg *hl7.Message, patientID string) (*fhir.Encounter, er
 	if attendingDoc != nil {
 		encounter.Participant = []fhir.Participant{*attendingDoc}
 	}
+	cc.RecordSource(encounter, "participant", pv1, 7)
 
 	//PV1-44 Admit DateTime
 	admitField := pv1.GetField(44)
@@ -44,14 +52,227 @@ func ConvertToEncounter(msg *hl7.Message, patientID string) (*fhir.Encounter, er
 		admitDate := admitField.GetCompontent(1)
 		if admitDate != "" {
 			encounter.Period = &fhir.Period{
-				Start: formatDateTime(admitDate),
+				Start: checkedDateTime(cc, pv1, 44, admitDate),
 			}
 		}
 	}
 
+	//MSH-9.2/EVN-1 Trigger Event drives status, period, location and history
+	applyEncounterEvent(cc, pv1, encounter)
+
+	//Periods and histories are timed by PV1-44/45 or the event
+	evn, msh := msg.GetSegment("EVN"), msg.GetSegment("MSH")
+	for _, element := range []string{"period", "statusHistory", "classHistory", "location"} {
+		cc.RecordSource(encounter, element, pv1, 44)
+		cc.RecordSource(encounter, element, pv1, 45)
+		cc.RecordSource(encounter, element, evn, 6)
+		cc.RecordSource(encounter, element, evn, 2)
+		cc.RecordSource(encounter, element, msh, 7)
+	}
+
+	cc.RecordSegment(encounter, pv1)
 	return encounter, nil
 }
 
+// applyEncounterEvent sets status, period, locations, statusHistory and classHistory from the ADT trigger event
+func applyEncounterEvent(cc *ConversionContext, pv1 *hl7.Segment, encounter *fhir.Encounter) {
+	msg := cc.Message
+	messageCode, trigger := messageType(msg)
+	if messageCode != "ADT" {
+		trigger = ""
+	} else if trigger == "" {
+		//EVN-1 Event Type Code, for senders that leave MSH-9.2 empty
+		if evn := msg.GetSegment("EVN"); evn != nil {
+			trigger = evn.GetField(1).GetCompontent(1)
+		}
+	}
+
+	admitted := ""
+	if encounter.Period != nil {
+		admitted = encounter.Period.Start
+	}
+	eventTime := fhir.FormatDateTime(eventDateTime(msg))
+
+	//PV1-45 Discharge DateTime
+	discharged := checkedDateTime(cc, pv1, 45, pv1.GetField(45).GetCompontent(1))
+
+	switch trigger {
+	case "A01", "A04": // Admit, register
+		encounter.Status = "in-progress"
+		encounter.StatusHistory = statusHistory("in-progress", firstNonEmpty(admitted, eventTime), "")
+	case "A05", "A14": // Pre-admit, pending admit
+		encounter.Status = "planned"
+		encounter.StatusHistory = statusHistory("planned", eventTime, "")
+	case "A03": // Discharge
+		encounter.Status = "finished"
+		end := firstNonEmpty(discharged, eventTime)
+		setPeriodEnd(encounter, end)
+		encounter.StatusHistory = append(
+			statusHistory("in-progress", admitted, end),
+			statusHistory("finished", end, "")...,
+		)
+	case "A02", "A17": // Transfer, swap patients
+		encounter.Status = "in-progress"
+		applyTransfer(pv1, encounter, eventTime)
+	case "A06": // Change outpatient to inpatient
+		encounter.Status = "in-progress"
+		encounter.ClassHistory = classHistory(mapPatientClass("O"), encounter.Class, admitted, eventTime)
+	case "A07": // Change inpatient to outpatient
+		encounter.Status = "in-progress"
+		encounter.ClassHistory = classHistory(mapPatientClass("I"), encounter.Class, admitted, eventTime)
+	case "A11", "A27", "A38": // Cancel admit, cancel pending admit, cancel pre-admit
+		encounter.Status = "cancelled"
+		encounter.StatusHistory = append(
+			statusHistory("in-progress", admitted, eventTime),
+			statusHistory("cancelled", eventTime, "")...,
+		)
+	case "A12": // Cancel transfer: PV1-3 is the location the patient returned to
+		encounter.Status = "in-progress"
+		for i := range encounter.Location {
+			encounter.Location[i].Period = &fhir.Period{Start: eventTime}
+		}
+	case "A13": // Cancel discharge
+		encounter.Status = "in-progress"
+		if encounter.Period != nil {
+			encounter.Period.End = ""
+		}
+		encounter.StatusHistory = statusHistory("in-progress", eventTime, "")
+	case "A21": // Leave of absence out
+		encounter.Status = "onleave"
+		encounter.StatusHistory = append(
+			statusHistory("in-progress", admitted, eventTime),
+			statusHistory("onleave", eventTime, "")...,
+		)
+	case "A22": // Return from leave of absence
+		encounter.Status = "in-progress"
+		encounter.StatusHistory = append(
+			statusHistory("onleave", "", eventTime),
+			statusHistory("in-progress", eventTime, "")...,
+		)
+	default: // A08 update, tracking and pending events, and non-ADT messages
+		encounter.Status = statusFromVisit(admitted, discharged)
+		if encounter.Status == "finished" {
+			setPeriodEnd(encounter, discharged)
+		}
+	}
+
+	//Record the class the encounter has now, unless a class change already did
+	if encounter.ClassHistory == nil && encounter.Class != nil && admitted != "" {
+		encounter.ClassHistory = []fhir.EncounterClassHistory{{
+			Class:  encounter.Class,
+			Period: &fhir.Period{Start: admitted, End: periodEnd(encounter)},
+		}}
+	}
+}
+
+// eventDateTime returns EVN-6 Event Occurred, falling back to EVN-2 Recorded DateTime and MSH-7
+func eventDateTime(msg *hl7.Message) string {
+	if evn := msg.GetSegment("EVN"); evn != nil {
+		occurred := evn.GetField(6).GetCompontent(1)
+		if occurred != "" {
+			return occurred
+		}
+		recorded := evn.GetField(2).GetCompontent(1)
+		if recorded != "" {
+			return recorded
+		}
+	}
+
+	if msh := msg.GetSegment("MSH"); msh != nil {
+		return msh.GetField(7).GetCompontent(1)
+	}
+	return ""
+}
+
+// statusFromVisit derives a status from PV1-44/45 when the trigger event does not say
+func statusFromVisit(admitted, discharged string) string {
+	switch {
+	case discharged != "":
+		return "finished"
+	case admitted != "":
+		return "in-progress"
+	default:
+		return "unknown"
+	}
+}
+
+// applyTransfer records PV1-6 Prior Patient Location as completed and PV1-3 as the active location
+func applyTransfer(pv1 *hl7.Segment, encounter *fhir.Encounter, eventTime string) {
+	var locations []fhir.EncounterLocation
+
+	prior := buildLocationFromField(pv1.GetField(6))
+	if prior != nil {
+		prior.Status = "completed"
+		prior.Period = &fhir.Period{End: eventTime}
+		locations = append(locations, *prior)
+	}
+
+	for _, current := range encounter.Location {
+		current.Period = &fhir.Period{Start: eventTime}
+		locations = append(locations, current)
+	}
+
+	encounter.Location = locations
+}
+
+// statusHistory builds a single statusHistory entry, or nil when there is no time to anchor it
+func statusHistory(status, start, end string) []fhir.EncounterStatusHistory {
+	if start == "" && end == "" {
+		return nil
+	}
+	return []fhir.EncounterStatusHistory{{
+		Status: status,
+		Period: &fhir.Period{Start: start, End: end},
+	}}
+}
+
+// classHistory records the class before and after a class change event
+func classHistory(prior, current *fhir.Coding, start, changed string) []fhir.EncounterClassHistory {
+	var history []fhir.EncounterClassHistory
+	if prior != nil {
+		history = append(history, fhir.EncounterClassHistory{
+			Class:  prior,
+			Period: &fhir.Period{Start: start, End: changed},
+		})
+	}
+	if current != nil {
+		history = append(history, fhir.EncounterClassHistory{
+			Class:  current,
+			Period: &fhir.Period{Start: changed},
+		})
+	}
+	return history
+}
+
+// setPeriodEnd sets Encounter.period.end, creating the period if needed
+func setPeriodEnd(encounter *fhir.Encounter, end string) {
+	if end == "" {
+		return
+	}
+	if encounter.Period == nil {
+		encounter.Period = &fhir.Period{}
+	}
+	encounter.Period.End = end
+}
+
+// periodEnd returns Encounter.period.end, or "" when there is no period
+func periodEnd(encounter *fhir.Encounter) string {
+	if encounter.Period == nil {
+		return ""
+	}
+	return encounter.Period.End
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
 //mapPatientClass converts HL7 Patient Class to FHIR
 
 func mapPatientClass(hl7class string) *fhir.Coding {
@@ -78,7 +299,11 @@ func mapPatientClass(hl7class string) *fhir.Coding {
 
 // buildLocation extracts location from PV1-3
 func buildLocation(pv1 *hl7.Segment) *fhir.EncounterLocation {
-	locField := pv1.GetField(3)
+	return buildLocationFromField(pv1.GetField(3))
+}
+
+// buildLocationFromField extracts a location from a PL field such as PV1-3 or PV1-6
+func buildLocationFromField(locField *hl7.Field) *fhir.EncounterLocation {
 	if locField == nil {
 		return nil
 	}
@@ -92,17 +317,9 @@ func buildLocation(pv1 *hl7.Segment) *fhir.EncounterLocation {
 		return nil
 	}
 
-	locationDisplay := unit
-	if room != "" {
-		locationDisplay += " Room " + room
-	}
-	if bed != "" {
-		locationDisplay += " Bed " + bed
-	}
-
 	return &fhir.EncounterLocation{
 		Location: &fhir.Reference{
-			Display: locationDisplay,
+			Display: locationDisplay(unit, room, bed),
 		},
 		Status: "active",
 	}
@@ -141,20 +358,3 @@ func buildAttendingDoctor(pv1 *hl7.Segment) *fhir.Participant {
 		},
 	}
 }
-
-// formatDateTime converts HL7 datetime to FHIR Format
-func formatDateTime(hl7DateTime string) string {
-	if len(hl7DateTime) < 8 {
-		return ""
-	}
-
-	//Basic YYYY-MM-DD
-	result := hl7DateTime[0:4] + "-" + hl7DateTime[4:6] + "-" + hl7DateTime[6:8]
-
-	//add time if present
-	if len(hl7DateTime) >= 12 {
-		result += "T" + hl7DateTime[8:10] + ":" + hl7DateTime[10:12] + ":00"
-	}
-
-	return result
-}
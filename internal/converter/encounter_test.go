@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToEncounterTriggerEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		trigger   string
+		pv1       string
+		status    string
+		periodEnd string
+		locations int
+	}{
+		{"admit", "A01", "PV1|1|I|ICU^0101^01||||||||||||||||V100|||||||||||||||||||||||||20231115080000", "in-progress", "", 1},
+		{"discharge", "A03", "PV1|1|I|ICU^0101^01||||||||||||||||V100|||||||||||||||||||||||||20231115080000|20231118140000", "finished", "2023-11-18T14:00:00", 1},
+		{"transfer", "A02", "PV1|1|I|ICU^0102^01|||MED^0201^02|||||||||||||V100|||||||||||||||||||||||||20231115080000", "in-progress", "", 2},
+		{"cancel admit", "A11", "PV1|1|I|ICU^0101^01||||||||||||||||V100|||||||||||||||||||||||||20231115080000", "cancelled", "", 1},
+		{"leave of absence", "A21", "PV1|1|I|ICU^0101^01||||||||||||||||V100|||||||||||||||||||||||||20231115080000", "onleave", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := "MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231116120000||ADT^" + tt.trigger + "|MSG001|P|2.5\r" +
+				"EVN|" + tt.trigger + "|20231116120000\r" +
+				"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+				tt.pv1
+
+			msg, err := hl7.Parse(raw)
+			if err != nil {
+				t.Fatalf("Parse() returned error: %v", err)
+			}
+
+			encounter, err := ConvertToEncounter(newTestContext(msg, &fhir.Patient{ResourceType: "Patient", ID: "12345"}))
+			if err != nil {
+				t.Fatalf("ConvertToEncounter() returned error: %v", err)
+			}
+
+			if encounter.Status != tt.status {
+				t.Errorf("Expected status %s, got %s", tt.status, encounter.Status)
+			}
+
+			if periodEnd(encounter) != tt.periodEnd {
+				t.Errorf("Expected period end %q, got %q", tt.periodEnd, periodEnd(encounter))
+			}
+
+			if len(encounter.Location) != tt.locations {
+				t.Fatalf("Expected %d locations, got %d", tt.locations, len(encounter.Location))
+			}
+
+			if len(encounter.ClassHistory) == 0 {
+				t.Errorf("Expected classHistory to be populated")
+			}
+		})
+	}
+}
+
+func TestConvertToEncounterTransferHistory(t *testing.T) {
+	raw := "MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231116120000||ADT^A02|MSG001|P|2.5\r" +
+		"EVN|A02|20231116120000\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"PV1|1|I|ICU^0102^01|||MED^0201^02|||||||||||||V100"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	encounter, err := ConvertToEncounter(newTestContext(msg, &fhir.Patient{ResourceType: "Patient", ID: "12345"}))
+	if err != nil {
+		t.Fatalf("ConvertToEncounter() returned error: %v", err)
+	}
+	if len(encounter.Location) != 2 {
+		t.Fatalf("Expected 2 locations, got %d", len(encounter.Location))
+	}
+
+	prior := encounter.Location[0]
+	if prior.Status != "completed" || prior.Period == nil || prior.Period.End != "2023-11-16T12:00:00" {
+		t.Errorf("Expected prior location completed at 2023-11-16T12:00:00, got %+v", prior)
+	}
+
+	current := encounter.Location[1]
+	if current.Status != "active" || current.Period == nil || current.Period.Start != "2023-11-16T12:00:00" {
+		t.Errorf("Expected current location active from 2023-11-16T12:00:00, got %+v", current)
+	}
+}
@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// assignFullURLs gives every entry a UUIDv5 fullUrl derived from the sender and the resource's identifier,
+// then rewrites Type/id references between entries to those urn:uuid values. Relative references that resolve to no
+// entry are reported and left out, so the bundle a server receives is still consistent; absolute URLs and urns are
+// kept as sent.
+func assignFullURLs(cc *ConversionContext) {
+	bundle, msg := cc.Bundle, cc.Message
+	sender := messageSender(msg)
+	fullURLs := map[string]string{}
+	used := map[string]bool{}
+
+	for i := range bundle.Entry {
+		entry := &bundle.Entry[i]
+		if entry.Resource == nil {
+			continue
+		}
+		resourceType, id, identifiers := entry.Resource.GetResourceType(), entry.Resource.GetID(), entry.Resource.GetIdentifiers()
+
+		//Prefer the business identifier so the same resource gets the same fullUrl from every message
+		name := sender + "|" + resourceType + "|" + identifierQuery(identifiers)
+		fullURL := "urn:uuid:" + fhir.UUIDv5(fhir.NamespaceURL, name)
+		if identifierQuery(identifiers) == "" || used[fullURL] {
+			fullURL = "urn:uuid:" + fhir.UUIDv5(fhir.NamespaceURL, sender+"|"+resourceType+"/"+id)
+		}
+		if used[fullURL] {
+			fullURL = "urn:uuid:" + fhir.UUIDv5(fhir.NamespaceURL, fmt.Sprintf("%s|%s#%d", sender, resourceType, i))
+		}
+
+		used[fullURL] = true
+		entry.FullURL = fullURL
+		if id != "" {
+			fullURLs[resourceType+"/"+id] = fullURL
+		}
+	}
+
+	for _, entry := range bundle.Entry {
+		fhir.RewriteReferences(entry.Resource, func(reference string) string {
+			if !fhir.IsRelativeReference(reference) {
+				return reference
+			}
+			if fullURL, ok := fullURLs[reference]; ok {
+				return fullURL
+			}
+			return reference
+		})
+	}
+
+	unresolved := map[string]bool{}
+	for _, reference := range bundle.UnresolvedReferences() {
+		unresolved[reference] = true
+		cc.Report("", SeverityError, IssueNotFound, "reference %s points at no resource of the bundle and was left out", reference)
+	}
+	if len(unresolved) == 0 {
+		return
+	}
+	for _, entry := range bundle.Entry {
+		fhir.RemoveReferences(entry.Resource, func(reference string) bool {
+			return unresolved[reference]
+		})
+	}
+}
+
+// messageSender identifies the sending system from MSH-3 Sending Application and MSH-4 Sending Facility
+func messageSender(msg *hl7.Message) string {
+	msh := msg.GetSegment("MSH")
+	if msh == nil {
+		return ""
+	}
+	return msh.GetField(3).GetCompontent(1) + "^" + msh.GetField(4).GetCompontent(1)
+}
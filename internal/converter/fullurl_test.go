@@ -0,0 +1,165 @@
+package converter
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToBundleFullURLs(t *testing.T) {
+	raw := "MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"PV1|1|I|ICU^0101^01||||||||||||||||V100\r" +
+		"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-100||||F"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	bundle, err := ConvertToBundle(msg)
+	if err != nil {
+		t.Fatalf("ConvertToBundle() returned error: %v", err)
+	}
+
+	uuidPattern := regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, entry := range bundle.Entry {
+		if !uuidPattern.MatchString(entry.FullURL) {
+			t.Errorf("Expected UUIDv5 fullUrl, got %s", entry.FullURL)
+		}
+		for _, reference := range fhir.CollectReferences(entry.Resource) {
+			if !strings.HasPrefix(reference, "urn:uuid:") {
+				t.Errorf("Expected reference rewritten to urn:uuid, got %s", reference)
+			}
+		}
+	}
+
+	if unresolved := bundle.UnresolvedReferences(); len(unresolved) > 0 {
+		t.Errorf("Expected all references to resolve, got %v", unresolved)
+	}
+
+	again, _ := ConvertToBundle(msg)
+	if again.Entry[0].FullURL != bundle.Entry[0].FullURL {
+		t.Errorf("Expected deterministic fullUrl, got %s and %s", bundle.Entry[0].FullURL, again.Entry[0].FullURL)
+	}
+}
+
+func TestConvertToBundleUnresolvedReferences(t *testing.T) {
+	//PID-3 is empty
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231115||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||||Doe^John||19800115|M\r" +
+		"PV1|1|I|ICU^0101^01||||||||||||||||V100")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	registry := NewRegistry()
+	registry.Register("ADT", "", "", func(cc *ConversionContext) error {
+		if err := convertPatientStep(cc); err != nil {
+			return err
+		}
+		return cc.AddResource(&fhir.Procedure{
+			ResourceType: "Procedure",
+			ID:           "procedure-1",
+			Subject:      cc.PatientReference(),
+			Encounter:    &fhir.Reference{Reference: "Encounter/missing"},
+		})
+	})
+	opts := DefaultConvertOptions()
+	opts.Registry = registry
+
+	bundle, issues, err := ConvertToBundleWithIssues(msg, opts)
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithIssues() returned error: %v", err)
+	}
+	if unresolved := bundle.UnresolvedReferences(); len(unresolved) != 0 {
+		t.Errorf("Expected the unresolved references to be left out, got %v", unresolved)
+	}
+
+	var procedure *fhir.Procedure
+	for _, entry := range bundle.Entry {
+		if resource, ok := entry.Resource.(*fhir.Procedure); ok {
+			procedure = resource
+		}
+	}
+	if procedure == nil || procedure.Subject == nil || procedure.Encounter != nil {
+		t.Fatalf("Expected the procedure to keep its subject and lose its encounter, got %+v", procedure)
+	}
+
+	codes := map[string]bool{}
+	for _, issue := range issues {
+		codes[issue.Code] = true
+	}
+	if !codes[IssueRequired] || !codes[IssueNotFound] {
+		t.Errorf("Expected issues for the missing PID-3 and the unresolved reference, got %v", issues)
+	}
+}
+
+func TestConvertToBundleKeepsExternalReferences(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231115||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"PV1|1|I|ICU^0101^01||||||||||||||||V100")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	external := []string{
+		"https://fhir.example.org/Encounter/1",
+		"http://docs.example.org/report.pdf",
+		"urn:oid:2.16.840.1.113883.19.5",
+	}
+	registry := NewRegistry()
+	registry.Register("ADT", "", "", func(cc *ConversionContext) error {
+		if err := convertPatientStep(cc); err != nil {
+			return err
+		}
+		if err := cc.AddResource(&fhir.Procedure{
+			ResourceType: "Procedure",
+			ID:           "procedure-1",
+			Subject:      cc.PatientReference(),
+			Encounter:    &fhir.Reference{Reference: external[0]},
+		}); err != nil {
+			return err
+		}
+		return cc.AddResource(&fhir.DocumentReference{
+			ResourceType: "DocumentReference",
+			ID:           "document-1",
+			Status:       "current",
+			Subject:      cc.PatientReference(),
+			Author:       []fhir.Reference{{Reference: external[2]}},
+			Content:      []fhir.DocumentReferenceContent{{Attachment: fhir.Attachment{URL: external[1]}}},
+		})
+	})
+	opts := DefaultConvertOptions()
+	opts.Registry = registry
+	opts.Mode = ModeStrict
+
+	bundle, issues, err := ConvertToBundleWithIssues(msg, opts)
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithIssues() returned error: %v", err)
+	}
+	for _, issue := range issues {
+		if issue.Code == IssueNotFound {
+			t.Errorf("Expected no unresolved reference issue, got %v", issue)
+		}
+	}
+
+	kept := map[string]bool{}
+	for _, entry := range bundle.Entry {
+		for _, reference := range fhir.CollectReferences(entry.Resource) {
+			kept[reference] = true
+			if strings.HasPrefix(reference, "Patient/") {
+				t.Errorf("Expected the relative Patient reference rewritten to its fullUrl, got %s", reference)
+			}
+		}
+	}
+	for _, reference := range external {
+		if !kept[reference] {
+			t.Errorf("Expected %s kept as sent, got %v", reference, kept)
+		}
+	}
+}
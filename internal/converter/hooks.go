@@ -0,0 +1,201 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mourice12/hl7-to-fhir/internal/mapping"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// SegmentHook handles a custom segment, such as ZPI, after the built-in conversion
+type SegmentHook interface {
+	// Segment returns the name of the segment the hook handles
+	Segment() string
+	// Apply is called once for every occurrence of the segment, in message order
+	Apply(segment *hl7.Segment, cc *ConversionContext) error
+}
+
+// segmentHookFunc is a SegmentHook backed by a function
+type segmentHookFunc struct {
+	name  string
+	apply func(segment *hl7.Segment, cc *ConversionContext) error
+}
+
+func (h segmentHookFunc) Segment() string { return h.name }
+
+func (h segmentHookFunc) Apply(segment *hl7.Segment, cc *ConversionContext) error {
+	return h.apply(segment, cc)
+}
+
+// NewSegmentHook returns a SegmentHook that calls apply for every occurrence of the named segment
+func NewSegmentHook(name string, apply func(segment *hl7.Segment, cc *ConversionContext) error) SegmentHook {
+	return segmentHookFunc{name: name, apply: apply}
+}
+
+// RegisterSegmentHook adds a segment hook to DefaultRegistry
+func RegisterSegmentHook(hook SegmentHook) {
+	DefaultRegistry.RegisterSegmentHook(hook)
+}
+
+// RegisterSegmentHook adds a hook for the segment it names; several hooks may handle the same segment
+func (r *Registry) RegisterSegmentHook(hook SegmentHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.segmentHooks[hook.Segment()] = append(r.segmentHooks[hook.Segment()], hook)
+}
+
+// segmentHooksFor returns the hooks registered for a segment name
+func (r *Registry) segmentHooksFor(name string) []SegmentHook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.segmentHooks[name]
+}
+
+// applySegmentHooks runs the declarative segment mappings and then the registered hooks
+func applySegmentHooks(cc *ConversionContext, registry *Registry) error {
+	if set := cc.Options.Mappings; set != nil && len(set.Segments) > 0 {
+		if err := applySegmentMappings(cc, set); err != nil {
+			return err
+		}
+	}
+
+	for i := range cc.Message.Segments {
+		segment := &cc.Message.Segments[i]
+		for _, hook := range registry.segmentHooksFor(segment.Name) {
+			if err := cc.Err(); err != nil {
+				return err
+			}
+			if err := hook.Apply(segment, cc); err != nil {
+				return fmt.Errorf("%s hook: %w", segment.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// applySegmentMappings applies each occurrence of a mapped segment to the first resource of its type in the bundle,
+// reporting the occurrences whose resource is missing or whose targets the resource has no element for
+func applySegmentMappings(cc *ConversionContext, set *mapping.Set) error {
+	targets := map[string][]string{}
+	seen := map[string]bool{}
+	for _, segment := range set.Segments {
+		if key := segment.Segment + "|" + segment.Resource; !seen[key] {
+			seen[key] = true
+			targets[segment.Segment] = append(targets[segment.Segment], segment.Resource)
+		}
+	}
+
+	for i := range cc.Message.Segments {
+		segment := &cc.Message.Segments[i]
+		for _, resourceType := range targets[segment.Name] {
+			resources := cc.Resources(resourceType)
+			if len(resources) == 0 {
+				cc.ReportSegment(segment, 0, SeverityWarning, IssueNotFound, "%s segment mapping has no %s to extend", segment.Name, resourceType)
+				continue
+			}
+			if err := applySegmentMapping(cc, segment, set, resources[0]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// applySegmentMapping extends one resource from one segment occurrence through its JSON form
+func applySegmentMapping(cc *ConversionContext, segment *hl7.Segment, set *mapping.Set, resource fhir.Resource) error {
+	resourceType := resource.GetResourceType()
+
+	decoded, err := decodeResource(resource)
+	if err != nil {
+		return err
+	}
+	if !set.Extend(cc.Message, segment, resourceType, decoded) {
+		return nil
+	}
+
+	//Decode back into the same struct so references to it stay valid
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(resource).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	if err := json.Unmarshal(data, resource); err != nil {
+		return fmt.Errorf("%s segment mapping for %s: %w", segment.Name, resourceType, err)
+	}
+
+	//Elements the resource type does not have are dropped by the round trip
+	kept, err := decodeResource(resource)
+	if err != nil {
+		return err
+	}
+	if dropped := droppedPaths(decoded, kept, ""); len(dropped) > 0 {
+		cc.ReportSegment(segment, 0, SeverityWarning, IssueNotSupported, "%s has no element for %s; the %s segment mapping values were dropped",
+			resourceType, strings.Join(dropped, ", "), segment.Name)
+	}
+
+	return nil
+}
+
+// decodeResource converts a resource to decoded JSON
+func decodeResource(resource fhir.Resource) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// droppedPaths lists the paths of want, decoded JSON, whose values are missing from got
+func droppedPaths(want, got interface{}, path string) []string {
+	var dropped []string
+
+	switch want := want.(type) {
+	case map[string]interface{}:
+		gotMap, _ := got.(map[string]interface{})
+		keys := make([]string, 0, len(want))
+		for key := range want {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			dropped = append(dropped, droppedPaths(want[key], gotMap[key], child)...)
+		}
+	case []interface{}:
+		gotList, _ := got.([]interface{})
+		for i, item := range want {
+			var gotItem interface{}
+			if i < len(gotList) {
+				gotItem = gotList[i]
+			}
+			dropped = append(dropped, droppedPaths(item, gotItem, path+"["+strconv.Itoa(i)+"]")...)
+		}
+	case nil, string:
+		//Empty values are left out by omitempty rather than dropped
+		if want != nil && want != "" && !reflect.DeepEqual(want, got) {
+			dropped = append(dropped, path)
+		}
+	default:
+		if !reflect.DeepEqual(want, got) {
+			dropped = append(dropped, path)
+		}
+	}
+
+	return dropped
+}
@@ -0,0 +1,154 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/internal/mapping"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+const zSegmentMessage = "MSH|^~\\&|ADT|FAC1|EHR|FAC2|20231115120000||ADT^A01|MSG001|P|2.5\r" +
+	"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+	"PV1|1|I|WARD1^101^A\r" +
+	"ZPI|1|VIP|EMP-778\r" +
+	"ZPV|1|Y"
+
+func TestSegmentHooks(t *testing.T) {
+	msg, err := hl7.Parse(zSegmentMessage)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	registry := NewRegistry()
+	handler, err := DefaultRegistry.Lookup(msg)
+	if err != nil {
+		t.Fatalf("Lookup() returned error: %v", err)
+	}
+	registry.Register("ADT", "", "", handler)
+
+	registry.RegisterSegmentHook(NewSegmentHook("ZPI", func(segment *hl7.Segment, cc *ConversionContext) error {
+		cc.Patient().Identifier = append(cc.Patient().Identifier, fhir.Identifier{
+			System: "urn:example:employee",
+			Value:  segment.GetField(3).GetCompontent(1),
+		})
+		return nil
+	}))
+	registry.RegisterSegmentHook(NewSegmentHook("ZPV", func(segment *hl7.Segment, cc *ConversionContext) error {
+		if cc.Encounter() == nil {
+			t.Errorf("Expected the hook to see the converted Encounter")
+		}
+		return cc.AddResource(&fhir.Condition{
+			ResourceType: "Condition",
+			ID:           "isolation",
+			Code:         &fhir.CodeableConcept{Text: "Isolation required"},
+			Subject:      cc.PatientReference(),
+		})
+	}))
+
+	var set mapping.Set
+	config := `{"segments": [{"segment": "ZPI", "resource": "Patient", "rules": [
+		{"target": "extension[]", "rules": [
+			{"target": "url", "value": "urn:example:vip-status"},
+			{"target": "valueCode", "source": "ZPI-2"}
+		]}
+	]}]}`
+	if err := json.Unmarshal([]byte(config), &set); err != nil {
+		t.Fatalf("Unmarshal() returned error: %v", err)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.Registry = registry
+	opts.Mappings = &set
+	bundle, err := ConvertToBundleWithOptions(msg, opts)
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithOptions() returned error: %v", err)
+	}
+
+	var patient *fhir.Patient
+	var isolation *fhir.Condition
+	for _, entry := range bundle.Entry {
+		switch resource := entry.Resource.(type) {
+		case *fhir.Patient:
+			patient = resource
+		case *fhir.Condition:
+			isolation = resource
+		}
+	}
+
+	if patient == nil || len(patient.Extension) != 1 || patient.Extension[0].ValueCode != "VIP" {
+		t.Fatalf("Expected a VIP extension on the Patient, got %+v", patient)
+	}
+	if last := patient.Identifier[len(patient.Identifier)-1]; last.Value != "EMP-778" {
+		t.Errorf("Expected employee identifier EMP-778, got %s", last.Value)
+	}
+	if isolation == nil {
+		t.Fatal("Expected a Condition from the ZPV hook")
+	}
+	if !strings.HasPrefix(isolation.Subject.Reference, "urn:uuid:") {
+		t.Errorf("Expected the Condition subject to be rewritten to a fullUrl, got %s", isolation.Subject.Reference)
+	}
+}
+
+func TestSegmentMappingsReportDroppedValues(t *testing.T) {
+	msg, err := hl7.Parse(strings.Replace(zSegmentMessage, "ZPI|", "DG1|1||I10^Essential hypertension^I10\rZPI|", 1))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	var set mapping.Set
+	config := `{"segments": [
+		{"segment": "ZPV", "resource": "Condition", "rules": [
+			{"target": "extension[]", "rules": [
+				{"target": "url", "value": "urn:example:isolation"},
+				{"target": "valueCode", "source": "ZPV-2"}
+			]}
+		]},
+		{"segment": "ZPV", "resource": "Encounter", "rules": [
+			{"target": "isolationCode", "source": "ZPV-2"}
+		]},
+		{"segment": "ZPI", "resource": "AllergyIntolerance", "rules": [
+			{"target": "code.text", "source": "ZPI-2"}
+		]}
+	]}`
+	if err := json.Unmarshal([]byte(config), &set); err != nil {
+		t.Fatalf("Unmarshal() returned error: %v", err)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.Mappings = &set
+	bundle, issues, err := ConvertToBundleWithIssues(msg, opts)
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithIssues() returned error: %v", err)
+	}
+
+	var condition *fhir.Condition
+	for _, entry := range bundle.Entry {
+		if resource, ok := entry.Resource.(*fhir.Condition); ok {
+			condition = resource
+		}
+	}
+	if condition == nil || len(condition.Extension) != 1 || condition.Extension[0].ValueCode != "Y" {
+		t.Errorf("Expected an isolation extension on the Condition, got %+v", condition)
+	}
+
+	expected := map[string]string{
+		"ZPV[1]": "Encounter has no element for isolationCode; the ZPV segment mapping values were dropped",
+		"ZPI[1]": "ZPI segment mapping has no AllergyIntolerance to extend",
+	}
+	for _, issue := range issues {
+		if want, ok := expected[issue.Location]; ok && issue.Message == want && issue.Severity == SeverityWarning {
+			delete(expected, issue.Location)
+		}
+	}
+	for location, message := range expected {
+		t.Errorf("Expected a warning at %s: %s, got %v", location, message, issues)
+	}
+
+	opts.Mode = ModeStrict
+	if _, err := ConvertToBundleWithOptions(msg, opts); err == nil {
+		t.Errorf("Expected strict mode to fail on the dropped values")
+	}
+}
@@ -0,0 +1,128 @@
+package converter
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// IDStrategy assigns the logical id of each resource in a bundle
+type IDStrategy interface {
+	ResourceID(resourceType string, key IDKey) string
+}
+
+// IDKey holds what an IDStrategy can build an id from
+type IDKey struct {
+	Sender     string // MSH-3^MSH-4
+	ControlID  string // MSH-10 Message Control ID
+	SourceID   string // id built from the message, e.g. servicerequest-ORD123 or observation-1
+	Identifier string // identifier=system|value of the resource's business identifier (placer/filler, MRN), if any
+	// MessageScoped is set when SourceID comes from set IDs and is only unique within one message
+	MessageScoped bool
+}
+
+// HashIDStrategy derives a UUIDv5 from the sender and the business identifier, MSH-10 plus set ID, or source ID
+type HashIDStrategy struct{}
+
+// ResourceID returns the same id for the same resource from the same sender
+func (HashIDStrategy) ResourceID(resourceType string, key IDKey) string {
+	name := key.SourceID
+	switch {
+	case key.Identifier != "":
+		name = key.Identifier
+	case key.MessageScoped || key.SourceID == "":
+		name = key.ControlID + "|" + key.SourceID
+	}
+	return fhir.UUIDv5(fhir.NamespaceURL, key.Sender+"|"+resourceType+"|"+name)
+}
+
+// RandomIDStrategy gives every resource a new random UUID
+type RandomIDStrategy struct{}
+
+// ResourceID returns a random (version 4) UUID
+func (RandomIDStrategy) ResourceID(resourceType string, key IDKey) string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return HashIDStrategy{}.ResourceID(resourceType, key)
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+// SourceIDStrategy keeps the ids built from the message, qualifying empty ones with MSH-10
+type SourceIDStrategy struct{}
+
+// ResourceID returns the source id unchanged
+func (SourceIDStrategy) ResourceID(resourceType string, key IDKey) string {
+	if key.SourceID == "" {
+		return fhir.UUIDv5(fhir.NamespaceURL, key.Sender+"|"+resourceType+"|"+key.ControlID)
+	}
+	return key.SourceID
+}
+
+// IDStrategyByName returns the strategy for a deployment setting: hash, uuid or source
+func IDStrategyByName(name string) (IDStrategy, error) {
+	switch name {
+	case "hash", "":
+		return HashIDStrategy{}, nil
+	case "uuid":
+		return RandomIDStrategy{}, nil
+	case "source":
+		return SourceIDStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown id strategy %q", name)
+	}
+}
+
+// messageScopedTypes are the resource types whose source ids are built from set IDs
+var messageScopedTypes = map[string]bool{
+	"AllergyIntolerance":       true,
+	"Condition":                true,
+	"Immunization":             true,
+	"MedicationAdministration": true,
+	"MedicationDispense":       true,
+	"Observation":              true,
+	"Procedure":                true,
+}
+
+// applyIDStrategy replaces every resource id using the strategy and rewrites Type/id references to match
+func applyIDStrategy(bundle *fhir.Bundle, msg *hl7.Message, strategy IDStrategy) {
+	if strategy == nil {
+		return
+	}
+
+	controlID := messageControlID(msg)
+	sender := messageSender(msg)
+
+	ids := map[string]string{}
+	for _, entry := range bundle.Entry {
+		if entry.Resource == nil {
+			continue
+		}
+		resourceType, id, identifiers := entry.Resource.GetResourceType(), entry.Resource.GetID(), entry.Resource.GetIdentifiers()
+
+		newID := strategy.ResourceID(resourceType, IDKey{
+			Sender:        sender,
+			ControlID:     controlID,
+			SourceID:      id,
+			Identifier:    identifierQuery(identifiers),
+			MessageScoped: messageScopedTypes[resourceType],
+		})
+		entry.Resource.SetID(newID)
+		if id != "" {
+			ids[resourceType+"/"+id] = resourceType + "/" + newID
+		}
+	}
+
+	for _, entry := range bundle.Entry {
+		fhir.RewriteReferences(entry.Resource, func(reference string) string {
+			if newReference, ok := ids[reference]; ok {
+				return newReference
+			}
+			return reference
+		})
+	}
+}
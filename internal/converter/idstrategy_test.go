@@ -0,0 +1,86 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestIDStrategies(t *testing.T) {
+	build := func(controlID string) *hl7.Message {
+		raw := "MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|" + controlID + "|P|2.5\r" +
+			"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+			"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000\r" +
+			"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-100||||F"
+		msg, err := hl7.Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse() returned error: %v", err)
+		}
+		return msg
+	}
+
+	//Conditions come from ADT DG1 segments
+	buildADT := func(controlID string) *hl7.Message {
+		raw := "MSH|^~\\&|ADT|FAC1|EHR|FAC2|20231115120000||ADT^A08|" + controlID + "|P|2.5\r" +
+			"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+			"DG1|1||E11.9^Type 2 diabetes^I10"
+		msg, err := hl7.Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse() returned error: %v", err)
+		}
+		return msg
+	}
+
+	ids := func(msg *hl7.Message, strategy IDStrategy) map[string]string {
+		opts := DefaultConvertOptions()
+		opts.IDs = strategy
+		bundle, err := ConvertToBundleWithOptions(msg, opts)
+		if err != nil {
+			t.Fatalf("ConvertToBundleWithOptions() returned error: %v", err)
+		}
+		result := map[string]string{}
+		for _, entry := range bundle.Entry {
+			result[entry.Resource.GetResourceType()] = entry.Resource.GetID()
+		}
+		return result
+	}
+
+	first := ids(build("MSG001"), HashIDStrategy{})
+	second := ids(build("MSG002"), HashIDStrategy{})
+
+	if ids(buildADT("MSG001"), HashIDStrategy{})["Condition"] == ids(buildADT("MSG002"), HashIDStrategy{})["Condition"] {
+		t.Errorf("Expected set ID based Condition ids to differ across messages")
+	}
+	if first["Observation"] != second["Observation"] {
+		t.Errorf("Expected Observation ids from the filler number to match, got %s and %s", first["Observation"], second["Observation"])
+	}
+	if first["ServiceRequest"] != ids(build("MSG001"), HashIDStrategy{})["ServiceRequest"] {
+		t.Errorf("Expected hashed ids to be deterministic")
+	}
+
+	source := ids(buildADT("MSG001"), SourceIDStrategy{})
+	if source["Condition"] != "condition-1" {
+		t.Errorf("Expected source id condition-1, got %s", source["Condition"])
+	}
+
+	random := ids(build("MSG001"), RandomIDStrategy{})
+	if random["Patient"] == ids(build("MSG001"), RandomIDStrategy{})["Patient"] {
+		t.Errorf("Expected random ids to differ between conversions")
+	}
+}
+
+func TestIDStrategyRewritesReferences(t *testing.T) {
+	bundle := fhir.NewBundle()
+	patient := &fhir.Patient{ResourceType: "Patient", ID: "12345"}
+	condition := &fhir.Condition{ResourceType: "Condition", ID: "condition-1", Subject: &fhir.Reference{Reference: "Patient/12345"}}
+	bundle.AddEntry("Patient", patient.ID, patient)
+	bundle.AddEntry("Condition", condition.ID, condition)
+
+	msg, _ := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5")
+	applyIDStrategy(bundle, msg, HashIDStrategy{})
+
+	if condition.Subject.Reference != "Patient/"+patient.ID {
+		t.Errorf("Expected subject Patient/%s, got %s", patient.ID, condition.Subject.Reference)
+	}
+}
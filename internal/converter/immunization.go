@@ -0,0 +1,189 @@
+package converter
+
+import (
+	"strconv"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// LOINC codes of the OBX segments the CDC immunization IG attaches to an RXA
+const (
+	loincVFCEligibility = "64994-7"
+	loincFundingSource  = "30963-3"
+)
+
+// ConvertToImmunizations converts RXA segments (with their RXR and OBX) to FHIR Immunizations
+func ConvertToImmunizations(cc *ConversionContext) ([]*fhir.Immunization, []*fhir.Practitioner, error) {
+	msg := cc.Message
+	var immunizations []*fhir.Immunization
+	var practitioners []*fhir.Practitioner
+
+	//ORC precedes its RXA in the CDC IG, so look it up from a separate grouping
+	orders := orderControls(msg, "RXA")
+
+	groups := msg.GetGroups("RXA", "RXR", "OBX")
+	for i, group := range groups {
+		rxa := group.Head
+
+		immunization := &fhir.Immunization{
+			ResourceType: "Immunization",
+			ID:           "immunization-" + strconv.Itoa(i+1),
+			Patient:      cc.PatientReference(),
+		}
+
+		//ORC-3 Filler Order Number
+		if orc := orders[rxa]; orc != nil {
+			filler := orc.GetField(3)
+			identifier := buildEIIdentifier(filler.GetCompontent(1), filler.GetCompontent(2), filler.GetCompontent(3), filler.GetCompontent(4))
+			if identifier != nil {
+				immunization.Identifier = []fhir.Identifier{*identifier}
+			}
+		}
+
+		//RXA-3 Administration Start DateTime
+		adminDate := rxa.GetField(3).GetCompontent(1)
+		if adminDate != "" {
+			immunization.OccurrenceDateTime = checkedDateTime(cc, rxa, 3, adminDate)
+		}
+
+		//RXA-5 Administered Code (CVX)
+		immunization.VaccineCode = buildCodeableConcept(cc, rxa.GetField(5))
+
+		//RXA-6/7 Administered Amount and Units
+		immunization.DoseQuantity = buildDoseQuantity(rxa)
+
+		//RXA-9 Administration Notes: 00 is a new record, anything else is historical
+		notes := rxa.GetField(9).GetCompontent(1)
+		if notes != "" {
+			primarySource := notes == "00"
+			immunization.PrimarySource = &primarySource
+		}
+
+		//RXA-10 Administering Provider
+		providerField := rxa.GetField(10)
+		if providerField != nil {
+			for _, rep := range providerField.Repetitions {
+				practitioner := buildPractitioner(rep)
+				if practitioner == nil {
+					continue
+				}
+
+				practitioners = addPractitioner(practitioners, practitioner)
+				immunization.Performer = append(immunization.Performer, fhir.ImmunizationPerformer{
+					Function: &fhir.CodeableConcept{
+						Coding: []fhir.Coding{{
+							System:  "http://terminology.hl7.org/CodeSystem/v2-0443",
+							Code:    "AP",
+							Display: "Administering Provider",
+						}},
+					},
+					Actor: &fhir.Reference{
+						Reference: "Practitioner/" + practitioner.ID,
+						Display:   practitionerDisplay(practitioner),
+					},
+				})
+			}
+		}
+
+		//RXA-15 Lot Number, RXA-16 Expiration Date
+		immunization.LotNumber = rxa.GetField(15).GetCompontent(1)
+		immunization.ExpirationDate = checkedDate(cc, rxa, 16, rxa.GetField(16).GetCompontent(1))
+
+		//RXA-17 Manufacturer (MVX)
+		immunization.Manufacturer = buildManufacturer(rxa)
+
+		//RXA-20 Completion Status, RXA-21 Action Code
+		mapCompletionStatus(cc, rxa, immunization)
+
+		//RXR-1 Route, RXR-2 Site
+		rxr := group.GetSegment("RXR")
+		if rxr != nil {
+			immunization.Route = buildCodeableConcept(cc, rxr.GetField(1))
+			immunization.Site = buildCodeableConcept(cc, rxr.GetField(2))
+		}
+
+		//OBX VFC eligibility and funding source
+		for _, obx := range group.GetSegments("OBX") {
+			switch obx.GetField(3).GetCompontent(1) {
+			case loincVFCEligibility:
+				eligibility := buildCodeableConcept(cc, obx.GetField(5))
+				if eligibility != nil {
+					immunization.ProgramEligibility = append(immunization.ProgramEligibility, *eligibility)
+				}
+			case loincFundingSource:
+				immunization.FundingSource = buildCodeableConcept(cc, obx.GetField(5))
+			}
+		}
+
+		cc.RecordSegment(immunization, rxa)
+		immunizations = append(immunizations, immunization)
+	}
+
+	return immunizations, practitioners, nil
+}
+
+// buildDoseQuantity extracts the dose from RXA-6 and RXA-7, where 999 means unknown
+func buildDoseQuantity(rxa *hl7.Segment) *fhir.Quantity {
+	amountStr := rxa.GetField(6).GetCompontent(1)
+	if amountStr == "" || amountStr == "999" {
+		return nil
+	}
+
+	return buildQuantity(amountStr, rxa.GetField(7))
+}
+
+// buildManufacturer extracts the vaccine manufacturer from RXA-17
+func buildManufacturer(rxa *hl7.Segment) *fhir.Reference {
+	field := rxa.GetField(17)
+	code := field.GetCompontent(1)
+	name := field.GetCompontent(2)
+
+	if code == "" && name == "" {
+		return nil
+	}
+
+	manufacturer := &fhir.Reference{Display: name}
+	if code != "" {
+		manufacturer.Identifier = &fhir.Identifier{
+			System: mapCodeSystem("MVX"),
+			Value:  code,
+		}
+	}
+	return manufacturer
+}
+
+// mapCompletionStatus sets status and statusReason from RXA-20, RXA-18 and RXA-21
+func mapCompletionStatus(cc *ConversionContext, rxa *hl7.Segment, immunization *fhir.Immunization) {
+	//RXA-18 Substance/Treatment Refusal Reason
+	refusalReason := buildCodeableConcept(cc, rxa.GetField(18))
+
+	switch rxa.GetField(20).GetCompontent(1) {
+	case "RE": // Refused
+		immunization.Status = "not-done"
+		immunization.StatusReason = refusalReason
+		if immunization.StatusReason == nil {
+			immunization.StatusReason = &fhir.CodeableConcept{
+				Coding: []fhir.Coding{{
+					System:  "http://terminology.hl7.org/CodeSystem/v3-ActReason",
+					Code:    "PATOBJ",
+					Display: "patient objection",
+				}},
+			}
+		}
+	case "NA": // Not administered
+		immunization.Status = "not-done"
+		immunization.StatusReason = refusalReason
+	case "PA": // Partially administered
+		immunization.Status = "completed"
+		subpotent := true
+		immunization.IsSubpotent = &subpotent
+	default: // CP Complete, or not valued
+		immunization.Status = "completed"
+	}
+
+	//RXA-21 Action Code D means the record was deleted
+	if rxa.GetField(21).GetCompontent(1) == "D" {
+		immunization.Status = "entered-in-error"
+	}
+}
@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToImmunizations(t *testing.T) {
+	cc := newSampleContext(t, "sample-vxu.hl7")
+	immunizations, practitioners, err := ConvertToImmunizations(cc)
+	if err != nil {
+		t.Fatalf("ConvertToImmunizations() returned error: %v", err)
+	}
+	if len(immunizations) != 1 || len(practitioners) != 1 {
+		t.Fatalf("Expected 1 immunization and 1 practitioner, got %d and %d", len(immunizations), len(practitioners))
+	}
+
+	immunization := immunizations[0]
+	if len(immunization.Identifier) != 1 || immunization.DoseQuantity == nil || immunization.PrimarySource == nil ||
+		immunization.Route == nil || immunization.Site == nil || immunization.FundingSource == nil ||
+		len(immunization.ProgramEligibility) != 1 || len(immunization.Performer) != 1 || immunization.Manufacturer == nil {
+		t.Fatalf("Expected every sample field to be converted, got %+v", immunization)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"patient", immunization.Patient.Reference, "Patient/583295"},
+		{"ORC-3 identifier", immunization.Identifier[0].Value, "IZ-783274"},
+		{"ORC-3 namespace is not a system", immunization.Identifier[0].System, ""},
+		{"ORC-3 assigner", immunization.Identifier[0].Assigner.Display, "MYEHR"},
+		{"RXA-3 occurrence", immunization.OccurrenceDateTime, "2023-11-15"},
+		{"RXA-5 vaccine code", immunization.VaccineCode.Coding[0].Code, "08"},
+		{"RXA-5 CVX system", immunization.VaccineCode.Coding[0].System, "http://hl7.org/fhir/sid/cvx"},
+		{"RXA-7 dose unit", immunization.DoseQuantity.Unit, "mL"},
+		{"RXA-10 performer function", immunization.Performer[0].Function.Coding[0].Code, "AP"},
+		{"RXA-15 lot number", immunization.LotNumber, "AB1234"},
+		{"RXA-16 expiration date", immunization.ExpirationDate, "2024-12-31"},
+		{"RXA-17 manufacturer", immunization.Manufacturer.Identifier.Value, "MSD"},
+		{"RXA-20 status", immunization.Status, "completed"},
+		{"RXR-1 route", immunization.Route.Coding[0].System, "http://terminology.hl7.org/CodeSystem/v2-0162"},
+		{"RXR-2 site", immunization.Site.Coding[0].Code, "LA"},
+		{"OBX VFC eligibility", immunization.ProgramEligibility[0].Coding[0].Code, "V02"},
+		{"OBX funding source", immunization.FundingSource.Coding[0].Code, "VXC50"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, test.got)
+		}
+	}
+	if *immunization.DoseQuantity.Value != 0.5 || !*immunization.PrimarySource {
+		t.Errorf("Expected a 0.5 mL primary source dose, got %+v, %v", immunization.DoseQuantity, *immunization.PrimarySource)
+	}
+}
+
+func TestConvertToImmunizationsStatus(t *testing.T) {
+	tests := []struct {
+		rxa       string
+		status    string
+		reason    string
+		subpotent bool
+		dose      bool
+	}{
+		{"RXA|0|1|20231115||08^HepB^CVX|0.5|mL||00||||||AB1234||||||CP|A", "completed", "", false, true},
+		{"RXA|0|1|20231115||08^HepB^CVX|999|||00|||||||||00^Parental decision^NIP002||RE", "not-done", "00", false, false},
+		{"RXA|0|1|20231115||08^HepB^CVX|999|||00|||||||||||RE", "not-done", "PATOBJ", false, false},
+		{"RXA|0|1|20231115||08^HepB^CVX|0.25|mL||00|||||||||||PA", "completed", "", true, true},
+		{"RXA|0|1|20231115||08^HepB^CVX|0.5|mL||00|||||||||||CP|D", "entered-in-error", "", false, true},
+	}
+
+	for _, test := range tests {
+		msg, err := hl7.Parse("MSH|^~\\&|MYEHR|CLINIC|IIS|STATEIIS|20231115143000||VXU^V04|MSG001|P|2.5.1\r" +
+			"PID|1||583295^^^ADT1^MR||DOE^JOHN||20200115|M\r" + test.rxa)
+		if err != nil {
+			t.Fatalf("Parse() returned error: %v", err)
+		}
+
+		immunizations, _, err := ConvertToImmunizations(newTestContext(msg, &fhir.Patient{ResourceType: "Patient", ID: "583295"}))
+		if err != nil || len(immunizations) != 1 {
+			t.Fatalf("%s: expected one immunization, got %d (%v)", test.rxa, len(immunizations), err)
+		}
+
+		immunization := immunizations[0]
+		if immunization.Status != test.status {
+			t.Errorf("%s: expected status %s, got %s", test.rxa, test.status, immunization.Status)
+		}
+		reason := ""
+		if immunization.StatusReason != nil {
+			reason = immunization.StatusReason.Coding[0].Code
+		}
+		if reason != test.reason {
+			t.Errorf("%s: expected status reason %q, got %q", test.rxa, test.reason, reason)
+		}
+		if (immunization.IsSubpotent != nil && *immunization.IsSubpotent) != test.subpotent {
+			t.Errorf("%s: expected subpotent %v, got %v", test.rxa, test.subpotent, immunization.IsSubpotent)
+		}
+		if (immunization.DoseQuantity != nil) != test.dose {
+			t.Errorf("%s: expected a dose %v, got %+v", test.rxa, test.dose, immunization.DoseQuantity)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package converter
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// Issue severities, as in FHIR OperationOutcome.issue.severity
+const (
+	SeverityFatal       = "fatal"
+	SeverityError       = "error"
+	SeverityWarning     = "warning"
+	SeverityInformation = "information"
+)
+
+// Issue codes used by the converter, from the FHIR issue-type value set
+const (
+	IssueInvalid      = "invalid"       // the value is not valid for its HL7 data type
+	IssueValue        = "value"         // the value could not be converted and was dropped or kept as text
+	IssueRequired     = "required"      // a required segment or field is missing
+	IssueCodeInvalid  = "code-invalid"  // the coding system is not known
+	IssueNotSupported = "not-supported" // the content is valid HL7 but is not converted
+	IssueNotFound     = "not-found"     // a reference points at no resource of the bundle
+)
+
+// Issue is a problem found while converting a message
+type Issue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Location string `json:"location,omitempty"` // HL7 location, e.g. OBX[3]-5
+	Message  string `json:"message"`
+}
+
+// reportFieldIssue records an issue about a field found only by its pointer
+func reportFieldIssue(cc *ConversionContext, field *hl7.Field, severity, code, format string, args ...interface{}) {
+	if field == nil {
+		return
+	}
+	for i := range cc.Message.Segments {
+		segment := &cc.Message.Segments[i]
+		for j := range segment.Fields {
+			if &segment.Fields[j] == field {
+				cc.ReportSegment(segment, j+1, severity, code, format, args...)
+				return
+			}
+		}
+	}
+}
+
+// segmentLocation returns NAME[n] for the nth segment of that name in the message
+func segmentLocation(msg *hl7.Message, segment *hl7.Segment) (string, bool) {
+	occurrence := 0
+	for i := range msg.Segments {
+		if msg.Segments[i].Name != segment.Name {
+			continue
+		}
+		occurrence++
+		if &msg.Segments[i] == segment {
+			return segment.Name + "[" + strconv.Itoa(occurrence) + "]", true
+		}
+	}
+	return "", false
+}
+
+// hl7DateTime matches DT/DTM values: YYYY[MM[DD[HH[MM[SS[.S[S[S[S]]]]]]]]][+/-ZZZZ]
+var hl7DateTime = regexp.MustCompile(`^\d{4}(\d{2}(\d{2}(\d{2}(\d{2}(\d{2}(\.\d{1,4})?)?)?)?)?)?([+-]\d{4})?$`)
+
+// checkedDate formats a DT value read from a segment field, reporting values that are not dates
+func checkedDate(cc *ConversionContext, segment *hl7.Segment, field int, value string) string {
+	return checkedTime(cc, segment, field, value, fhir.FormatDate)
+}
+
+// checkedDateTime formats a DTM value read from a segment field, reporting values that are not timestamps
+func checkedDateTime(cc *ConversionContext, segment *hl7.Segment, field int, value string) string {
+	return checkedTime(cc, segment, field, value, fhir.FormatDateTime)
+}
+
+// checkedInstant formats a DTM value read from a segment field as an instant, reporting values without a time
+func checkedInstant(cc *ConversionContext, segment *hl7.Segment, field int, value string) string {
+	return checkedTime(cc, segment, field, value, fhir.FormatInstant)
+}
+
+// checkedTime formats value and reports it when it is not a valid HL7 time or is too imprecise to convert
+func checkedTime(cc *ConversionContext, segment *hl7.Segment, field int, value string, format func(string) string) string {
+	if value == "" {
+		return ""
+	}
+	if !hl7DateTime.MatchString(value) {
+		cc.ReportSegment(segment, field, SeverityWarning, IssueInvalid, "%q is not a valid HL7 date/time and was dropped", value)
+		return ""
+	}
+
+	formatted := format(value)
+	if formatted == "" {
+		cc.ReportSegment(segment, field, SeverityWarning, IssueValue, "%q is too imprecise for the FHIR element and was dropped", value)
+	}
+	return formatted
+}
+
+// checkedNumber parses a numeric field value, reporting values that are not numbers
+func checkedNumber(cc *ConversionContext, segment *hl7.Segment, field int, value string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		cc.ReportSegment(segment, field, SeverityWarning, IssueInvalid, "%q is not a number and was dropped", value)
+		return 0, false
+	}
+	return number, true
+}
+
+// ToOperationOutcome reports conversion issues as a FHIR OperationOutcome
+func ToOperationOutcome(issues []Issue) *fhir.OperationOutcome {
+	outcome := &fhir.OperationOutcome{ResourceType: "OperationOutcome"}
+
+	for _, issue := range issues {
+		entry := fhir.OperationOutcomeIssue{
+			Severity:    issue.Severity,
+			Code:        issue.Code,
+			Diagnostics: issue.Message,
+		}
+		if issue.Location != "" {
+			entry.Location = []string{issue.Location}
+		}
+		outcome.Issue = append(outcome.Issue, entry)
+	}
+
+	//An OperationOutcome needs at least one issue
+	if len(outcome.Issue) == 0 {
+		outcome.Issue = []fhir.OperationOutcomeIssue{{
+			Severity:    SeverityInformation,
+			Code:        "informational",
+			Diagnostics: "Converted without issues",
+		}}
+	}
+
+	return outcome
+}
@@ -0,0 +1,124 @@
+package converter
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToBundleWithIssues(t *testing.T) {
+	raw := "MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||198001|M\r" +
+		"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-100||||F\r" +
+		"OBX|2|NM|2160-0^Creatinine^LN||pending|mg/dL|||||F|||2023-11-15\r" +
+		"OBX|3|SN|2823-3^Potassium^LN||<^3.1|mmol/L|||||F\r" +
+		"OBX|4|ST|8251-1^Comment^LN||Hemolyzed sample||||||F"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	bundle, issues, err := ConvertToBundleWithIssues(msg, DefaultConvertOptions())
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithIssues() returned error: %v", err)
+	}
+
+	locations := map[string]string{}
+	for _, issue := range issues {
+		locations[issue.Location] = issue.Code
+	}
+	if locations["OBX[2]-5"] != IssueValue {
+		t.Errorf("Expected a value issue at OBX[2]-5, got %v", issues)
+	}
+	if locations["OBX[2]-14"] != IssueInvalid {
+		t.Errorf("Expected an invalid date issue at OBX[2]-14, got %v", issues)
+	}
+
+	observations := map[string]*fhir.Oberservation{}
+	var patient *fhir.Patient
+	for _, entry := range bundle.Entry {
+		switch resource := entry.Resource.(type) {
+		case *fhir.Oberservation:
+			observations[resource.Code.Coding[0].Code] = resource
+		case *fhir.Patient:
+			patient = resource
+		}
+	}
+
+	if patient.BirthDate != "1980-01" {
+		t.Errorf("Expected partial birth date 1980-01, got %s", patient.BirthDate)
+	}
+	if obs := observations["2160-0"]; obs.ValueQuantity != nil || obs.ValueString != "pending" {
+		t.Errorf("Expected non-numeric NM value as valueString, got %+v", obs)
+	}
+	if q := observations["2823-3"].ValueQuantity; q == nil || q.Comparator != "<" || *q.Value != 3.1 {
+		t.Errorf("Expected SN value < 3.1, got %+v", q)
+	}
+	if obs := observations["8251-1"]; obs.ValueString != "Hemolyzed sample" {
+		t.Errorf("Expected ST valueString, got %q", obs.ValueString)
+	}
+
+	outcome := ToOperationOutcome(issues)
+	if len(outcome.Issue) != len(issues) || outcome.Issue[0].Location == nil {
+		t.Errorf("Expected one OperationOutcome issue per issue with its location, got %+v", outcome.Issue)
+	}
+}
+
+func TestConvertToBundleMissingPIDIssue(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|ADT|FAC1|EHR|FAC2|20231115120000||ADT^A01|MSG001|P|2.5\rPV1|1|I")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	_, issues, err := ConvertToBundleWithIssues(msg, DefaultConvertOptions())
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithIssues() returned error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Severity != SeverityError || issues[0].Code != IssueRequired {
+		t.Errorf("Expected a required PID error, got %v", issues)
+	}
+}
+
+func TestConverterIssuesOnOwnContext(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||1980-01-15|M\r" +
+		"OBR|1|ORD123|LAB987|2345-7^Glucose^LN\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||pending|mg/dL|||||F")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	//Converters called directly report on the context they are given, even when the same message is converted
+	//by several contexts at once
+	contexts := make([]*ConversionContext, 4)
+	var wg sync.WaitGroup
+	for i := range contexts {
+		contexts[i] = NewConversionContext(context.Background(), msg, DefaultConvertOptions())
+		wg.Add(1)
+		go func(cc *ConversionContext) {
+			defer wg.Done()
+			if _, err := ConvertToPatient(cc); err != nil {
+				t.Errorf("ConvertToPatient() returned error: %v", err)
+			}
+			if _, err := ConvertToObservations(cc); err != nil {
+				t.Errorf("ConvertToObservations() returned error: %v", err)
+			}
+		}(contexts[i])
+	}
+	wg.Wait()
+
+	for _, cc := range contexts {
+		locations := map[string]string{}
+		for _, issue := range cc.Issues() {
+			locations[issue.Location] = issue.Code
+		}
+		if len(locations) != 2 || locations["PID[1]-7"] != IssueInvalid || locations["OBX[1]-5"] != IssueValue {
+			t.Errorf("Expected the PID-7 and OBX-5 issues once per context, got %v", cc.Issues())
+		}
+	}
+}
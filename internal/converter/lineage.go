@@ -0,0 +1,289 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// Lineage links a populated FHIR element to the HL7 value it was converted from
+type Lineage struct {
+	Path     string `json:"path"`               // FHIR path, e.g. Bundle.entry[2].resource.code.coding[0].code
+	Value    string `json:"value"`              // the FHIR value
+	Source   string `json:"source,omitempty"`   // HL7 location, e.g. OBX[1]-3.1; empty for generated values
+	RawValue string `json:"rawValue,omitempty"` // the HL7 value as received
+}
+
+// hl7Value is one non-empty value of a message with its location
+type hl7Value struct {
+	location string
+	raw      string
+}
+
+// elementSource is a resource element, such as code or valueQuantity.unit, and the segment field it was converted from
+type elementSource struct {
+	element string
+	segment *hl7.Segment
+	field   int
+}
+
+// ConvertToBundleWithLineage converts like ConvertToBundleWithIssues and also returns the lineage of every
+// populated element of the bundle
+func ConvertToBundleWithLineage(msg *hl7.Message, opts ConvertOptions) (*fhir.Bundle, []Lineage, []Issue, error) {
+	cc, bundle, issues, err := convertMessage(context.Background(), msg, opts)
+	if err != nil {
+		return nil, nil, issues, err
+	}
+
+	lineage, err := buildLineage(cc, bundle)
+	if err != nil {
+		return nil, nil, issues, err
+	}
+	return bundle, lineage, issues, nil
+}
+
+// buildLineage lists every populated element of the bundle with the HL7 value it came from. An element takes the
+// value it equals in the fields the converter recorded for it, or for the element that holds it; a value recorded
+// for exactly its element but translated, such as a mapped status, takes the whole field. Elements without a
+// recorded field, including generated values (ids, references, constants), have no source.
+func buildLineage(cc *ConversionContext, bundle *fhir.Bundle) ([]Lineage, error) {
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := lineageLeaves(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, leaf := range leaves {
+		entry, element, ok := resourceElement(leaf.Path)
+		if !ok || entry >= len(bundle.Entry) || bundle.Entry[entry].Resource == nil {
+			continue
+		}
+		if value, ok := cc.elementSource(bundle.Entry[entry].Resource, element, leaf.Value); ok {
+			leaves[i].Source = value.location
+			leaves[i].RawValue = value.raw
+		}
+	}
+
+	return leaves, nil
+}
+
+// elementSource returns the HL7 value an element of a resource came from
+func (cc *ConversionContext) elementSource(resource fhir.Resource, element, fhirValue string) (hl7Value, bool) {
+	msg := cc.Message
+
+	//The fields recorded for the most specific element that holds this one
+	var fields []elementSource
+	for _, source := range cc.recordedSources(resource) {
+		if !holdsElement(source.element, element) {
+			continue
+		}
+		if len(fields) > 0 && len(source.element) < len(fields[0].element) {
+			continue
+		}
+		if len(fields) > 0 && len(source.element) > len(fields[0].element) {
+			fields = nil
+		}
+		fields = append(fields, source)
+	}
+
+	for _, source := range fields {
+		if value, ok := matchValue(fieldValues(msg, source.segment, source.field), fhirValue, msg.Delimiters); ok {
+			return value, true
+		}
+	}
+	//A value translated from the field recorded for exactly this element, such as a mapped status
+	for _, source := range fields {
+		if source.element != element {
+			continue
+		}
+		if raw := fieldText(source.segment.GetField(source.field), msg.Delimiters); raw != "" {
+			location, _ := segmentLocation(msg, source.segment)
+			return hl7Value{location: location + "-" + strconv.Itoa(source.field), raw: raw}, true
+		}
+	}
+	return hl7Value{}, false
+}
+
+// holdsElement reports whether element is path or one of its parents, e.g. valueQuantity for valueQuantity.unit
+func holdsElement(element, path string) bool {
+	if !strings.HasPrefix(path, element) {
+		return false
+	}
+	rest := path[len(element):]
+	return rest == "" || rest[0] == '.' || rest[0] == '['
+}
+
+// resourceElement splits Bundle.entry[n].resource.code.text into the entry index and the element path code.text
+func resourceElement(path string) (int, string, bool) {
+	rest := strings.TrimPrefix(path, "Bundle.entry[")
+	end := strings.Index(rest, "]")
+	if rest == path || end < 0 || !strings.HasPrefix(rest[end+1:], ".resource.") {
+		return 0, "", false
+	}
+	entry, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return 0, "", false
+	}
+	return entry, strings.TrimPrefix(rest[end+1:], ".resource."), true
+}
+
+// WriteLineageReport writes lineage as a side-by-side table of FHIR and HL7 paths and values
+func WriteLineageReport(w io.Writer, lineage []Lineage) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FHIR PATH\tFHIR VALUE\tHL7 PATH\tHL7 VALUE")
+	for _, entry := range lineage {
+		source, raw := entry.Source, entry.RawValue
+		if source == "" {
+			source, raw = "-", "-"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", entry.Path, reportValue(entry.Value), source, reportValue(raw))
+	}
+	return table.Flush()
+}
+
+// reportValue shortens a value to one table cell
+func reportValue(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if len(value) > 60 {
+		return value[:57] + "..."
+	}
+	return value
+}
+
+// lineageLeaves walks bundle JSON in document order and returns the path and value of every populated leaf
+func lineageLeaves(data []byte) ([]Lineage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var leaves []Lineage
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				for decoder.More() {
+					key, err := decoder.Token()
+					if err != nil {
+						return err
+					}
+					if err := walk(path + "." + key.(string)); err != nil {
+						return err
+					}
+				}
+			case '[':
+				for i := 0; decoder.More(); i++ {
+					if err := walk(path + "[" + strconv.Itoa(i) + "]"); err != nil {
+						return err
+					}
+				}
+			}
+			//The closing delimiter
+			_, err := decoder.Token()
+			return err
+		case string:
+			if t != "" {
+				leaves = append(leaves, Lineage{Path: path, Value: t})
+			}
+		case json.Number:
+			leaves = append(leaves, Lineage{Path: path, Value: t.String()})
+		case bool:
+			leaves = append(leaves, Lineage{Path: path, Value: strconv.FormatBool(t)})
+		}
+		return nil
+	}
+
+	if err := walk("Bundle"); err != nil {
+		return nil, err
+	}
+	return leaves, nil
+}
+
+// fieldValues lists the non-empty subcomponents of a segment field with their locations, e.g. PID[1]-5.1 or
+// PID[1]-3(2).1 for the second repetition; MSH-1 and MSH-2 are the delimiters and are left out
+func fieldValues(msg *hl7.Message, segment *hl7.Segment, field int) []hl7Value {
+	var values []hl7Value
+	prefix, _ := segmentLocation(msg, segment)
+
+	for f, fieldValue := range segment.Fields {
+		if segment.Name == "MSH" && f < 2 || f+1 != field {
+			continue
+		}
+		for r, repetition := range fieldValue.Repetitions {
+			location := prefix + "-" + strconv.Itoa(f+1)
+			if len(fieldValue.Repetitions) > 1 {
+				location += "(" + strconv.Itoa(r+1) + ")"
+			}
+			for c, component := range repetition.Components {
+				for s, sub := range component.Subcomponents {
+					if sub == "" {
+						continue
+					}
+					at := location
+					if len(repetition.Components) > 1 || len(component.Subcomponents) > 1 {
+						at += "." + strconv.Itoa(c+1)
+					}
+					if len(component.Subcomponents) > 1 {
+						at += "." + strconv.Itoa(s+1)
+					}
+					values = append(values, hl7Value{location: at, raw: sub})
+				}
+			}
+		}
+	}
+	return values
+}
+
+// matchValue returns the first HL7 value a FHIR value was converted from
+func matchValue(values []hl7Value, fhirValue string, delim hl7.Delimiters) (hl7Value, bool) {
+	for _, value := range values {
+		if sourceMatches(value.raw, fhirValue, delim) {
+			return value, true
+		}
+	}
+	return hl7Value{}, false
+}
+
+// sourceMatches reports whether a FHIR value is the HL7 value as is, unescaped, as a number or as a formatted date
+func sourceMatches(raw, fhirValue string, delim hl7.Delimiters) bool {
+	if raw == fhirValue || strings.EqualFold(hl7.Unescape(raw, delim), fhirValue) {
+		return true
+	}
+
+	if hl7DateTime.MatchString(raw) {
+		for _, format := range []func(string) string{fhir.FormatDate, fhir.FormatDateTime, fhir.FormatInstant} {
+			formatted := format(raw)
+			if formatted == "" {
+				continue
+			}
+			//Times may have been moved into the profile's timezone, so compare without the offset
+			if formatted == fhirValue || len(formatted) >= 19 && len(fhirValue) >= 19 && formatted[:19] == fhirValue[:19] {
+				return true
+			}
+		}
+		return false
+	}
+
+	number, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return false
+	}
+	converted, err := strconv.ParseFloat(fhirValue, 64)
+	return err == nil && number == converted
+}
@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToBundleWithLineage(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-100||||F\r" +
+		"OBX|2|NM|2160-0^Creatinine^LN||1.1|mg/dL|0.6-1.2||||F")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	_, lineage, _, err := ConvertToBundleWithLineage(msg, DefaultConvertOptions())
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithLineage() returned error: %v", err)
+	}
+
+	sources := map[string]Lineage{}
+	for _, entry := range lineage {
+		sources[entry.Path] = entry
+	}
+
+	//Find the creatinine Observation by its code, so the paths don't depend on entry order
+	var creatinine string
+	for path, entry := range sources {
+		if strings.HasSuffix(path, ".resource.code.coding[0].code") && entry.Value == "2160-0" {
+			creatinine = strings.TrimSuffix(path, ".code.coding[0].code")
+		}
+	}
+	if creatinine == "" {
+		t.Fatalf("Expected a lineage entry for the creatinine code, got %v", lineage)
+	}
+
+	tests := map[string]string{
+		creatinine + ".code.coding[0].code":    "OBX[2]-3.1",
+		creatinine + ".valueQuantity.value":    "OBX[2]-5",
+		creatinine + ".valueQuantity.unit":     "OBX[2]-6",
+		creatinine + ".referenceRange[0].text": "OBX[2]-7",
+	}
+	for path, want := range tests {
+		if got := sources[path].Source; got != want {
+			t.Errorf("Expected %s to come from %s, got %q", path, want, got)
+		}
+	}
+	for _, element := range []string{".resourceType", ".id"} {
+		if got := sources[creatinine+element].Source; got != "" {
+			t.Errorf("Expected %s to have no HL7 source, got %q", element, got)
+		}
+	}
+
+	//Translated values come from the field recorded for their element, with the value as received
+	if got := sources[creatinine+".status"]; got.Source != "OBX[2]-11" || got.RawValue != "F" {
+		t.Errorf("Expected status to come from OBX[2]-11 F, got %s %q", got.Source, got.RawValue)
+	}
+	var gender Lineage
+	for path, entry := range sources {
+		if strings.HasSuffix(path, ".resource.gender") {
+			gender = entry
+		}
+	}
+	if gender.Value != "male" || gender.Source != "PID[1]-8" || gender.RawValue != "M" {
+		t.Errorf("Expected gender male to come from PID[1]-8 M, got %+v", gender)
+	}
+
+	var report bytes.Buffer
+	if err := WriteLineageReport(&report, lineage); err != nil {
+		t.Fatalf("WriteLineageReport() returned error: %v", err)
+	}
+	if !strings.Contains(report.String(), "OBX[2]-5") {
+		t.Errorf("Expected the report to list OBX[2]-5, got:\n%s", report.String())
+	}
+}
@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// buildLocationResource converts a PL repetition (unit^room^bed^facility) to a FHIR Location
+func buildLocationResource(rep hl7.Repetition) *fhir.Location {
+	unit := getComponentValue(rep, 1)
+	room := getComponentValue(rep, 2)
+	bed := getComponentValue(rep, 3)
+	facility := getComponentValue(rep, 4)
+
+	var parts []string
+	for _, part := range []string{facility, unit, room, bed} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if unit == "" && room == "" && bed == "" {
+		return nil
+	}
+
+	location := &fhir.Location{
+		ResourceType: "Location",
+		ID:           "location-" + strings.Join(parts, "-"),
+		Status:       "active",
+		Name:         locationDisplay(unit, room, bed),
+		Mode:         "instance",
+	}
+
+	//PL-9 Location Description
+	description := getComponentValue(rep, 9)
+	if description != "" {
+		location.Name = description
+	}
+
+	return location
+}
+
+// locationDisplay builds a display name like "ICU Room 0101 Bed 01"
+func locationDisplay(unit, room, bed string) string {
+	display := unit
+	if room != "" {
+		display += " Room " + room
+	}
+	if bed != "" {
+		display += " Bed " + bed
+	}
+	return strings.TrimSpace(display)
+}
+
+// addLocation appends a Location unless one with the same ID is already present
+func addLocation(locations []*fhir.Location, location *fhir.Location) []*fhir.Location {
+	for _, existing := range locations {
+		if existing.ID == location.ID {
+			return locations
+		}
+	}
+	return append(locations, location)
+}
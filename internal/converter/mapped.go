@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConvertWithMappings builds resources from the declarative mapping rules in the context's options
+func ConvertWithMappings(cc *ConversionContext) ([]fhir.Resource, error) {
+	set := cc.Options.Mappings
+	if set == nil {
+		return nil, nil
+	}
+
+	mapped, err := set.Apply(cc.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []fhir.Resource
+	for _, resource := range mapped {
+		typed := fhir.NewResource(resource.Type)
+		if typed == nil {
+			return nil, fmt.Errorf("mapping builds unsupported resource type %s", resource.Type)
+		}
+
+		data, err := json.Marshal(resource.Data)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, typed); err != nil {
+			return nil, fmt.Errorf("mapping for %s: %w", resource.Type, err)
+		}
+		cc.RecordSegment(typed, resource.Segment)
+		resources = append(resources, typed)
+	}
+
+	return resources, nil
+}
+
+// applyMappings replaces the resources the built-in conversion made from the segment occurrences the mapping set
+// rebuilds, keeping their place in the bundle, and adds the mapped resources that have no built-in counterpart
+func applyMappings(cc *ConversionContext) error {
+	if cc.Options.Mappings == nil {
+		return nil
+	}
+
+	resources, err := ConvertWithMappings(cc)
+	if err != nil {
+		return err
+	}
+
+	bundle := cc.Bundle
+	for _, resource := range resources {
+		resourceType, id := resource.GetResourceType(), resource.GetID()
+		if i := builtEntry(cc, resourceType, cc.SourceSegment(resource)); i >= 0 {
+			bundle.Entry[i] = fhir.BundleEntry{
+				FullURL:  "urn:uuid:" + fhir.UUIDv5(fhir.NamespaceURL, resourceType+"/"+id),
+				Resource: resource,
+			}
+			continue
+		}
+		bundle.AddEntry(resourceType, id, resource)
+	}
+
+	return nil
+}
+
+// builtEntry returns the index of the bundle entry of a type built from segment, or -1 when there is none
+func builtEntry(cc *ConversionContext, resourceType string, segment *hl7.Segment) int {
+	if segment == nil {
+		return -1
+	}
+	for i, entry := range cc.Bundle.Entry {
+		if entry.Resource != nil && entry.Resource.GetResourceType() == resourceType && cc.SourceSegment(entry.Resource) == segment {
+			return i
+		}
+	}
+	return -1
+}
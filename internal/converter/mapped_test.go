@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/internal/mapping"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestDefaultMappingsMatchBuiltInConversion(t *testing.T) {
+	defaults, err := mapping.Defaults()
+	if err != nil {
+		t.Fatalf("Defaults() returned error: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "testdata", "*.hl7"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Expected sample messages, got %v (%v)", files, err)
+	}
+
+	messages := map[string]*hl7.Message{}
+	for _, file := range files {
+		messages[filepath.Base(file)] = parseSampleFile(t, file)
+	}
+
+	//The ADT events that drive Encounter status, period, locations and history, with a prior location and discharge
+	raw, err := os.ReadFile(filepath.Join("..", "..", "testdata", "sample.hl7"))
+	if err != nil {
+		t.Fatalf("ReadFile() returned error: %v", err)
+	}
+	visit := strings.Replace(string(raw), "ICU^0101^01||||", "ICU^0101^01|||MED^0202^02|", 1)
+	discharged := strings.Replace(visit, "|||||||20231115120000|", "|||||||20231115120000|20231120090000|", 1)
+	for _, trigger := range []string{"A02", "A03", "A04", "A05", "A06", "A07", "A08", "A11", "A12", "A13", "A14", "A17", "A21", "A22", "A27", "A38"} {
+		for name, text := range map[string]string{"": visit, " discharged": discharged} {
+			event := strings.Replace(strings.Replace(text, "ADT^A01", "ADT^"+trigger, 1), "EVN|A01", "EVN|"+trigger, 1)
+			msg, err := hl7.Parse(event)
+			if err != nil {
+				t.Fatalf("%s: Parse() returned error: %v", trigger, err)
+			}
+			messages["ADT^"+trigger+name] = msg
+		}
+	}
+
+	//Partial dates, ISO assigning authorities, results that are not plain numbers and an OBR-22 report time
+	edgeCases := map[string]string{
+		"partial dates": "MSH|^~\\&|ADT|FAC1|EHR|FAC2|20231115120000||ADT^A01|MSG001|P|2.5\r" +
+			"PID|1||12345^^^MRN||Doe^John||198001|M\rPV1|1|I|ICU^0101^01||||||||||||||||V1|||||||||||||||||||||||||2023",
+		"merge with an ISO authority": "MSH|^~\\&|ADT|FAC1|EHR|FAC2|20231120093000||ADT^A40|MSG001|P|2.5\r" +
+			"PID|1||583295^^^ADT1&2.16.840.1.113883.19.5&ISO^MR||DOE^JOHN||19800115|M\r" +
+			"MRG|583301^^^ADT1&2.16.840.1.113883.19.5&ISO^MR||||||DOE^JON",
+		"result values": "MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+			"PID|1||12345^^^MRN||Doe^John||1980|M\r" +
+			"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000" + strings.Repeat("|", 15) + "202311151200\r" +
+			"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-100||||F|||20231115\r" +
+			"OBX|2|NM|2160-0^Creatinine^LN||pending|mg/dL|||||F\r" +
+			"OBX|3|NM|1975-2^Bilirubin^LN||<5|mg/dL|||||F\r" +
+			"OBX|4|NM|1742-6^ALT^LN||>= 40|U/L|||||P\r" +
+			"OBX|5|SN|2823-3^Potassium^LN||<^3.1|mmol/L|||||F",
+	}
+	for name, text := range edgeCases {
+		msg, err := hl7.Parse(text)
+		if err != nil {
+			t.Fatalf("%s: Parse() returned error: %v", name, err)
+		}
+		messages[name] = msg
+	}
+
+	for name, msg := range messages {
+		builtIn, err := ConvertToBundle(msg)
+		if err != nil {
+			t.Fatalf("%s: ConvertToBundle() returned error: %v", name, err)
+		}
+
+		opts := DefaultConvertOptions()
+		opts.Mappings = defaults
+		mapped, err := ConvertToBundleWithOptions(msg, opts)
+		if err != nil {
+			t.Fatalf("%s: ConvertToBundleWithOptions() returned error: %v", name, err)
+		}
+
+		want, got := decodeBundle(t, builtIn), decodeBundle(t, mapped)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: the default mappings differ from the built-in conversion at %v and %v",
+				name, droppedPaths(want, got, ""), droppedPaths(got, want, ""))
+		}
+	}
+}
+
+// decodeBundle converts a bundle to decoded JSON
+func decodeBundle(t *testing.T, bundle interface{}) map[string]interface{} {
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
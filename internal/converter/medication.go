@@ -0,0 +1,379 @@
+package converter
+
+import (
+	"strconv"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// pharmacySegments are the segments that belong to a pharmacy ORC group
+var pharmacySegments = []string{"RXO", "RXE", "RXD", "RXA", "RXR", "TQ1"}
+
+// ConvertToMedicationRequests converts ORC/RXE (or RXO) order groups to FHIR MedicationRequests
+func ConvertToMedicationRequests(cc *ConversionContext) ([]*fhir.MedicationRequest, []*fhir.Medication, []*fhir.Practitioner, error) {
+	msg := cc.Message
+	var requests []*fhir.MedicationRequest
+	var medications []*fhir.Medication
+	var practitioners []*fhir.Practitioner
+
+	groups := msg.GetGroups("ORC", pharmacySegments...)
+	for i, group := range groups {
+		orc := group.Head
+		rxe := group.GetSegment("RXE")
+		rxo := group.GetSegment("RXO")
+		if rxe == nil && rxo == nil {
+			continue
+		}
+
+		request := &fhir.MedicationRequest{
+			ResourceType: "MedicationRequest",
+			ID:           medicationRequestID(orc, i),
+			Identifier:   buildOrderIdentifiers(orc),
+			Status:       mapMedicationOrderStatus(orc),
+			Intent:       "order",
+			Subject:      cc.PatientReference(),
+		}
+
+		//RXE-2 Give Code, falling back to RXO-1 Requested Give Code
+		var medication *fhir.Medication
+		if rxe != nil {
+			medication = buildMedication(cc, rxe.GetField(2), rxe.GetField(6))
+		} else {
+			medication = buildMedication(cc, rxo.GetField(1), rxo.GetField(5))
+		}
+		if medication != nil {
+			medications = addMedication(medications, medication)
+			request.MedicationReference = medicationReference(medication)
+		}
+
+		//ORC-9 Transaction DateTime
+		authored := orc.GetField(9).GetCompontent(1)
+		if authored != "" {
+			request.AuthoredOn = checkedDateTime(cc, orc, 9, authored)
+		}
+
+		//ORC-12 Ordering Provider
+		if practitioner := buildOrderingProvider(orc); practitioner != nil {
+			practitioners = addPractitioner(practitioners, practitioner)
+			request.Requester = &fhir.Reference{
+				Reference: "Practitioner/" + practitioner.ID,
+				Display:   practitionerDisplay(practitioner),
+			}
+		}
+
+		request.DosageInstruction = buildDosageInstruction(cc, group)
+
+		//RXE-10/11 Dispense Amount and Units, RXE-12 Number of Refills
+		if rxe != nil {
+			dispense := &fhir.MedicationDispenseRequest{
+				Quantity: buildQuantity(rxe.GetField(10).GetCompontent(1), rxe.GetField(11)),
+			}
+			if refills, ok := checkedNumber(cc, rxe, 12, rxe.GetField(12).GetCompontent(1)); ok {
+				dispense.NumberOfRepeatsAllowed = int(refills)
+			}
+			if dispense.Quantity != nil || dispense.NumberOfRepeatsAllowed > 0 {
+				request.DispenseRequest = dispense
+			}
+		}
+
+		cc.RecordSegment(request, orc)
+		requests = append(requests, request)
+	}
+
+	return requests, medications, practitioners, nil
+}
+
+// ConvertToMedicationDispenses converts RXD segments from RDS^O13 to FHIR MedicationDispenses
+func ConvertToMedicationDispenses(cc *ConversionContext) ([]*fhir.MedicationDispense, []*fhir.Medication, []*fhir.Practitioner, error) {
+	msg := cc.Message
+	var dispenses []*fhir.MedicationDispense
+	var medications []*fhir.Medication
+	var practitioners []*fhir.Practitioner
+
+	groups := msg.GetGroups("ORC", pharmacySegments...)
+	for i, group := range groups {
+		orc := group.Head
+		for j, rxd := range group.GetSegments("RXD") {
+			dispense := &fhir.MedicationDispense{
+				ResourceType: "MedicationDispense",
+				ID:           "medicationdispense-" + strconv.Itoa(i+1) + "-" + strconv.Itoa(j+1),
+				Status:       mapDispenseStatus(orc),
+				Subject:      cc.PatientReference(),
+			}
+
+			//RXD-7 Prescription Number
+			prescription := rxd.GetField(7).GetCompontent(1)
+			if prescription != "" {
+				dispense.Identifier = []fhir.Identifier{{Value: prescription}}
+			}
+
+			//RXD-2 Dispense/Give Code, RXD-6 Actual Dosage Form
+			medication := buildMedication(cc, rxd.GetField(2), rxd.GetField(6))
+			if medication != nil {
+				medications = addMedication(medications, medication)
+				dispense.MedicationReference = medicationReference(medication)
+			}
+
+			//RXD-3 Date/Time Dispensed
+			dispensed := rxd.GetField(3).GetCompontent(1)
+			if dispensed != "" {
+				dispense.WhenHandedOver = checkedDateTime(cc, rxd, 3, dispensed)
+			}
+
+			//RXD-4/5 Actual Dispense Amount and Units
+			dispense.Quantity = buildQuantity(rxd.GetField(4).GetCompontent(1), rxd.GetField(5))
+
+			//RXD-10 Dispensing Provider
+			providerField := rxd.GetField(10)
+			if providerField != nil && len(providerField.Repetitions) > 0 {
+				if practitioner := buildPractitioner(providerField.Repetitions[0]); practitioner != nil {
+					practitioners = addPractitioner(practitioners, practitioner)
+					dispense.Performer = []fhir.MedicationActor{{
+						Actor: &fhir.Reference{
+							Reference: "Practitioner/" + practitioner.ID,
+							Display:   practitionerDisplay(practitioner),
+						},
+					}}
+				}
+			}
+
+			//Link back to the order this dispense fulfils
+			if group.GetSegment("RXE") != nil || group.GetSegment("RXO") != nil {
+				dispense.AuthorizingPrescription = []fhir.Reference{{
+					Reference: "MedicationRequest/" + medicationRequestID(orc, i),
+				}}
+			}
+
+			dispense.DosageInstruction = buildDosageInstruction(cc, group)
+
+			cc.RecordSegment(dispense, rxd)
+			dispenses = append(dispenses, dispense)
+		}
+	}
+
+	return dispenses, medications, practitioners, nil
+}
+
+// ConvertToMedicationAdministrations converts RXA segments from RAS^O17 to FHIR MedicationAdministrations
+func ConvertToMedicationAdministrations(cc *ConversionContext) ([]*fhir.MedicationAdministration, []*fhir.Medication, []*fhir.Practitioner, error) {
+	msg := cc.Message
+	var administrations []*fhir.MedicationAdministration
+	var medications []*fhir.Medication
+	var practitioners []*fhir.Practitioner
+
+	groups := msg.GetGroups("ORC", pharmacySegments...)
+	for i, group := range groups {
+		orc := group.Head
+		for j, rxa := range group.GetSegments("RXA") {
+			administration := &fhir.MedicationAdministration{
+				ResourceType: "MedicationAdministration",
+				ID:           "medicationadministration-" + strconv.Itoa(i+1) + "-" + strconv.Itoa(j+1),
+				Status:       mapAdministrationStatus(rxa),
+				Subject:      cc.PatientReference(),
+			}
+
+			//RXA-5 Administered Code
+			medication := buildMedication(cc, rxa.GetField(5), rxa.GetField(8))
+			if medication != nil {
+				medications = addMedication(medications, medication)
+				administration.MedicationReference = medicationReference(medication)
+			}
+
+			//RXA-3/4 Administration Start and End DateTime
+			start := rxa.GetField(3).GetCompontent(1)
+			end := rxa.GetField(4).GetCompontent(1)
+			if end != "" && end != start {
+				administration.EffectivePeriod = &fhir.Period{
+					Start: checkedDateTime(cc, rxa, 3, start),
+					End:   checkedDateTime(cc, rxa, 4, end),
+				}
+			} else if start != "" {
+				administration.EffectiveDateTime = checkedDateTime(cc, rxa, 3, start)
+			}
+
+			//RXA-18 Substance/Treatment Refusal Reason
+			if administration.Status == "not-done" {
+				if reason := buildCodeableConcept(cc, rxa.GetField(18)); reason != nil {
+					administration.StatusReason = []fhir.CodeableConcept{*reason}
+				}
+			}
+
+			//RXA-10 Administering Provider
+			providerField := rxa.GetField(10)
+			if providerField != nil && len(providerField.Repetitions) > 0 {
+				if practitioner := buildPractitioner(providerField.Repetitions[0]); practitioner != nil {
+					practitioners = addPractitioner(practitioners, practitioner)
+					administration.Performer = []fhir.MedicationActor{{
+						Actor: &fhir.Reference{
+							Reference: "Practitioner/" + practitioner.ID,
+							Display:   practitionerDisplay(practitioner),
+						},
+					}}
+				}
+			}
+
+			//RXA-6/7 Administered Amount and Units, RXR-1 Route
+			dosage := &fhir.MedicationAdminDosage{
+				Dose: buildQuantity(rxa.GetField(6).GetCompontent(1), rxa.GetField(7)),
+			}
+			if rxr := group.GetSegment("RXR"); rxr != nil {
+				dosage.Route = buildCodeableConcept(cc, rxr.GetField(1))
+			}
+			if dosage.Dose != nil || dosage.Route != nil {
+				administration.Dosage = dosage
+			}
+
+			//Link back to the order this administration fulfils
+			if group.GetSegment("RXE") != nil || group.GetSegment("RXO") != nil {
+				administration.Request = &fhir.Reference{
+					Reference: "MedicationRequest/" + medicationRequestID(orc, i),
+				}
+			}
+
+			cc.RecordSegment(administration, rxa)
+			administrations = append(administrations, administration)
+		}
+	}
+
+	return administrations, medications, practitioners, nil
+}
+
+// medicationRequestID builds the MedicationRequest ID from ORC-2, falling back to the group position
+func medicationRequestID(orc *hl7.Segment, index int) string {
+	placer := orc.GetField(2).GetCompontent(1)
+	if placer != "" {
+		return "medicationrequest-" + placer
+	}
+	return "medicationrequest-" + strconv.Itoa(index+1)
+}
+
+// buildMedication builds a Medication from a give code (NDC, RxNorm) and dosage form
+func buildMedication(cc *ConversionContext, codeField, formField *hl7.Field) *fhir.Medication {
+	code := buildCodeableConcept(cc, codeField)
+	if code == nil {
+		return nil
+	}
+
+	id := codeField.GetCompontent(1)
+	if id == "" {
+		id = code.Text
+	}
+
+	return &fhir.Medication{
+		ResourceType: "Medication",
+		ID:           "medication-" + id,
+		Code:         code,
+		Form:         buildCodeableConcept(cc, formField),
+	}
+}
+
+// addMedication appends a Medication unless one with the same ID is already present
+func addMedication(medications []*fhir.Medication, medication *fhir.Medication) []*fhir.Medication {
+	for _, existing := range medications {
+		if existing.ID == medication.ID {
+			return medications
+		}
+	}
+	return append(medications, medication)
+}
+
+// medicationReference builds a reference to a Medication
+func medicationReference(medication *fhir.Medication) *fhir.Reference {
+	return &fhir.Reference{
+		Reference: "Medication/" + medication.ID,
+		Display:   medication.Code.Text,
+	}
+}
+
+// buildDosageInstruction builds dose, route and timing from RXE (or RXO), RXR and TQ1
+func buildDosageInstruction(cc *ConversionContext, group *hl7.SegmentGroup) []fhir.Dosage {
+	dosage := fhir.Dosage{}
+
+	//TQ1 timing, falling back to RXE-1 Quantity/Timing
+	rxe := group.GetSegment("RXE")
+	dosage.Timing = buildTiming(group.GetSegment("TQ1"))
+	if dosage.Timing == nil && rxe != nil {
+		dosage.Timing = buildTimingFromTQ(rxe.GetField(1))
+	}
+	if dosage.Timing != nil && dosage.Timing.Code != nil {
+		dosage.Text = dosage.Timing.Code.Text
+		dosage.AsNeeded = dosage.Timing.Code.Text == "PRN"
+	}
+
+	//RXE-3/5 Give Amount and Units, falling back to RXO-2/4
+	var dose *fhir.Quantity
+	if rxe != nil {
+		dose = buildQuantity(rxe.GetField(3).GetCompontent(1), rxe.GetField(5))
+	} else if rxo := group.GetSegment("RXO"); rxo != nil {
+		dose = buildQuantity(rxo.GetField(2).GetCompontent(1), rxo.GetField(4))
+	}
+	if dose != nil {
+		dosage.DoseAndRate = []fhir.DoseAndRate{{DoseQuantity: dose}}
+	}
+
+	//RXR-1 Route
+	if rxr := group.GetSegment("RXR"); rxr != nil {
+		dosage.Route = buildCodeableConcept(cc, rxr.GetField(1))
+	}
+
+	if dosage.Timing == nil && dosage.Route == nil && len(dosage.DoseAndRate) == 0 {
+		return nil
+	}
+	return []fhir.Dosage{dosage}
+}
+
+// buildQuantity converts a numeric amount and a CE units field to a FHIR Quantity
+func buildQuantity(amountStr string, unitField *hl7.Field) *fhir.Quantity {
+	if amountStr == "" {
+		return nil
+	}
+
+	amount, err := strconv.ParseFloat(amountStr, 64)
+	if err != nil {
+		return nil
+	}
+
+	quantity := &fhir.Quantity{
+		Value: &amount,
+		Unit:  unitField.GetCompontent(1),
+	}
+
+	system := unitField.GetCompontent(3)
+	if system == "UCUM" {
+		quantity.System = mapCodeSystem(system)
+		quantity.Code = unitField.GetCompontent(1)
+	}
+
+	return quantity
+}
+
+// mapDispenseStatus converts ORC-1 order control to a MedicationDispense status
+func mapDispenseStatus(orc *hl7.Segment) string {
+	switch orc.GetField(1).GetCompontent(1) {
+	case "CA", "CR", "OC":
+		return "cancelled"
+	case "DC", "DR", "OD":
+		return "stopped"
+	case "HD", "HR", "OH":
+		return "on-hold"
+	default:
+		return "completed"
+	}
+}
+
+// mapAdministrationStatus converts RXA-20 completion status and RXA-21 action code
+func mapAdministrationStatus(rxa *hl7.Segment) string {
+	if rxa.GetField(21).GetCompontent(1) == "D" {
+		return "entered-in-error"
+	}
+
+	switch rxa.GetField(20).GetCompontent(1) {
+	case "RE", "NA":
+		return "not-done"
+	case "PA":
+		return "stopped"
+	default:
+		return "completed"
+	}
+}
@@ -0,0 +1,113 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToMedicationRequests(t *testing.T) {
+	cc := newSampleContext(t, "sample-rde.hl7")
+	requests, medications, practitioners, err := ConvertToMedicationRequests(cc)
+	if err != nil {
+		t.Fatalf("ConvertToMedicationRequests() returned error: %v", err)
+	}
+	if len(requests) != 1 || len(medications) != 1 || len(practitioners) != 1 {
+		t.Fatalf("Expected 1 request, medication and practitioner, got %d, %d and %d", len(requests), len(medications), len(practitioners))
+	}
+
+	request, medication := requests[0], medications[0]
+	if len(request.Identifier) != 2 || len(request.DosageInstruction) != 1 || request.DispenseRequest == nil ||
+		request.MedicationReference == nil || request.Requester == nil || medication.Form == nil {
+		t.Fatalf("Expected every sample field to be converted, got %+v", request)
+	}
+	dosage := request.DosageInstruction[0]
+	if dosage.Timing == nil || dosage.Timing.Repeat == nil || dosage.Route == nil || len(dosage.DoseAndRate) != 1 {
+		t.Fatalf("Expected timing, route and dose, got %+v", dosage)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id from ORC-2", request.ID, "medicationrequest-ORD445566"},
+		{"status from ORC-1", request.Status, "active"},
+		{"intent", request.Intent, "order"},
+		{"subject", request.Subject.Reference, "Patient/583295"},
+		{"ORC-2 placer number", request.Identifier[0].Value, "ORD445566"},
+		{"ORC-2 placer type", request.Identifier[0].Type.Coding[0].Code, "PLAC"},
+		{"ORC-2 namespace is not a system", request.Identifier[0].System, ""},
+		{"ORC-2 assigner", request.Identifier[0].Assigner.Display, "EMR"},
+		{"ORC-3 filler number", request.Identifier[1].Value, "RX778899"},
+		{"ORC-3 assigner", request.Identifier[1].Assigner.Display, "PHARM"},
+		{"ORC-9 authored on", request.AuthoredOn, "2023-11-15T14:55:00"},
+		{"ORC-12 requester", request.Requester.Reference, "Practitioner/practitioner-1234567"},
+		{"RXE-2 medication", request.MedicationReference.Reference, "Medication/medication-00409-4888-01"},
+		{"RXE-2 NDC system", medication.Code.Coding[0].System, "http://hl7.org/fhir/sid/ndc"},
+		{"RXE-6 form", medication.Form.Coding[0].Code, "INJ"},
+		{"TQ1-3 timing", dosage.Timing.Code.Text, "Q6H"},
+		{"TQ1-7 start", dosage.Timing.Repeat.BoundsPeriod.Start, "2023-11-15T16:00:00"},
+		{"RXE-5 dose unit", dosage.DoseAndRate[0].DoseQuantity.Code, "mg"},
+		{"RXR-1 route", dosage.Route.Coding[0].Code, "IV"},
+		{"RXE-11 dispense unit", request.DispenseRequest.Quantity.Unit, "EA"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, test.got)
+		}
+	}
+	if *dosage.DoseAndRate[0].DoseQuantity.Value != 4 || *request.DispenseRequest.Quantity.Value != 10 ||
+		request.DispenseRequest.NumberOfRepeatsAllowed != 2 {
+		t.Errorf("Expected a 4 mg dose, 10 dispensed and 2 refills, got %+v", request.DispenseRequest)
+	}
+}
+
+func TestMapMedicationOrderStatus(t *testing.T) {
+	tests := []struct {
+		orc  string
+		want string
+	}{
+		{"ORC|NW", "active"},
+		{"ORC|XO", "active"},
+		{"ORC|CA", "cancelled"},
+		{"ORC|DC", "stopped"},
+		{"ORC|HD", "on-hold"},
+		{"ORC|SC||||CM", "completed"},
+		{"ORC|SC||||IP", "active"},
+		{"ORC|SC", "unknown"},
+	}
+
+	for _, test := range tests {
+		msg, err := hl7.Parse("MSH|^~\\&|PHARM|HOSPITAL|EMR|HOSPITAL|20231115150000||RDE^O11|MSG001|P|2.5\r" + test.orc)
+		if err != nil {
+			t.Fatalf("Parse() returned error: %v", err)
+		}
+		if got := mapMedicationOrderStatus(msg.GetSegment("ORC")); got != test.want {
+			t.Errorf("%s: expected %s, got %s", test.orc, test.want, got)
+		}
+	}
+}
+
+func TestConvertToMedicationRequestsFromRXO(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|EMR|HOSPITAL|PHARM|HOSPITAL|20231115150000||OMP^O09|MSG001|P|2.5\r" +
+		"PID|1||583295^^^ADT1^MR||DOE^JOHN||19800115|M\r" +
+		"ORC|NW|ORD1^EMR\r" +
+		"RXO|197361^Amlodipine 5 MG Oral Tablet^RXNORM|5||mg^milligram^UCUM")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	requests, medications, _, err := ConvertToMedicationRequests(newTestContext(msg, &fhir.Patient{ResourceType: "Patient", ID: "583295"}))
+	if err != nil || len(requests) != 1 || len(medications) != 1 {
+		t.Fatalf("Expected one request and medication, got %d, %d (%v)", len(requests), len(medications), err)
+	}
+	if medications[0].Code.Coding[0].System != "http://www.nlm.nih.gov/research/umls/rxnorm" {
+		t.Errorf("Expected the RxNorm system, got %s", medications[0].Code.Coding[0].System)
+	}
+	if request := requests[0]; request.DispenseRequest != nil || len(request.DosageInstruction) != 1 ||
+		*request.DosageInstruction[0].DoseAndRate[0].DoseQuantity.Value != 5 {
+		t.Errorf("Expected the RXO-2 dose and no dispense request, got %+v", request)
+	}
+}
@@ -0,0 +1,174 @@
+package converter
+
+import (
+	"encoding/json"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConvertToPatientMerges links the surviving Patient of an A18/A34/A40 merge to the prior Patients in MRG-1.
+// The context's Patient is the survivor built from the first PID; further PID/MRG pairs in an A40 return their own survivor.
+func ConvertToPatientMerges(cc *ConversionContext) ([]*fhir.Patient, error) {
+	msg := cc.Message
+	patient := cc.Patient()
+	var patients []*fhir.Patient
+
+	for i, group := range msg.GetGroups("PID", "MRG") {
+		survivor := patient
+		if i > 0 || survivor == nil {
+			survivor = buildPatient(cc, group.Head)
+			cc.RecordSegment(survivor, group.Head)
+			patients = append(patients, survivor)
+		}
+
+		for _, mrg := range group.GetSegments("MRG") {
+			prior := buildPriorPatient(cc, mrg)
+			if prior == nil {
+				continue
+			}
+
+			active := false
+			prior.Active = &active
+			prior.Link = append(prior.Link, fhir.PatientLink{
+				Other: conditionalPatientReference(survivor),
+				Type:  "replaced-by",
+			})
+
+			survivorActive := true
+			survivor.Active = &survivorActive
+			survivor.Link = append(survivor.Link, fhir.PatientLink{
+				Other: conditionalPatientReference(prior),
+				Type:  "replaces",
+			})
+
+			cc.RecordSegment(prior, mrg)
+			patients = append(patients, prior)
+		}
+	}
+
+	return patients, nil
+}
+
+// isMergeMessage reports whether the message is a patient merge event
+func isMergeMessage(msg *hl7.Message) bool {
+	messageCode, trigger := messageType(msg)
+	if messageCode != "ADT" {
+		return false
+	}
+	switch trigger {
+	case "A18", "A34", "A40": // Merge patient information, merge patient ID, merge patient identifier list
+		return true
+	default:
+		return false
+	}
+}
+
+// buildPriorPatient converts MRG-1 Prior Patient Identifier List to the Patient being merged away
+func buildPriorPatient(cc *ConversionContext, mrg *hl7.Segment) *fhir.Patient {
+	identifiers := buildCXIdentifiers(mrg.GetField(1))
+	if len(identifiers) == 0 {
+		//MRG-4 Prior Patient ID, deprecated in favour of MRG-1
+		identifiers = buildCXIdentifiers(mrg.GetField(4))
+	}
+	if len(identifiers) == 0 {
+		return nil
+	}
+
+	for i := range identifiers {
+		identifiers[i].Use = "old"
+	}
+
+	patient := &fhir.Patient{
+		ResourceType: "Patient",
+		ID:           identifiers[0].Value,
+		Identifier:   identifiers,
+	}
+
+	//MRG-7 Prior Patient Name
+	patient.Name = buildXPNNames(mrg.GetField(7))
+
+	cc.RecordSource(patient, "id", mrg, 1)
+	cc.RecordSource(patient, "id", mrg, 4)
+	cc.RecordSource(patient, "identifier", mrg, 1)
+	cc.RecordSource(patient, "identifier", mrg, 4)
+	cc.RecordSource(patient, "name", mrg, 7)
+
+	return patient
+}
+
+// conditionalPatientReference references a Patient by its first identifier so the server resolves it
+func conditionalPatientReference(patient *fhir.Patient) *fhir.Reference {
+	if len(patient.Identifier) == 0 {
+		return &fhir.Reference{Reference: "Patient/" + patient.ID}
+	}
+
+	identifier := patient.Identifier[0]
+	return &fhir.Reference{
+		Reference: "Patient?" + identifierQuery([]fhir.Identifier{identifier}),
+		Identifier: &fhir.Identifier{
+			System: identifier.System,
+			Value:  identifier.Value,
+		},
+	}
+}
+
+// priorPatientPatch returns the FHIRPath Patch that retires a Patient merged away, or nil for any other resource.
+// MRG carries only the prior identifiers and name, so a PUT of the prior Patient would wipe its demographics;
+// the patch deactivates it and adds its replaced-by links instead.
+func priorPatientPatch(resource fhir.Resource) *fhir.Parameters {
+	if resource == nil || resource.GetResourceType() != "Patient" {
+		return nil
+	}
+
+	//Built-in and mapped Patients alike
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+	var patient fhir.Patient
+	if err := json.Unmarshal(data, &patient); err != nil {
+		return nil
+	}
+
+	var links []fhir.PatientLink
+	for _, link := range patient.Link {
+		if link.Type == "replaced-by" {
+			links = append(links, link)
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	inactive := false
+	patch := &fhir.Parameters{
+		ResourceType: "Parameters",
+		Parameter: []fhir.ParametersParameter{
+			patchOperation("delete", "Patient.active", ""),
+			patchOperation("add", "Patient", "active", fhir.ParametersParameter{Name: "value", ValueBoolean: &inactive}),
+		},
+	}
+	for _, link := range links {
+		patch.Parameter = append(patch.Parameter, patchOperation("add", "Patient", "link", fhir.ParametersParameter{
+			Name: "value",
+			Part: []fhir.ParametersParameter{
+				{Name: "other", ValueReference: link.Other},
+				{Name: "type", ValueCode: link.Type},
+			},
+		}))
+	}
+	return patch
+}
+
+// patchOperation builds one FHIRPath Patch operation on path, naming the element it adds when name is set
+func patchOperation(operation, path, name string, value ...fhir.ParametersParameter) fhir.ParametersParameter {
+	parts := []fhir.ParametersParameter{
+		{Name: "type", ValueCode: operation},
+		{Name: "path", ValueString: path},
+	}
+	if name != "" {
+		parts = append(parts, fhir.ParametersParameter{Name: "name", ValueString: name})
+	}
+	return fhir.ParametersParameter{Name: "operation", Part: append(parts, value...)}
+}
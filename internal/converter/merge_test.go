@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToPatientMerges(t *testing.T) {
+	raw := "MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231120093000||ADT^A40|MSG001|P|2.5\r" +
+		"EVN|A40|20231120093000\r" +
+		"PID|1||583295^^^ADT1^MR||DOE^JOHN||19800115|M\r" +
+		"MRG|583301^^^ADT1&2.16.840.1.113883.19.5&ISO^MR||||||DOE^JON"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	cc := newTestContext(msg)
+	patient, _ := ConvertToPatient(cc)
+	cc.AddResource(patient)
+	merged, err := ConvertToPatientMerges(cc)
+	if err != nil {
+		t.Fatalf("ConvertToPatientMerges() returned error: %v", err)
+	}
+
+	if len(merged) != 1 {
+		t.Fatalf("Expected 1 prior patient, got %d", len(merged))
+	}
+
+	prior := merged[0]
+	if prior.Active == nil || *prior.Active {
+		t.Errorf("Expected prior patient to be inactive")
+	}
+	if len(prior.Link) != 1 || prior.Link[0].Type != "replaced-by" {
+		t.Fatalf("Expected prior patient link replaced-by, got %+v", prior.Link)
+	}
+	//The ADT1 namespace is not a system, so only the value identifies the survivor
+	if prior.Link[0].Other.Reference != "Patient?identifier=583295" {
+		t.Errorf("Expected conditional reference to survivor, got %s", prior.Link[0].Other.Reference)
+	}
+
+	if len(patient.Link) != 1 || patient.Link[0].Type != "replaces" {
+		t.Fatalf("Expected surviving patient link replaces, got %+v", patient.Link)
+	}
+	if patient.Link[0].Other.Reference != "Patient?identifier=urn%3Aoid%3A2.16.840.1.113883.19.5%7C583301" {
+		t.Errorf("Expected conditional reference to prior patient, got %s", patient.Link[0].Other.Reference)
+	}
+}
+
+func TestConvertToBundlePatchesPriorPatient(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231120093000||ADT^A40|MSG001|P|2.5\r" +
+		"EVN|A40|20231120093000\r" +
+		"PID|1||583295^^^ADT1^MR||DOE^JOHN||19800115|M\r" +
+		"MRG|583301^^^ADT1&2.16.840.1.113883.19.5&ISO^MR||||||DOE^JON")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	bundle, err := ConvertToBundle(msg)
+	if err != nil {
+		t.Fatalf("ConvertToBundle() returned error: %v", err)
+	}
+
+	//The prior Patient is patched by identifier instead of replaced by a Patient holding only MRG's name
+	priorURL := "Patient?identifier=urn%3Aoid%3A2.16.840.1.113883.19.5%7C583301"
+	var patch *fhir.Parameters
+	for _, entry := range bundle.Entry {
+		if entry.Request != nil && entry.Request.Method == "PATCH" {
+			if entry.Request.URL != priorURL {
+				t.Errorf("Expected PATCH %s, got %s", priorURL, entry.Request.URL)
+			}
+			patch, _ = entry.Resource.(*fhir.Parameters)
+		}
+		if patient, ok := entry.Resource.(*fhir.Patient); ok && patient.Identifier[0].Value == "583301" {
+			t.Errorf("Expected no Patient body for the prior patient, got %+v", patient)
+		}
+	}
+	if patch == nil || len(patch.Parameter) != 3 {
+		t.Fatalf("Expected a patch deactivating and linking the prior patient, got %+v", patch)
+	}
+	link := patch.Parameter[2].Part
+	if link[2].ValueString != "link" || link[3].Part[0].ValueReference.Reference != "Patient?identifier=583295" ||
+		link[3].Part[1].ValueCode != "replaced-by" {
+		t.Errorf("Expected a replaced-by link to the survivor, got %+v", link)
+	}
+
+	provenance := bundle.Entry[len(bundle.Entry)-1].Resource.(*fhir.Provenance)
+	targeted := false
+	for _, target := range provenance.Target {
+		targeted = targeted || target.Reference == priorURL
+	}
+	if !targeted {
+		t.Errorf("Expected the Provenance to target the patched patient, got %+v", provenance.Target)
+	}
+}
@@ -0,0 +1,167 @@
+package converter
+
+import (
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// Bundle types produced by ConvertToBundleWithOptions
+const (
+	BundleTransaction = "transaction"
+	BundleMessage     = "message"
+)
+
+// ConvertToMessageHeader converts MSH to a FHIR MessageHeader
+func ConvertToMessageHeader(cc *ConversionContext) (*fhir.MessageHeader, error) {
+	msg := cc.Message
+	msh := msg.GetSegment("MSH")
+	if msh == nil {
+		return nil, nil
+	}
+
+	header := &fhir.MessageHeader{
+		ResourceType: "MessageHeader",
+		//MSH-10 Message Control ID
+		ID: msh.GetField(10).GetCompontent(1),
+	}
+
+	//MSH-9.2 Trigger Event (HL7 table 0003)
+	_, trigger := messageType(msg)
+	if trigger != "" {
+		header.EventCoding = &fhir.Coding{
+			System:  "http://terminology.hl7.org/CodeSystem/v2-0003",
+			Code:    trigger,
+			Display: eventDisplay(trigger),
+		}
+	}
+
+	//MSH-3 Sending Application, MSH-4 Sending Facility
+	header.Source = &fhir.MessageSource{
+		Name:     msh.GetField(3).GetCompontent(1),
+		Endpoint: applicationEndpoint(msh.GetField(3), msh.GetField(4)),
+	}
+
+	//MSH-5 Receiving Application, MSH-6 Receiving Facility
+	receiver := msh.GetField(5).GetCompontent(1)
+	facility := msh.GetField(6).GetCompontent(1)
+	if receiver != "" || facility != "" {
+		header.Destination = []fhir.MessageDestination{{
+			Name:     receiver,
+			Endpoint: applicationEndpoint(msh.GetField(5), msh.GetField(6)),
+		}}
+	}
+
+	return header, nil
+}
+
+// toMessageBundle turns a converted bundle into a message bundle that starts with a MessageHeader
+func toMessageBundle(cc *ConversionContext) error {
+	bundle, msg := cc.Bundle, cc.Message
+	header, err := ConvertToMessageHeader(cc)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return nil
+	}
+
+	//Focus on the resources the event is about
+	focusTypes := map[string]bool{}
+	for _, resourceType := range focusResourceTypes(msg) {
+		focusTypes[resourceType] = true
+	}
+	for _, entry := range bundle.Entry {
+		if entry.Resource != nil && focusTypes[entry.Resource.GetResourceType()] {
+			header.Focus = append(header.Focus, fhir.Reference{Reference: entry.FullURL})
+		}
+	}
+
+	entry := fhir.BundleEntry{
+		FullURL:  "urn:uuid:" + fhir.UUIDv5(fhir.NamespaceURL, messageSender(msg)+"|MessageHeader|"+header.ID),
+		Resource: header,
+	}
+	bundle.Entry = append([]fhir.BundleEntry{entry}, bundle.Entry...)
+	bundle.Type = BundleMessage
+
+	//MSH-7 Date/Time of Message
+	if msh := msg.GetSegment("MSH"); msh != nil {
+		bundle.Timestamp = fhir.FormatInstant(msh.GetField(7).GetCompontent(1))
+	}
+
+	return nil
+}
+
+// focusResourceTypes returns the primary resource types of a message, following the v2-to-FHIR message maps
+func focusResourceTypes(msg *hl7.Message) []string {
+	messageCode, _ := messageType(msg)
+
+	if isMergeMessage(msg) {
+		return []string{"Patient"}
+	}
+
+	switch messageCode {
+	case "ADT":
+		return []string{"Patient", "Encounter"}
+	case "ORU":
+		return []string{"DiagnosticReport"}
+	case "ORM", "OML", "OMG":
+		return []string{"ServiceRequest"}
+	case "VXU":
+		return []string{"Immunization"}
+	case "RDE", "OMP":
+		return []string{"MedicationRequest"}
+	case "RDS":
+		return []string{"MedicationDispense"}
+	case "RAS":
+		return []string{"MedicationAdministration"}
+	case "SIU":
+		return []string{"Appointment"}
+	case "MDM":
+		return []string{"DocumentReference"}
+	case "DFT":
+		return []string{"ChargeItem"}
+	default:
+		return []string{"Patient"}
+	}
+}
+
+// applicationEndpoint builds an endpoint URI from an application and facility HD pair
+func applicationEndpoint(application, facility *hl7.Field) string {
+	//HD-2 Universal ID identifies the application globally
+	if oid := application.GetCompontent(2); oid != "" && application.GetCompontent(3) == "ISO" {
+		return "urn:oid:" + oid
+	}
+	return "urn:hl7v2:" + application.GetCompontent(1) + ":" + facility.GetCompontent(1)
+}
+
+// eventDisplay returns the display text for common HL7 table 0003 trigger events
+func eventDisplay(trigger string) string {
+	displays := map[string]string{
+		"A01": "ADT/ACK - Admit/visit notification",
+		"A02": "ADT/ACK - Transfer a patient",
+		"A03": "ADT/ACK - Discharge/end visit",
+		"A04": "ADT/ACK - Register a patient",
+		"A05": "ADT/ACK - Pre-admit a patient",
+		"A08": "ADT/ACK - Update patient information",
+		"A11": "ADT/ACK - Cancel admit/visit notification",
+		"A18": "ADT/ACK - Merge patient information",
+		"A34": "ADT/ACK - Merge patient information - patient ID only",
+		"A40": "ADT/ACK - Merge patient - patient identifier list",
+		"O01": "ORM - Order message",
+		"O11": "RDE - Pharmacy/treatment encoded order",
+		"O13": "RDS - Pharmacy/treatment dispense",
+		"O17": "RAS - Pharmacy/treatment administration",
+		"O21": "OML - Laboratory order",
+		"P03": "DFT/ACK - Post detail financial transaction",
+		"R01": "ORU/ACK - Unsolicited transmission of an observation message",
+		"S12": "SRM/SRR - Notification of new appointment booking",
+		"S13": "SRM/SRR - Notification of appointment rescheduling",
+		"S14": "SRM/SRR - Notification of appointment modification",
+		"S15": "SRM/SRR - Notification of appointment cancellation",
+		"S17": "SRM/SRR - Notification of appointment deletion",
+		"S26": "SRM/SRR - Notification that patient did not show up for scheduled appointment",
+		"T02": "MDM/ACK - Original document notification and content",
+		"V04": "VXU - Unsolicited vaccination record update",
+	}
+	return displays[trigger]
+}
@@ -0,0 +1,63 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToBundleMessageMode(t *testing.T) {
+	raw := "MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-100||||F"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.BundleType = BundleMessage
+	bundle, err := ConvertToBundleWithOptions(msg, opts)
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithOptions() returned error: %v", err)
+	}
+
+	if bundle.Type != "message" {
+		t.Errorf("Expected bundle type message, got %s", bundle.Type)
+	}
+
+	header, ok := bundle.Entry[0].Resource.(*fhir.MessageHeader)
+	if !ok {
+		t.Fatalf("Expected first entry to be a MessageHeader, got %T", bundle.Entry[0].Resource)
+	}
+
+	if header.ID != "MSG001" {
+		t.Errorf("Expected id MSG001, got %s", header.ID)
+	}
+	if header.EventCoding == nil || header.EventCoding.Code != "R01" {
+		t.Errorf("Expected eventCoding R01, got %+v", header.EventCoding)
+	}
+	if header.Source.Endpoint != "urn:hl7v2:LAB:FAC1" {
+		t.Errorf("Expected source endpoint urn:hl7v2:LAB:FAC1, got %s", header.Source.Endpoint)
+	}
+	if len(header.Destination) != 1 || header.Destination[0].Name != "EHR" {
+		t.Errorf("Expected destination EHR, got %+v", header.Destination)
+	}
+
+	if len(header.Focus) != 1 {
+		t.Fatalf("Expected focus on the DiagnosticReport, got %+v", header.Focus)
+	}
+	for _, entry := range bundle.Entry {
+		if entry.Request != nil {
+			t.Errorf("Expected no request in a message bundle, got %+v", entry.Request)
+		}
+		if entry.FullURL == header.Focus[0].Reference {
+			if _, ok := entry.Resource.(*fhir.DiagnosticReport); !ok {
+				t.Errorf("Expected focus to be the DiagnosticReport, got %T", entry.Resource)
+			}
+		}
+	}
+}
@@ -2,53 +2,118 @@ package converter
 
 import (
 	"strconv"
+	"strings"
 
-	"github.com/mourice12/hl7-to-fhir/internal/fhir"
-	"github.com/mourice12/hl7-to-fhir/internal/hl7"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
 )
 
 // ConvertToObservations converts OBX segments to FHIR Observations
-func ConvertToObservations(msg *hl7.Message, patientID string) ([]*fhir.Oberservation, error) {
+func ConvertToObservations(cc *ConversionContext) ([]*fhir.Oberservation, error) {
+	msg := cc.Message
 	var observations []*fhir.Oberservation
 
 	obxSegments := msg.GetSegments("OBX")
+	specimens := specimenReferencesByOBX(msg)
+	orders := observationOrders(msg)
 
 	for _, obx := range obxSegments {
+		//Encapsulated data (PDFs, images) is carried by DocumentReference instead
+		if obx.GetField(2).GetCompontent(1) == "ED" {
+			continue
+		}
+
 		obs := &fhir.Oberservation{
 			ResourceType: "Observation",
 			ID:           "observation-" + obx.GetField(1).GetCompontent(1),
-			Subject: &fhir.Reference{
-				Reference: "Patient/" + patientID,
-			},
+			Subject:      cc.PatientReference(),
+		}
+
+		//OBX-21 Observation Instance Identifier, or the order number and OBX-1
+		if identifier := buildObservationIdentifier(obx, orders[obx]); identifier != nil {
+			obs.Identifier = []fhir.Identifier{*identifier}
+			cc.RecordSource(obs, "identifier", obx, 21)
+			cc.RecordSource(obs, "identifier", orders[obx], 3)
+			cc.RecordSource(obs, "identifier", orders[obx], 2)
 		}
 
 		// OBX-3 observation ID
 		obs.Code = buildObservationCode(obx)
+		cc.RecordSource(obs, "code", obx, 3)
 
-		//OBX-5 value
-		obs.ValueQuantity = buildValueQuantity(obx)
+		//OBX-5 value, typed by OBX-2
+		setObservationValue(cc, obs, obx, msg.Delimiters)
+		for _, element := range []string{"valueQuantity", "valueCodeableConcept", "valueString"} {
+			cc.RecordSource(obs, element, obx, 5)
+		}
+		cc.RecordSource(obs, "valueQuantity.unit", obx, 6)
+
+		//SPM or OBR-15 specimen of the enclosing OBR
+		obs.Specimen = specimens[obx]
 
 		//OBX-7 Reference Range
 		refRange := obx.GetField(7).GetCompontent(1)
 		if refRange != "" {
 			obs.ReferenceRange = []fhir.ReferenceRange{{Text: refRange}}
+			cc.RecordSource(obs, "referenceRange", obx, 7)
 		}
 
 		//OBX-11 Status
 		obs.Status = mapObservationStatus(obx.GetField(11).GetCompontent(1))
+		cc.RecordSource(obs, "status", obx, 11)
 
 		//OBX-14 DateTime
 		obsDateTime := obx.GetField(14).GetCompontent(1)
 		if obsDateTime != "" {
-			obs.EffectiveDateTime = formatDateTime(obsDateTime)
+			obs.EffectiveDateTime = checkedDateTime(cc, obx, 14, obsDateTime)
+			cc.RecordSource(obs, "effectiveDateTime", obx, 14)
+		}
+
+		//OBR-22 Results Rpt/Status Chng - Date/Time of the enclosing order
+		if obr := orders[obx]; obr != nil {
+			obs.Issued = checkedInstant(cc, obr, 22, obr.GetField(22).GetCompontent(1))
+			cc.RecordSource(obs, "issued", obr, 22)
 		}
 
+		cc.RecordSegment(obs, obx)
 		observations = append(observations, obs)
 	}
 
 	return observations, nil
 }
 
+// observationOrders maps each OBX to the OBR it reports on
+func observationOrders(msg *hl7.Message) map[*hl7.Segment]*hl7.Segment {
+	orders := map[*hl7.Segment]*hl7.Segment{}
+	for _, group := range msg.GetGroups("OBR", "OBX") {
+		for _, obx := range group.GetSegments("OBX") {
+			orders[obx] = group.Head
+		}
+	}
+	return orders
+}
+
+// buildObservationIdentifier identifies a result across messages so it can be created only once
+func buildObservationIdentifier(obx, obr *hl7.Segment) *fhir.Identifier {
+	identifier := buildOrderIdentifier(obx.GetField(21), "")
+	if identifier == nil && obr != nil {
+		//OBR-3 Filler Order Number, falling back to OBR-2 Placer Order Number
+		identifier = buildOrderIdentifier(obr.GetField(3), "")
+		if identifier == nil {
+			identifier = buildOrderIdentifier(obr.GetField(2), "")
+		}
+		if identifier != nil {
+			identifier.Value += "-" + obx.GetField(1).GetCompontent(1)
+		}
+	}
+	if identifier == nil {
+		return nil
+	}
+
+	identifier.Type = nil
+	return identifier
+}
+
 // buildObservationCode extracts LOINC code
 func buildObservationCode(obx *hl7.Segment) *fhir.CodeableConcept {
 	codeField := obx.GetField(3)
@@ -79,14 +144,81 @@ func buildObservationCode(obx *hl7.Segment) *fhir.CodeableConcept {
 	}
 }
 
-// buildValueQuantity extracts value
+// setObservationValue converts OBX-5 according to its OBX-2 value type, keeping values that cannot be typed as text
+func setObservationValue(cc *ConversionContext, obs *fhir.Oberservation, obx *hl7.Segment, delim hl7.Delimiters) {
+	valueType := obx.GetField(2).GetCompontent(1)
+	raw := obx.GetField(5).GetCompontent(1)
+	if raw == "" && valueType != "CWE" && valueType != "CE" {
+		return
+	}
+
+	switch valueType {
+	case "NM", "SN":
+		obs.ValueQuantity = buildValueQuantity(obx)
+		if obs.ValueQuantity == nil {
+			obs.ValueString = fieldText(obx.GetField(5), delim)
+			cc.ReportSegment(obx, 5, SeverityWarning, IssueValue, "%s value %q is not numeric and was kept as valueString", valueType, obs.ValueString)
+		}
+	case "CWE", "CE", "CNE":
+		obs.ValueCodeable = buildCodeableConcept(cc, obx.GetField(5))
+	case "ST", "TX", "FT", "":
+		obs.ValueString = hl7.Unescape(raw, delim)
+	default:
+		obs.ValueString = hl7.Unescape(raw, delim)
+		cc.ReportSegment(obx, 2, SeverityInformation, IssueNotSupported, "value type %s is converted as valueString", valueType)
+	}
+}
+
+// fieldText returns the first repetition of a field as text, with components joined by the component separator
+func fieldText(field *hl7.Field, delim hl7.Delimiters) string {
+	if field == nil {
+		return ""
+	}
+	rep := field.GetRepetition(1)
+	if rep == nil {
+		return ""
+	}
+
+	if delim == (hl7.Delimiters{}) {
+		delim = hl7.DefaultDelimiters()
+	}
+	var components []string
+	for _, component := range rep.Components {
+		components = append(components, hl7.Unescape(strings.Join(component.Subcomponents, delim.Subcomponent), delim))
+	}
+	return strings.TrimRight(strings.Join(components, delim.Component), delim.Component)
+}
+
+// buildValueQuantity converts an NM value or an SN comparator^number value, with OBX-6 units
 func buildValueQuantity(obx *hl7.Segment) *fhir.Quantity {
-	valueStr := obx.GetField(5).GetCompontent(1)
+	field := obx.GetField(5)
 	unit := obx.GetField(6).GetCompontent(1)
 
+	comparator := ""
+	valueStr := field.GetCompontent(1)
+	if obx.GetField(2).GetCompontent(1) == "SN" {
+		//SN-1 Comparator, SN-2 Num1; ranges and ratios (SN-3/SN-4) are not quantities
+		comparator, valueStr = valueStr, field.GetCompontent(2)
+		if field.GetCompontent(3) != "" {
+			return nil
+		}
+	} else {
+		for _, prefix := range []string{"<=", ">=", "<", ">"} {
+			if strings.HasPrefix(valueStr, prefix) {
+				comparator, valueStr = prefix, strings.TrimSpace(valueStr[len(prefix):])
+				break
+			}
+		}
+	}
+
 	if valueStr == "" {
 		return nil
 	}
+	switch comparator {
+	case "", "<", "<=", ">", ">=":
+	default:
+		return nil
+	}
 
 	value, err := strconv.ParseFloat(valueStr, 64)
 	if err != nil {
@@ -94,8 +226,9 @@ func buildValueQuantity(obx *hl7.Segment) *fhir.Quantity {
 	}
 
 	return &fhir.Quantity{
-		Value: value,
-		Unit:  unit,
+		Value:      &value,
+		Comparator: comparator,
+		Unit:       unit,
 	}
 }
 
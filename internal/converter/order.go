@@ -0,0 +1,197 @@
+package converter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// orderControls maps each segment with the given name to the ORC that precedes it
+func orderControls(msg *hl7.Message, name string) map[*hl7.Segment]*hl7.Segment {
+	orders := map[*hl7.Segment]*hl7.Segment{}
+	for _, order := range msg.GetGroups("ORC", name) {
+		for _, seg := range order.GetSegments(name) {
+			orders[seg] = order.Head
+		}
+	}
+	return orders
+}
+
+// buildOrderIdentifier converts an EI field (ID^Namespace^UniversalID^Type) to a placer or filler identifier
+func buildOrderIdentifier(field *hl7.Field, typeCode string) *fhir.Identifier {
+	identifier := buildEIIdentifier(field.GetCompontent(1), field.GetCompontent(2), field.GetCompontent(3), field.GetCompontent(4))
+	if identifier == nil {
+		return nil
+	}
+
+	identifier.Type = &fhir.CodeableConcept{
+		Coding: []fhir.Coding{{
+			System: "http://terminology.hl7.org/CodeSystem/v2-0203",
+			Code:   typeCode,
+		}},
+	}
+	return identifier
+}
+
+// buildEIIdentifier converts the parts of an EI. EI.3 Universal ID is the system only when EI.4 says it is an ISO
+// OID; EI.2 Namespace ID is a local name, so it is kept as the assigner for a profile to give it a system.
+func buildEIIdentifier(value, namespace, universalID, universalIDType string) *fhir.Identifier {
+	if value == "" {
+		return nil
+	}
+
+	identifier := &fhir.Identifier{Value: value}
+	if universalID != "" && universalIDType == "ISO" {
+		identifier.System = "urn:oid:" + universalID
+	}
+	if namespace != "" {
+		identifier.Assigner = &fhir.Reference{Display: namespace}
+	}
+	return identifier
+}
+
+// buildOrderIdentifiers extracts placer (ORC-2) and filler (ORC-3) order numbers
+func buildOrderIdentifiers(orc *hl7.Segment) []fhir.Identifier {
+	var identifiers []fhir.Identifier
+
+	if placer := buildOrderIdentifier(orc.GetField(2), "PLAC"); placer != nil {
+		identifiers = append(identifiers, *placer)
+	}
+	if filler := buildOrderIdentifier(orc.GetField(3), "FILL"); filler != nil {
+		identifiers = append(identifiers, *filler)
+	}
+
+	return identifiers
+}
+
+// mapMedicationOrderStatus converts ORC-1 order control, falling back to ORC-5 order status
+func mapMedicationOrderStatus(orc *hl7.Segment) string {
+	switch orc.GetField(1).GetCompontent(1) {
+	case "NW", "OK", "XO", "XR", "RL", "OR", "RO": // New, accepted, changed, released
+		return "active"
+	case "CA", "CR", "OC": // Cancelled
+		return "cancelled"
+	case "DC", "DR", "OD": // Discontinued
+		return "stopped"
+	case "HD", "HR", "OH": // On hold
+		return "on-hold"
+	}
+
+	switch orc.GetField(5).GetCompontent(1) {
+	case "CM":
+		return "completed"
+	case "CA":
+		return "cancelled"
+	case "DC":
+		return "stopped"
+	case "HD":
+		return "on-hold"
+	case "IP", "SC", "A":
+		return "active"
+	default:
+		return "unknown"
+	}
+}
+
+// buildOrderingProvider extracts the ordering provider from ORC-12
+func buildOrderingProvider(orc *hl7.Segment) *fhir.Practitioner {
+	field := orc.GetField(12)
+	if field == nil || len(field.Repetitions) == 0 {
+		return nil
+	}
+	return buildPractitioner(field.Repetitions[0])
+}
+
+// buildTiming builds a FHIR Timing from TQ1 (TQ1-3 repeat pattern, TQ1-7/8 start and end)
+func buildTiming(tq1 *hl7.Segment) *fhir.Timing {
+	if tq1 == nil {
+		return nil
+	}
+
+	return buildTimingFromPattern(
+		tq1.GetField(3).GetCompontent(1),
+		tq1.GetField(7).GetCompontent(1),
+		tq1.GetField(8).GetCompontent(1),
+	)
+}
+
+// buildTimingFromTQ builds a FHIR Timing from a deprecated TQ field (quantity^interval^duration^start^end)
+func buildTimingFromTQ(field *hl7.Field) *fhir.Timing {
+	if field == nil {
+		return nil
+	}
+
+	return buildTimingFromPattern(
+		field.GetCompontent(2),
+		field.GetCompontent(4),
+		field.GetCompontent(5),
+	)
+}
+
+// buildTimingFromPattern converts a repeat pattern like BID or Q6H and optional bounds to a FHIR Timing
+func buildTimingFromPattern(pattern, start, end string) *fhir.Timing {
+	if pattern == "" && start == "" {
+		return nil
+	}
+
+	timing := &fhir.Timing{}
+
+	if pattern != "" {
+		timing.Code = &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{
+				System: "http://terminology.hl7.org/CodeSystem/v3-GTSAbbreviation",
+				Code:   pattern,
+			}},
+			Text: pattern,
+		}
+		timing.Repeat = parseRepeatPattern(pattern)
+	}
+
+	if start != "" {
+		if timing.Repeat == nil {
+			timing.Repeat = &fhir.TimingRepeat{}
+		}
+		timing.Repeat.BoundsPeriod = &fhir.Period{
+			Start: fhir.FormatDateTime(start),
+			End:   fhir.FormatDateTime(end),
+		}
+	}
+
+	return timing
+}
+
+// parseRepeatPattern converts HL7 table 0335 repeat patterns to frequency and period
+func parseRepeatPattern(pattern string) *fhir.TimingRepeat {
+	switch pattern {
+	case "QD", "QAM", "QPM", "QHS", "DAILY":
+		return &fhir.TimingRepeat{Frequency: 1, Period: 1, PeriodUnit: "d"}
+	case "BID":
+		return &fhir.TimingRepeat{Frequency: 2, Period: 1, PeriodUnit: "d"}
+	case "TID":
+		return &fhir.TimingRepeat{Frequency: 3, Period: 1, PeriodUnit: "d"}
+	case "QID":
+		return &fhir.TimingRepeat{Frequency: 4, Period: 1, PeriodUnit: "d"}
+	case "QOD":
+		return &fhir.TimingRepeat{Frequency: 1, Period: 2, PeriodUnit: "d"}
+	}
+
+	//Q<n>S, Q<n>M, Q<n>H, Q<n>D, Q<n>W patterns
+	if len(pattern) < 3 || pattern[0] != 'Q' {
+		return nil
+	}
+
+	units := map[byte]string{'S': "s", 'M': "min", 'H': "h", 'D': "d", 'W': "wk"}
+	unit, ok := units[pattern[len(pattern)-1]]
+	if !ok {
+		return nil
+	}
+
+	period, err := strconv.ParseFloat(strings.TrimSpace(pattern[1:len(pattern)-1]), 64)
+	if err != nil {
+		return nil
+	}
+
+	return &fhir.TimingRepeat{Frequency: 1, Period: period, PeriodUnit: unit}
+}
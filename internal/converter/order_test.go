@@ -0,0 +1,50 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/internal/profile"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestBuildOrderIdentifier(t *testing.T) {
+	tests := []struct {
+		ei       string
+		system   string
+		assigner string
+	}{
+		{"ORD123^LAB", "", "LAB"},
+		{"ORD123^LAB^2.16.840.1.113883.19.4^ISO", "urn:oid:2.16.840.1.113883.19.4", "LAB"},
+		{"ORD123^^http://lab.example.org^URI", "", ""},
+		{"ORD123", "", ""},
+	}
+
+	for _, test := range tests {
+		msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115||ORM^O01|MSG001|P|2.5\rORC|NW|" + test.ei)
+		if err != nil {
+			t.Fatalf("Parse() returned error: %v", err)
+		}
+
+		identifier := buildOrderIdentifier(msg.GetSegment("ORC").GetField(2), "PLAC")
+		if identifier == nil || identifier.Value != "ORD123" || identifier.Type.Coding[0].Code != "PLAC" {
+			t.Fatalf("%s: expected placer identifier ORD123, got %+v", test.ei, identifier)
+		}
+		if identifier.System != test.system {
+			t.Errorf("%s: expected system %q, got %q", test.ei, test.system, identifier.System)
+		}
+		assigner := ""
+		if identifier.Assigner != nil {
+			assigner = identifier.Assigner.Display
+		}
+		if assigner != test.assigner {
+			t.Errorf("%s: expected assigner %q, got %q", test.ei, test.assigner, assigner)
+		}
+
+		//A profile gives the namespace its system
+		p := &profile.Profile{IdentifierSystems: map[string]profile.IdentifierSystem{"LAB": {System: "http://lab.example.org/orders"}}}
+		applyIdentifierSystem(identifier, p)
+		if test.assigner == "LAB" && test.system == "" && identifier.System != "http://lab.example.org/orders" {
+			t.Errorf("%s: expected the profile system for LAB, got %q", test.ei, identifier.System)
+		}
+	}
+}
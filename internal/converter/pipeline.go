@@ -0,0 +1,309 @@
+package converter
+
+import (
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+)
+
+// step converts one part of a message; steps after convertPatientStep are skipped when there is no patient
+type step func(cc *ConversionContext) error
+
+// pipeline runs steps in order as a Handler
+func pipeline(steps ...step) Handler {
+	return func(cc *ConversionContext) error {
+		for _, s := range steps {
+			if err := cc.Err(); err != nil {
+				return err
+			}
+			if err := s(cc); err != nil {
+				return err
+			}
+			if cc.Patient() == nil {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// convertPatientStep converts PID, MRG merges and the PID-18 account
+func convertPatientStep(cc *ConversionContext) error {
+	patient, err := ConvertToPatient(cc)
+	if err != nil {
+		return err
+	}
+	if patient == nil {
+		return nil
+	}
+	cc.Bundle.AddEntry("Patient", patient.ID, patient)
+
+	//Convert Patient Merges
+	if isMergeMessage(cc.Message) {
+		merged, err := ConvertToPatientMerges(cc)
+		if err != nil {
+			return err
+		}
+
+		for _, mergedPatient := range merged {
+			cc.Bundle.AddEntry("Patient", mergedPatient.ID, mergedPatient)
+		}
+	}
+
+	//Convert Account
+	account, err := ConvertToAccount(cc)
+	if err != nil {
+		return err
+	}
+	if account != nil {
+		cc.Bundle.AddEntry("Account", account.ID, account)
+	}
+
+	return nil
+}
+
+// convertEncounterStep converts PV1
+func convertEncounterStep(cc *ConversionContext) error {
+	encounter, err := ConvertToEncounter(cc)
+	if err != nil {
+		return err
+	}
+	if encounter != nil {
+		cc.Bundle.AddEntry("Encounter", encounter.ID, encounter)
+	}
+	return nil
+}
+
+// convertProceduresStep converts PR1
+func convertProceduresStep(cc *ConversionContext) error {
+	procedures, practitioners, err := ConvertToProcedures(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, practitioner := range practitioners {
+		addPractitionerEntry(cc, practitioner)
+	}
+	for _, procedure := range procedures {
+		cc.Bundle.AddEntry("Procedure", procedure.ID, procedure)
+	}
+	return nil
+}
+
+// convertConditionsStep converts DG1
+func convertConditionsStep(cc *ConversionContext) error {
+	conditions, err := ConvertToConditions(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, condition := range conditions {
+		cc.Bundle.AddEntry("Condition", condition.ID, condition)
+	}
+	return nil
+}
+
+// convertAllergiesStep converts AL1
+func convertAllergiesStep(cc *ConversionContext) error {
+	allergies, err := ConvertToAllergies(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, allergy := range allergies {
+		cc.Bundle.AddEntry("AllergyIntolerance", allergy.ID, allergy)
+	}
+	return nil
+}
+
+// convertChargesStep converts DFT FT1
+func convertChargesStep(cc *ConversionContext) error {
+	chargeItems, practitioners, err := ConvertToChargeItems(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, practitioner := range practitioners {
+		addPractitionerEntry(cc, practitioner)
+	}
+	for _, chargeItem := range chargeItems {
+		cc.Bundle.AddEntry("ChargeItem", chargeItem.ID, chargeItem)
+	}
+	return nil
+}
+
+// convertImmunizationsStep converts VXU RXA; its OBX segments describe the vaccination
+func convertImmunizationsStep(cc *ConversionContext) error {
+	immunizations, practitioners, err := ConvertToImmunizations(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, practitioner := range practitioners {
+		addPractitionerEntry(cc, practitioner)
+	}
+	for _, immunization := range immunizations {
+		cc.Bundle.AddEntry("Immunization", immunization.ID, immunization)
+	}
+	return nil
+}
+
+// convertPharmacyStep converts pharmacy orders, dispenses and administrations
+func convertPharmacyStep(cc *ConversionContext) error {
+	messageCode, _ := messageType(cc.Message)
+
+	requests, medications, practitioners, err := ConvertToMedicationRequests(cc)
+	if err != nil {
+		return err
+	}
+
+	var dispenses []*fhir.MedicationDispense
+	if messageCode == "RDS" {
+		var dispenseMedications []*fhir.Medication
+		var dispensePractitioners []*fhir.Practitioner
+		dispenses, dispenseMedications, dispensePractitioners, err = ConvertToMedicationDispenses(cc)
+		if err != nil {
+			return err
+		}
+		for _, medication := range dispenseMedications {
+			medications = addMedication(medications, medication)
+		}
+		practitioners = append(practitioners, dispensePractitioners...)
+	}
+
+	var administrations []*fhir.MedicationAdministration
+	if messageCode == "RAS" {
+		var adminMedications []*fhir.Medication
+		var adminPractitioners []*fhir.Practitioner
+		administrations, adminMedications, adminPractitioners, err = ConvertToMedicationAdministrations(cc)
+		if err != nil {
+			return err
+		}
+		for _, medication := range adminMedications {
+			medications = addMedication(medications, medication)
+		}
+		practitioners = append(practitioners, adminPractitioners...)
+	}
+
+	for _, practitioner := range practitioners {
+		addPractitionerEntry(cc, practitioner)
+	}
+	for _, medication := range medications {
+		cc.Bundle.AddEntry("Medication", medication.ID, medication)
+	}
+	for _, request := range requests {
+		cc.Bundle.AddEntry("MedicationRequest", request.ID, request)
+	}
+	for _, dispense := range dispenses {
+		cc.Bundle.AddEntry("MedicationDispense", dispense.ID, dispense)
+	}
+	for _, administration := range administrations {
+		cc.Bundle.AddEntry("MedicationAdministration", administration.ID, administration)
+	}
+	return nil
+}
+
+// convertAppointmentStep converts SIU SCH and its resource groups
+func convertAppointmentStep(cc *ConversionContext) error {
+	appointment, practitioners, locations, err := ConvertToAppointment(cc)
+	if err != nil {
+		return err
+	}
+	if appointment == nil {
+		return nil
+	}
+
+	schedules, slots := ConvertToSlots(appointment)
+
+	for _, practitioner := range practitioners {
+		addPractitionerEntry(cc, practitioner)
+	}
+	for _, location := range locations {
+		cc.Bundle.AddEntry("Location", location.ID, location)
+	}
+	for _, schedule := range schedules {
+		cc.Bundle.AddEntry("Schedule", schedule.ID, schedule)
+	}
+	for _, slot := range slots {
+		cc.Bundle.AddEntry("Slot", slot.ID, slot)
+	}
+	cc.Bundle.AddEntry("Appointment", appointment.ID, appointment)
+	return nil
+}
+
+// convertDocumentsStep converts MDM TXA/OBX and ORU text reports
+func convertDocumentsStep(cc *ConversionContext) error {
+	documents, binaries, practitioners, err := ConvertToDocumentReferences(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, practitioner := range practitioners {
+		addPractitionerEntry(cc, practitioner)
+	}
+	for _, binary := range binaries {
+		cc.Bundle.AddEntry("Binary", binary.ID, binary)
+	}
+	for _, document := range documents {
+		cc.Bundle.AddEntry("DocumentReference", document.ID, document)
+	}
+	return nil
+}
+
+// convertObservationsStep converts OBX
+func convertObservationsStep(cc *ConversionContext) error {
+	observations, err := ConvertToObservations(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, obs := range observations {
+		cc.Bundle.AddEntry("Observation", obs.ID, obs)
+	}
+	return nil
+}
+
+// convertServiceRequestsStep converts ORC/OBR orders
+func convertServiceRequestsStep(cc *ConversionContext) error {
+	requests, practitioners, err := ConvertToServiceRequests(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, practitioner := range practitioners {
+		addPractitionerEntry(cc, practitioner)
+	}
+	for _, request := range requests {
+		cc.Bundle.AddEntry("ServiceRequest", request.ID, request)
+	}
+	return nil
+}
+
+// convertSpecimensStep converts SPM, or OBR-15
+func convertSpecimensStep(cc *ConversionContext) error {
+	specimens, err := ConvertToSpecimens(cc)
+	if err != nil {
+		return err
+	}
+
+	for _, specimen := range specimens {
+		cc.Bundle.AddEntry("Specimen", specimen.ID, specimen)
+	}
+	return nil
+}
+
+// convertDiagnosticReportStep converts OBR to a DiagnosticReport with the converted observations as results
+func convertDiagnosticReportStep(cc *ConversionContext) error {
+	report, err := ConvertToDiagnosticReport(cc)
+	if err != nil {
+		return err
+	}
+	if report == nil {
+		return nil
+	}
+
+	for _, resource := range cc.Resources("Observation") {
+		obs := resource.(*fhir.Oberservation)
+		report.Result = append(report.Result, *cc.Reference("Observation", obs.ID))
+	}
+	cc.Bundle.AddEntry("DiagnosticReport", report.ID, report)
+	return nil
+}
@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// buildPractitioner converts an XCN repetition (ID^Family^Given^Middle) to a FHIR Practitioner
+func buildPractitioner(rep hl7.Repetition) *fhir.Practitioner {
+	id := getComponentValue(rep, 1)
+	family := getComponentValue(rep, 2)
+
+	if id == "" && family == "" {
+		return nil
+	}
+
+	practitioner := &fhir.Practitioner{
+		ResourceType: "Practitioner",
+		ID:           "practitioner-" + id,
+	}
+	if id == "" {
+		practitioner.ID = "practitioner-" + family
+	}
+
+	if id != "" {
+		identifier := fhir.Identifier{Value: id}
+
+		//XCN-9 Assigning Authority
+		applyAssigningAuthority(&identifier, rep, 9)
+		practitioner.Identifier = []fhir.Identifier{identifier}
+	}
+
+	name := fhir.HumanName{Family: family}
+	given := getComponentValue(rep, 3)
+	if given != "" {
+		name.Given = append(name.Given, given)
+	}
+	middle := getComponentValue(rep, 4)
+	if middle != "" {
+		name.Given = append(name.Given, middle)
+	}
+	if name.Family != "" || len(name.Given) > 0 {
+		practitioner.Name = []fhir.HumanName{name}
+	}
+
+	return practitioner
+}
+
+// practitionerDisplay builds a display name like "John Smith" for a Practitioner
+func practitionerDisplay(practitioner *fhir.Practitioner) string {
+	if len(practitioner.Name) == 0 {
+		return ""
+	}
+
+	name := practitioner.Name[0]
+	display := name.Family
+	if len(name.Given) > 0 {
+		display = name.Given[0] + " " + display
+	}
+	return display
+}
+
+// addPractitioner appends a Practitioner unless one with the same ID is already present
+func addPractitioner(practitioners []*fhir.Practitioner, practitioner *fhir.Practitioner) []*fhir.Practitioner {
+	for _, existing := range practitioners {
+		if existing.ID == practitioner.ID {
+			return practitioners
+		}
+	}
+	return append(practitioners, practitioner)
+}
+
+// addPractitionerEntry adds a Practitioner to the bundle unless another step already added one with the same ID,
+// since the same provider is often named by several segments
+func addPractitionerEntry(cc *ConversionContext, practitioner *fhir.Practitioner) {
+	for _, existing := range cc.Resources("Practitioner") {
+		if existing.GetID() == practitioner.ID {
+			return
+		}
+	}
+	cc.Bundle.AddEntry("Practitioner", practitioner.ID, practitioner)
+}
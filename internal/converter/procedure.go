@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConvertToProcedures converts PR1 segments to FHIR Procedures and the Practitioners performing them
+func ConvertToProcedures(cc *ConversionContext) ([]*fhir.Procedure, []*fhir.Practitioner, error) {
+	msg := cc.Message
+	var procedures []*fhir.Procedure
+	var practitioners []*fhir.Practitioner
+
+	pr1Segments := msg.GetSegments("PR1")
+	for _, pr1 := range pr1Segments {
+		procedure := &fhir.Procedure{
+			ResourceType: "Procedure",
+			ID:           "procedure-" + pr1.GetField(1).GetCompontent(1),
+			Status:       "completed",
+			Subject:      cc.PatientReference(),
+			Encounter:    cc.EncounterReference(),
+		}
+
+		//PR1-3 Procedure Code
+		procedure.Code = buildProcedureCode(pr1)
+
+		//PR1-5 Procedure DateTime
+		procDate := pr1.GetField(5).GetCompontent(1)
+		if procDate != "" {
+			procedure.PerformedDateTime = checkedDateTime(cc, pr1, 5, procDate)
+		}
+
+		//PR1-8 Anesthesiologist (deprecated), PR1-11 Surgeon, PR1-12 Procedure Practitioner
+		for _, role := range []struct {
+			field    int
+			function *fhir.CodeableConcept
+		}{
+			{11, procedureFunction("304292004", "Surgeon")},
+			{8, procedureFunction("88189002", "Anesthesiologist")},
+			{12, procedureFunction("158965000", "Medical practitioner")},
+		} {
+			field := pr1.GetField(role.field)
+			if field == nil {
+				continue
+			}
+
+			for _, rep := range field.Repetitions {
+				practitioner := buildPractitioner(rep)
+				if practitioner == nil {
+					continue
+				}
+
+				practitioners = addPractitioner(practitioners, practitioner)
+				procedure.Performer = append(procedure.Performer, fhir.ProcedurePerformer{
+					Function: role.function,
+					Actor: &fhir.Reference{
+						Reference: "Practitioner/" + practitioner.ID,
+						Display:   practitionerDisplay(practitioner),
+					},
+				})
+			}
+		}
+
+		cc.RecordSegment(procedure, pr1)
+		procedures = append(procedures, procedure)
+	}
+
+	return procedures, practitioners, nil
+}
+
+// buildProcedureCode extracts the procedure code from PR1-3, falling back to PR1-2 for the coding system
+func buildProcedureCode(pr1 *hl7.Segment) *fhir.CodeableConcept {
+	codeField := pr1.GetField(3)
+	if codeField == nil {
+		return nil
+	}
+
+	code := codeField.GetCompontent(1)
+	display := codeField.GetCompontent(2)
+	system := codeField.GetCompontent(3)
+
+	//PR1-2 Procedure Coding Method (deprecated)
+	if system == "" {
+		system = pr1.GetField(2).GetCompontent(1)
+	}
+
+	//PR1-4 Procedure Description
+	if display == "" {
+		display = pr1.GetField(4).GetCompontent(1)
+	}
+
+	if code == "" {
+		if display == "" {
+			return nil
+		}
+		return &fhir.CodeableConcept{Text: display}
+	}
+
+	return &fhir.CodeableConcept{
+		Coding: []fhir.Coding{{
+			System:  mapCodeSystem(system),
+			Code:    code,
+			Display: display,
+		}},
+		Text: display,
+	}
+}
+
+// procedureFunction builds a SNOMED CT performer function
+func procedureFunction(code, display string) *fhir.CodeableConcept {
+	return &fhir.CodeableConcept{
+		Coding: []fhir.Coding{{
+			System:  "http://snomed.info/sct",
+			Code:    code,
+			Display: display,
+		}},
+	}
+}
@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToProcedures(t *testing.T) {
+	raw := `MSH|^~\&|EPIC|FAC1|CERNER|FAC2|20231115||ADT^A01|MSG001|P|2.5
+PID|1||12345^^^MRN||Doe^John||19800115|M
+PV1|1|I|ICU^0101^01||||||||||||||||V100
+PR1|1||0DTJ4ZZ^Resection of appendix^I10P||20231115103000||||||1234567^SMITH^ROBERT~7654321^BROWN^ANNE|9876543^JONES^MARY`
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	cc := newTestContext(msg,
+		&fhir.Patient{ResourceType: "Patient", ID: "12345"},
+		&fhir.Encounter{ResourceType: "Encounter", ID: "V100"})
+	procedures, practitioners, err := ConvertToProcedures(cc)
+	if err != nil {
+		t.Fatalf("ConvertToProcedures() returned error: %v", err)
+	}
+
+	if len(procedures) != 1 {
+		t.Fatalf("Expected 1 procedure, got %d", len(procedures))
+	}
+
+	procedure := procedures[0]
+	if procedure.Code.Coding[0].System != "http://www.cms.gov/Medicare/Coding/ICD10" {
+		t.Errorf("Expected ICD-10-PCS system, got %s", procedure.Code.Coding[0].System)
+	}
+
+	if procedure.PerformedDateTime != "2023-11-15T10:30:00" {
+		t.Errorf("Expected performedDateTime 2023-11-15T10:30:00, got %s", procedure.PerformedDateTime)
+	}
+
+	if procedure.Encounter == nil || procedure.Encounter.Reference != "Encounter/V100" {
+		t.Errorf("Expected encounter reference Encounter/V100, got %v", procedure.Encounter)
+	}
+
+	if len(procedure.Performer) != 3 {
+		t.Fatalf("Expected 3 performers, got %d", len(procedure.Performer))
+	}
+
+	//PR1-11 Surgeon (two repetitions), then PR1-12 Procedure Practitioner
+	roles := []struct {
+		actor   string
+		code    string
+		display string
+	}{
+		{"Practitioner/practitioner-1234567", "304292004", "Surgeon"},
+		{"Practitioner/practitioner-7654321", "304292004", "Surgeon"},
+		{"Practitioner/practitioner-9876543", "158965000", "Medical practitioner"},
+	}
+	for i, want := range roles {
+		performer := procedure.Performer[i]
+		if performer.Actor == nil || performer.Actor.Reference != want.actor {
+			t.Errorf("Expected performer %d to be %s, got %v", i, want.actor, performer.Actor)
+		}
+		if performer.Function == nil || len(performer.Function.Coding) != 1 {
+			t.Errorf("Expected performer %d to have one function coding, got %v", i, performer.Function)
+			continue
+		}
+		coding := performer.Function.Coding[0]
+		if coding.Code != want.code || coding.Display != want.display {
+			t.Errorf("Expected performer %d function %s %q, got %s %q", i, want.code, want.display, coding.Code, coding.Display)
+		}
+	}
+
+	if len(practitioners) != 3 {
+		t.Errorf("Expected 3 practitioners, got %d", len(practitioners))
+	}
+}
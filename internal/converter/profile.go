@@ -0,0 +1,236 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mourice12/hl7-to-fhir/internal/profile"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ErrUnknownProfile is returned when ConvertOptions.Profile names a profile that is not loaded
+var ErrUnknownProfile = errors.New("unknown profile")
+
+var (
+	identifierType      = reflect.TypeOf(fhir.Identifier{})
+	codeableConceptType = reflect.TypeOf(fhir.CodeableConcept{})
+
+	//dateTimes and instants built from HL7 times that carry no offset
+	localDateTime = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}$`)
+)
+
+// selectProfile returns the profile named by opts.Profile, else the one selected by MSH-3/MSH-4
+func selectProfile(msg *hl7.Message, opts ConvertOptions) (*profile.Profile, error) {
+	if opts.Profiles == nil {
+		if opts.Profile != "" {
+			return nil, fmt.Errorf("%w %q", ErrUnknownProfile, opts.Profile)
+		}
+		return nil, nil
+	}
+
+	if opts.Profile != "" {
+		selected := opts.Profiles.Named(opts.Profile)
+		if selected == nil {
+			return nil, fmt.Errorf("%w %q", ErrUnknownProfile, opts.Profile)
+		}
+		return selected, nil
+	}
+
+	msh := msg.GetSegment("MSH")
+	if msh == nil {
+		return nil, nil
+	}
+	return opts.Profiles.Select(msh.GetField(3).GetCompontent(1), msh.GetField(4).GetCompontent(1)), nil
+}
+
+// withProfile returns the options with the profile's overrides applied
+func withProfile(opts ConvertOptions, p *profile.Profile) (ConvertOptions, error) {
+	if p == nil || p.IDStrategy == "" {
+		return opts, nil
+	}
+
+	strategy, err := IDStrategyByName(p.IDStrategy)
+	if err != nil {
+		return opts, fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	opts.IDs = strategy
+	return opts, nil
+}
+
+// applyProfile drops the resource types the profile disables and applies its identifier and code systems
+func applyProfile(bundle *fhir.Bundle, msg *hl7.Message, p *profile.Profile) {
+	if p == nil {
+		return
+	}
+
+	//Resource types the profile does not keep, and references to them
+	dropped := map[string]bool{}
+	entries := bundle.Entry[:0]
+	for _, entry := range bundle.Entry {
+		if resourceType := entry.Resource.GetResourceType(); !p.KeepsResource(resourceType) {
+			dropped[resourceType+"/"+entry.Resource.GetID()] = true
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	bundle.Entry = entries
+
+	rawSystems := codingSystemNames(msg)
+	for _, entry := range bundle.Entry {
+		if len(dropped) > 0 {
+			fhir.RewriteReferences(entry.Resource, func(reference string) string {
+				if dropped[reference] {
+					return ""
+				}
+				return reference
+			})
+		}
+
+		walkValues(reflect.ValueOf(entry.Resource), func(value reflect.Value) {
+			switch value.Type() {
+			case identifierType:
+				applyIdentifierSystem(value.Addr().Interface().(*fhir.Identifier), p)
+			case codeableConceptType:
+				applyCodeSystems(value.Addr().Interface().(*fhir.CodeableConcept), p, rawSystems)
+			}
+		})
+	}
+}
+
+// applyIdentifierSystem replaces the urn:oid system built from an assigning authority with the profile's system,
+// and gives identifiers that only name their assigner, such as an EI namespace, the system configured for it
+func applyIdentifierSystem(identifier *fhir.Identifier, p *profile.Profile) {
+	authority := strings.TrimPrefix(identifier.System, "urn:oid:")
+	if identifier.System == "" && identifier.Assigner != nil {
+		authority = identifier.Assigner.Display
+	} else if authority == identifier.System {
+		return
+	}
+	system, ok := p.IdentifierSystems[authority]
+	if !ok {
+		return
+	}
+
+	identifier.System = system.System
+	if system.Type != "" {
+		identifier.Type = &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{
+				System: "http://terminology.hl7.org/CodeSystem/v2-0203",
+				Code:   system.Type,
+			}},
+		}
+	}
+}
+
+// applyCodeSystems gives local codings their profile system and adds the standard codings they map to
+func applyCodeSystems(concept *fhir.CodeableConcept, p *profile.Profile, rawSystems map[string]string) {
+	for i := range concept.Coding {
+		coding := &concept.Coding[i]
+		if coding.System == "" && coding.Code != "" {
+			coding.System = p.CodeSystems[rawSystems[coding.Code+"^"+coding.Display]]
+		}
+	}
+
+	for _, coding := range concept.Coding {
+		mapped, ok := p.CodeMap[coding.System+"|"+coding.Code]
+		if !ok || hasCoding(concept, mapped.System, mapped.Code) {
+			continue
+		}
+		concept.Coding = append(concept.Coding, fhir.Coding{
+			System:  mapped.System,
+			Code:    mapped.Code,
+			Display: mapped.Display,
+		})
+	}
+}
+
+// hasCoding reports whether a concept already has a coding
+func hasCoding(concept *fhir.CodeableConcept, system, code string) bool {
+	for _, coding := range concept.Coding {
+		if coding.System == system && coding.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// codingSystemNames indexes the coding system name of every CE/CWE triple in the message by code^text,
+// since codings from systems without a standard URI are built with no system
+func codingSystemNames(msg *hl7.Message) map[string]string {
+	names := map[string]string{}
+	for _, segment := range msg.Segments {
+		for _, field := range segment.Fields {
+			for _, rep := range field.Repetitions {
+				//CWE-1..3 identifier and CWE-4..6 alternate identifier
+				for _, first := range []int{1, 4} {
+					code := getComponentValue(rep, first)
+					system := getComponentValue(rep, first+2)
+					if code != "" && system != "" {
+						names[code+"^"+getComponentValue(rep, first+1)] = system
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// applyTimezone reads the bundle's offset-less times in the profile's timezone
+func applyTimezone(bundle *fhir.Bundle, p *profile.Profile) {
+	if p == nil || p.Timezone == "" {
+		return
+	}
+	location := p.Location()
+
+	convert := func(value string) string {
+		if !localDateTime.MatchString(value) {
+			return value
+		}
+		t, err := time.ParseInLocation("2006-01-02T15:04:05", value, location)
+		if err != nil {
+			return value
+		}
+		return t.Format("2006-01-02T15:04:05-07:00")
+	}
+
+	bundle.Timestamp = convert(bundle.Timestamp)
+	for _, entry := range bundle.Entry {
+		walkValues(reflect.ValueOf(entry.Resource), func(value reflect.Value) {
+			if value.Kind() == reflect.String {
+				value.SetString(convert(value.String()))
+			}
+		})
+	}
+}
+
+// walkValues visits every settable struct and string reachable from value, parents before children
+func walkValues(value reflect.Value, visit func(reflect.Value)) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			walkValues(value.Elem(), visit)
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			walkValues(value.Index(i), visit)
+		}
+	case reflect.String:
+		if value.CanSet() {
+			visit(value)
+		}
+	case reflect.Struct:
+		if value.CanAddr() {
+			visit(value)
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				walkValues(value.Field(i), visit)
+			}
+		}
+	}
+}
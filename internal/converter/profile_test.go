@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/internal/profile"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToBundleWithProfile(t *testing.T) {
+	raw := "MSH|^~\\&|LAB|EAST|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^HOSP||Doe^John||19800115|M\r" +
+		"OBR|1|ORD123|LAB987|GLU^Glucose^99LAB|||20231115100000\r" +
+		"OBX|1|NM|GLU^Glucose^99LAB||95|mg/dL|70-100||||F|||20231115100000"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	east := &profile.Profile{
+		Name:    "east",
+		Senders: []profile.Sender{{Application: "LAB", Facility: "EAST"}},
+		IdentifierSystems: map[string]profile.IdentifierSystem{
+			"HOSP": {System: "http://east.example.org/mrn", Type: "MR"},
+		},
+		CodeSystems: map[string]string{"99LAB": "http://east.example.org/lab"},
+		CodeMap: map[string]profile.Coding{
+			"http://east.example.org/lab|GLU": {System: "http://loinc.org", Code: "2345-7"},
+		},
+		Timezone:  "America/Chicago",
+		Resources: []string{"Patient", "Observation", "DiagnosticReport"},
+	}
+	dir := t.TempDir()
+	writeProfile(t, dir, east)
+	profiles, err := profile.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir() returned error: %v", err)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.Profiles = profiles
+	bundle, err := ConvertToBundleWithOptions(msg, opts)
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithOptions() returned error: %v", err)
+	}
+
+	var patient *fhir.Patient
+	var obs *fhir.Oberservation
+	for _, entry := range bundle.Entry {
+		switch resource := entry.Resource.(type) {
+		case *fhir.Patient:
+			patient = resource
+		case *fhir.Oberservation:
+			obs = resource
+		case *fhir.ServiceRequest, *fhir.Practitioner:
+			t.Errorf("Expected the profile to drop %T", resource)
+		}
+	}
+
+	if patient == nil || patient.Identifier[0].System != "http://east.example.org/mrn" {
+		t.Fatalf("Expected the MRN system from the profile, got %+v", patient)
+	}
+	if obs == nil || len(obs.Code.Coding) != 2 {
+		t.Fatalf("Expected local and LOINC codings, got %+v", obs)
+	}
+	if obs.Code.Coding[0].System != "http://east.example.org/lab" || obs.Code.Coding[1].Code != "2345-7" {
+		t.Errorf("Unexpected codings %+v", obs.Code.Coding)
+	}
+	if obs.EffectiveDateTime != "2023-11-15T10:00:00-06:00" {
+		t.Errorf("Expected effective time in America/Chicago, got %s", obs.EffectiveDateTime)
+	}
+	for _, entry := range bundle.Entry {
+		if report, ok := entry.Resource.(*fhir.DiagnosticReport); ok && report.Issued != "2023-11-15T10:00:00-06:00" {
+			t.Errorf("Expected issued in America/Chicago, got %s", report.Issued)
+		}
+	}
+	if len(bundle.UnresolvedReferences()) != 0 {
+		t.Errorf("Unresolved references after dropping resources: %v", bundle.UnresolvedReferences())
+	}
+
+	opts.Profile = "west"
+	if _, err := ConvertToBundleWithOptions(msg, opts); err == nil {
+		t.Errorf("Expected an error for an unknown profile")
+	}
+}
+
+// writeProfile saves a profile as a JSON file in dir
+func writeProfile(t *testing.T, dir string, p *profile.Profile) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, p.Name+".json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,202 @@
+package converter
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// er7ContentType is the MIME type of an HL7 v2 message in pipe-and-hat encoding
+const er7ContentType = "x-application/hl7-v2+er7"
+
+// ConvertToProvenance records that the target resources were derived from the HL7 message
+func ConvertToProvenance(cc *ConversionContext, targets []fhir.Reference) (*fhir.Provenance, error) {
+	msg := cc.Message
+	msh := msg.GetSegment("MSH")
+	if msh == nil {
+		return nil, nil
+	}
+
+	controlID := msh.GetField(10).GetCompontent(1)
+	provenance := &fhir.Provenance{
+		ResourceType: "Provenance",
+		ID:           "provenance-" + controlID,
+		Target:       targets,
+		Recorded:     provenanceRecorded(cc, msh),
+		//MSH-7 Date/Time of Message
+		OccurredDateTime: fhir.FormatDateTime(msh.GetField(7).GetCompontent(1)),
+	}
+
+	//MSH-9.2 Trigger Event
+	_, trigger := messageType(msg)
+	if trigger != "" {
+		provenance.Activity = &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{
+				System:  "http://terminology.hl7.org/CodeSystem/v2-0003",
+				Code:    trigger,
+				Display: eventDisplay(trigger),
+			}},
+		}
+	}
+
+	//MSH-3 Sending Application on behalf of MSH-4 Sending Facility
+	agent := fhir.ProvenanceAgent{
+		Type: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{
+				System:  "http://terminology.hl7.org/CodeSystem/provenance-participant-type",
+				Code:    "author",
+				Display: "Author",
+			}},
+		},
+		Who: hdReference(msh.GetField(3)),
+	}
+	if facility := msh.GetField(4).GetCompontent(1); facility != "" {
+		agent.OnBehalfOf = hdReference(msh.GetField(4))
+	}
+	provenance.Agent = []fhir.ProvenanceAgent{agent}
+
+	//MSH-10 Message Control ID identifies the source message
+	provenance.Entity = []fhir.ProvenanceEntity{{
+		Role: "source",
+		What: &fhir.Reference{
+			Identifier: &fhir.Identifier{
+				System: applicationEndpoint(msh.GetField(3), msh.GetField(4)),
+				Value:  controlID,
+			},
+			Display: msh.GetField(9).GetCompontent(1) + "^" + trigger + " message " + controlID,
+		},
+	}}
+
+	return provenance, nil
+}
+
+// provenanceRecorded takes the recorded time from the message, so converting it again gives the same Provenance:
+// EVN-2 Recorded Date/Time, then MSH-7 Date/Time of Message. The current time is only used when both are missing.
+func provenanceRecorded(cc *ConversionContext, msh *hl7.Segment) string {
+	msg := cc.Message
+	if evn := msg.GetSegment("EVN"); evn != nil {
+		if recorded := fhir.FormatInstant(evn.GetField(2).GetCompontent(1)); recorded != "" {
+			return recorded
+		}
+	}
+	if recorded := fhir.FormatInstant(msh.GetField(7).GetCompontent(1)); recorded != "" {
+		return recorded
+	}
+	if date := msh.GetField(7).GetCompontent(1); len(date) == 8 {
+		//A date-only MSH-7 is recorded at the start of the day
+		return fhir.FormatInstant(date + "0000")
+	}
+
+	cc.ReportSegment(msh, 7, SeverityWarning, IssueRequired, "MSH-7 has no message date/time; Provenance.recorded is the conversion time")
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// ConvertToSourceBinary stores the original ER7 message as a Binary
+func ConvertToSourceBinary(cc *ConversionContext) (*fhir.Binary, error) {
+	msg := cc.Message
+	if msg.Raw == "" {
+		return nil, nil
+	}
+
+	controlID := messageControlID(msg)
+
+	return &fhir.Binary{
+		ResourceType: "Binary",
+		ID:           "binary-message-" + controlID,
+		ContentType:  er7ContentType,
+		Data:         base64.StdEncoding.EncodeToString([]byte(msg.Raw)),
+	}, nil
+}
+
+// addProvenance appends a Provenance targeting every resource in the bundle, and the source Binary when requested
+func addProvenance(cc *ConversionContext) error {
+	bundle, msg, opts := cc.Bundle, cc.Message, cc.Options
+	var targets []fhir.Reference
+	for _, entry := range bundle.Entry {
+		if entry.Resource == nil || entry.Resource.GetResourceType() == "MessageHeader" {
+			continue
+		}
+		//A patched resource is known by its conditional URL, the entry only holds the patch
+		if entry.Request != nil && entry.Request.Method == "PATCH" {
+			targets = append(targets, fhir.Reference{Reference: entry.Request.URL})
+			continue
+		}
+		targets = append(targets, fhir.Reference{Reference: entry.FullURL})
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	provenance, err := ConvertToProvenance(cc, targets)
+	if err != nil || provenance == nil {
+		return err
+	}
+
+	var source *fhir.Binary
+	if opts.IncludeSourceMessage {
+		source, err = ConvertToSourceBinary(cc)
+		if err != nil {
+			return err
+		}
+	}
+
+	if source != nil {
+		source.ID = generatedID(msg, opts, "Binary", source.ID)
+		fullURL := "urn:uuid:" + fhir.UUIDv5(fhir.NamespaceURL, messageSender(msg)+"|Binary/"+source.ID)
+		provenance.Entity[0].What.Reference = fullURL
+		bundle.Entry = append(bundle.Entry, generatedEntry(fullURL, "Binary", source.ID, source, opts))
+	}
+
+	provenance.ID = generatedID(msg, opts, "Provenance", provenance.ID)
+	fullURL := "urn:uuid:" + fhir.UUIDv5(fhir.NamespaceURL, messageSender(msg)+"|Provenance/"+provenance.ID)
+	bundle.Entry = append(bundle.Entry, generatedEntry(fullURL, "Provenance", provenance.ID, provenance, opts))
+
+	return nil
+}
+
+// generatedID runs an id for a resource added after the main conversion through the ID strategy
+func generatedID(msg *hl7.Message, opts ConvertOptions, resourceType, sourceID string) string {
+	if opts.IDs == nil {
+		return sourceID
+	}
+
+	controlID := messageControlID(msg)
+	return opts.IDs.ResourceID(resourceType, IDKey{
+		Sender:        messageSender(msg),
+		ControlID:     controlID,
+		SourceID:      sourceID,
+		MessageScoped: true,
+	})
+}
+
+// generatedEntry builds the bundle entry for a resource added after the main conversion
+func generatedEntry(fullURL, resourceType, id string, resource fhir.Resource, opts ConvertOptions) fhir.BundleEntry {
+	entry := fhir.BundleEntry{FullURL: fullURL, Resource: resource}
+	if opts.BundleType != BundleMessage {
+		entry.Request = buildEntryRequest(opts.requestMode(resourceType), resourceType, id, nil)
+	}
+	return entry
+}
+
+// hdReference references an application or facility by its HD value
+func hdReference(field *hl7.Field) *fhir.Reference {
+	reference := &fhir.Reference{
+		Display: field.GetCompontent(1),
+		Identifier: &fhir.Identifier{
+			Value: field.GetCompontent(1),
+		},
+	}
+
+	//HD-2 Universal ID and HD-3 Universal ID Type
+	if universalID := field.GetCompontent(2); universalID != "" {
+		reference.Identifier.Value = universalID
+		if field.GetCompontent(3) == "ISO" {
+			reference.Identifier.System = "urn:ietf:rfc:3986"
+			reference.Identifier.Value = "urn:oid:" + universalID
+		}
+	}
+
+	return reference
+}
@@ -0,0 +1,63 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToBundleProvenance(t *testing.T) {
+	raw := "MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-100||||F"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.IncludeSourceMessage = true
+	bundle, err := ConvertToBundleWithOptions(msg, opts)
+	if err != nil {
+		t.Fatalf("ConvertToBundleWithOptions() returned error: %v", err)
+	}
+
+	var provenance *fhir.Provenance
+	var source *fhir.BundleEntry
+	for i, entry := range bundle.Entry {
+		switch resource := entry.Resource.(type) {
+		case *fhir.Provenance:
+			provenance = resource
+		case *fhir.Binary:
+			if resource.ContentType == er7ContentType {
+				source = &bundle.Entry[i]
+			}
+		}
+	}
+
+	if provenance == nil {
+		t.Fatalf("Expected a Provenance entry")
+	}
+	if len(provenance.Target) != len(bundle.Entry)-2 {
+		t.Errorf("Expected Provenance to target %d resources, got %d", len(bundle.Entry)-2, len(provenance.Target))
+	}
+	if provenance.Agent[0].Who.Display != "LAB" || provenance.Agent[0].OnBehalfOf.Display != "FAC1" {
+		t.Errorf("Expected agent LAB on behalf of FAC1, got %+v", provenance.Agent[0])
+	}
+
+	//MSH-7, so converting the message again gives the same Provenance
+	if provenance.Recorded != "2023-11-15T12:00:00" {
+		t.Errorf("Expected recorded to be MSH-7, got %s", provenance.Recorded)
+	}
+
+	what := provenance.Entity[0].What
+	if what.Identifier == nil || what.Identifier.Value != "MSG001" {
+		t.Errorf("Expected entity identifier MSG001, got %+v", what.Identifier)
+	}
+	if source == nil || what.Reference != source.FullURL {
+		t.Errorf("Expected entity to reference the source Binary")
+	}
+}
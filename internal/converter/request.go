@@ -0,0 +1,173 @@
+package converter
+
+import (
+	"net/url"
+
+	"github.com/mourice12/hl7-to-fhir/internal/mapping"
+	"github.com/mourice12/hl7-to-fhir/internal/profile"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// RequestMode selects the transaction request used to write a resource
+type RequestMode string
+
+const (
+	// RequestUpdate writes the resource with PUT Type/id
+	RequestUpdate RequestMode = "update"
+	// RequestConditionalUpdate upserts with PUT Type?identifier=system|value
+	RequestConditionalUpdate RequestMode = "conditional-update"
+	// RequestCreate writes the resource with POST Type
+	RequestCreate RequestMode = "create"
+	// RequestConditionalCreate writes the resource with POST Type and ifNoneExist identifier=system|value
+	RequestConditionalCreate RequestMode = "conditional-create"
+	// RequestDelete removes the resource with DELETE Type/id
+	RequestDelete RequestMode = "delete"
+)
+
+// ConvertOptions configures how a message is turned into a bundle
+type ConvertOptions struct {
+	// Requests overrides the request mode per resource type; unlisted types use RequestUpdate
+	Requests map[string]RequestMode
+	// DeleteOnCancel turns the resources named by a cancellation event into DELETE entries
+	DeleteOnCancel bool
+	// IDs assigns resource ids; nil keeps the ids built from the message
+	IDs IDStrategy
+	// BundleType is BundleTransaction (the default) or BundleMessage
+	BundleType string
+	// IncludeSourceMessage stores the raw ER7 message as a Binary referenced by the Provenance
+	IncludeSourceMessage bool
+	// Mappings, when set, build their resource types from declarative rules instead of the built-in conversion
+	Mappings *mapping.Set
+	// Registry routes messages to handlers by MSH-9; nil uses DefaultRegistry
+	Registry *Registry
+	// Profiles are per-sender overrides selected by MSH-3/MSH-4
+	Profiles *profile.Set
+	// Profile forces the named profile instead of selecting one by sender
+	Profile string
+	// Mode is ModeLenient (the default) or ModeStrict, which fails the conversion on any issue
+	Mode string
+}
+
+// DefaultConvertOptions upserts Patients by identifier, creates Observations once, deletes cancelled resources
+// and hashes resource ids
+func DefaultConvertOptions() ConvertOptions {
+	return ConvertOptions{
+		Requests: map[string]RequestMode{
+			"Patient":     RequestConditionalUpdate,
+			"Observation": RequestConditionalCreate,
+		},
+		DeleteOnCancel: true,
+		IDs:            HashIDStrategy{},
+		BundleType:     BundleTransaction,
+	}
+}
+
+// requestMode returns the configured mode for a resource type
+func (o ConvertOptions) requestMode(resourceType string) RequestMode {
+	if mode, ok := o.Requests[resourceType]; ok {
+		return mode
+	}
+	return RequestUpdate
+}
+
+// cancelledResourceTypes returns the resource types a cancellation trigger event withdraws
+func cancelledResourceTypes(msg *hl7.Message) []string {
+	messageCode, trigger := messageType(msg)
+
+	switch messageCode + "^" + trigger {
+	case "ADT^A11", "ADT^A27", "ADT^A38": // Cancel admit, cancel pending admit, cancel pre-admit
+		return []string{"Encounter"}
+	case "SIU^S17": // Delete appointment
+		return []string{"Appointment", "Slot"}
+	case "MDM^T11": // Document cancel
+		return []string{"DocumentReference", "Binary"}
+	default:
+		return nil
+	}
+}
+
+// applyEntryRequests fills request on every bundle entry
+func applyEntryRequests(bundle *fhir.Bundle, msg *hl7.Message, opts ConvertOptions) {
+	cancelled := map[string]bool{}
+	if opts.DeleteOnCancel {
+		for _, resourceType := range cancelledResourceTypes(msg) {
+			cancelled[resourceType] = true
+		}
+	}
+
+	for i := range bundle.Entry {
+		entry := &bundle.Entry[i]
+		if entry.Resource == nil {
+			continue
+		}
+		resourceType, id, identifiers := entry.Resource.GetResourceType(), entry.Resource.GetID(), entry.Resource.GetIdentifiers()
+
+		mode := opts.requestMode(resourceType)
+		if cancelled[resourceType] {
+			mode = RequestDelete
+		}
+
+		//A Patient merged away is patched by identifier, so the demographics the server holds are kept
+		if patch := priorPatientPatch(entry.Resource); patch != nil && mode != RequestDelete {
+			if query := identifierQuery(identifiers); query != "" {
+				entry.Request = &fhir.BundleEntryRequest{Method: "PATCH", URL: resourceType + "?" + query}
+				entry.Resource = patch
+				continue
+			}
+		}
+
+		entry.Request = buildEntryRequest(mode, resourceType, id, identifiers)
+		if entry.Request.Method == "DELETE" {
+			entry.Resource = nil
+		}
+	}
+}
+
+// buildEntryRequest builds the request for one entry, falling back to PUT/POST when there is no identifier
+func buildEntryRequest(mode RequestMode, resourceType, id string, identifiers []fhir.Identifier) *fhir.BundleEntryRequest {
+	query := identifierQuery(identifiers)
+
+	switch mode {
+	case RequestConditionalUpdate:
+		if query != "" {
+			return &fhir.BundleEntryRequest{Method: "PUT", URL: resourceType + "?" + query}
+		}
+	case RequestCreate:
+		return &fhir.BundleEntryRequest{Method: "POST", URL: resourceType}
+	case RequestConditionalCreate:
+		return &fhir.BundleEntryRequest{Method: "POST", URL: resourceType, IfNoneExist: query}
+	case RequestDelete:
+		return &fhir.BundleEntryRequest{Method: "DELETE", URL: resourceType + "/" + id}
+	}
+
+	if id == "" {
+		return &fhir.BundleEntryRequest{Method: "POST", URL: resourceType}
+	}
+	return &fhir.BundleEntryRequest{Method: "PUT", URL: resourceType + "/" + id}
+}
+
+// identifierQuery builds an escaped identifier=system|value search, preferring identifiers that have a system
+func identifierQuery(identifiers []fhir.Identifier) string {
+	var chosen *fhir.Identifier
+	for i := range identifiers {
+		if identifiers[i].Value == "" {
+			continue
+		}
+		if identifiers[i].System != "" {
+			chosen = &identifiers[i]
+			break
+		}
+		if chosen == nil {
+			chosen = &identifiers[i]
+		}
+	}
+	if chosen == nil {
+		return ""
+	}
+
+	if chosen.System == "" {
+		return "identifier=" + url.QueryEscape(chosen.Value)
+	}
+	return "identifier=" + url.QueryEscape(chosen.System+"|"+chosen.Value)
+}
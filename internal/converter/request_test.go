@@ -0,0 +1,56 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToBundleEntryRequests(t *testing.T) {
+	raw := "MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231116120000||ADT^A11|MSG001|P|2.5\r" +
+		"EVN|A11|20231116120000\r" +
+		"PID|1||12345^^^MRN&2.16.840.1.113883.19.5&ISO||Doe^John||19800115|M\r" +
+		"PV1|1|I|ICU^0101^01||||||||||||||||V100"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	bundle, err := ConvertToBundle(msg)
+	if err != nil {
+		t.Fatalf("ConvertToBundle() returned error: %v", err)
+	}
+
+	requests := map[string]*fhir.BundleEntryRequest{}
+	var encounterRequest *fhir.BundleEntryRequest
+	for _, entry := range bundle.Entry {
+		if entry.Request == nil {
+			t.Fatalf("Expected request on entry %s", entry.FullURL)
+		}
+		if strings.HasPrefix(entry.Request.URL, "Encounter/") {
+			encounterRequest = entry.Request
+		}
+		requests[entry.Request.URL] = entry.Request
+	}
+
+	if request := requests["Patient?identifier=urn%3Aoid%3A2.16.840.1.113883.19.5%7C12345"]; request == nil || request.Method != "PUT" {
+		t.Errorf("Expected conditional PUT for Patient, got %+v", requests)
+	}
+
+	if encounterRequest == nil || encounterRequest.Method != "DELETE" {
+		t.Errorf("Expected DELETE for cancelled Encounter, got %+v", encounterRequest)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.DeleteOnCancel = false
+	opts.Requests["Patient"] = RequestUpdate
+	bundle, _ = ConvertToBundleWithOptions(msg, opts)
+	for _, entry := range bundle.Entry {
+		if entry.Request.Method != "PUT" {
+			t.Errorf("Expected PUT for %s, got %s", entry.Request.URL, entry.Request.Method)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ErrUnsupportedMessageType is returned when no handler is registered for a message's MSH-9
+var ErrUnsupportedMessageType = errors.New("unsupported message type")
+
+// Handler converts the context's message, adding its resources to the context
+type Handler func(cc *ConversionContext) error
+
+// Registry routes messages to handlers by MSH-9 message code, trigger event and message structure
+type Registry struct {
+	mu           sync.RWMutex
+	handlers     map[string]Handler
+	segmentHooks map[string][]SegmentHook
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[string]Handler{}, segmentHooks: map[string][]SegmentHook{}}
+}
+
+// DefaultRegistry holds the built-in handlers and is used when ConvertOptions.Registry is nil
+var DefaultRegistry = newDefaultRegistry()
+
+// Register adds a handler to DefaultRegistry
+func Register(messageCode, trigger, structure string, handler Handler) {
+	DefaultRegistry.Register(messageCode, trigger, structure, handler)
+}
+
+// Register adds a handler for MSH-9.1/9.2/9.3; an empty trigger or structure matches any value,
+// and registering the same key again replaces the handler
+func (r *Registry) Register(messageCode, trigger, structure string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[routeKey(messageCode, trigger, structure)] = handler
+}
+
+// Lookup returns the most specific handler for the message: code^trigger^structure, code^trigger,
+// code with structure, then code alone
+func (r *Registry) Lookup(msg *hl7.Message) (Handler, error) {
+	messageCode, trigger, structure := messageStructure(msg)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	candidates := []string{
+		routeKey(messageCode, trigger, structure),
+		routeKey(messageCode, trigger, ""),
+		routeKey(messageCode, "", structure),
+		routeKey(messageCode, "", ""),
+	}
+	for _, key := range candidates {
+		if handler, ok := r.handlers[key]; ok {
+			return handler, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %s^%s^%s", ErrUnsupportedMessageType, messageCode, trigger, structure)
+}
+
+// routeKey builds the registry key for a route
+func routeKey(messageCode, trigger, structure string) string {
+	return messageCode + "^" + trigger + "^" + structure
+}
+
+// messageStructure returns MSH-9.1 Message Code, MSH-9.2 Trigger Event and MSH-9.3 Message Structure
+func messageStructure(msg *hl7.Message) (string, string, string) {
+	msh := msg.GetSegment("MSH")
+	if msh == nil {
+		return "", "", ""
+	}
+
+	field := msh.GetField(9)
+	return field.GetCompontent(1), field.GetCompontent(2), field.GetCompontent(3)
+}
+
+// newDefaultRegistry registers the built-in handlers by message code
+func newDefaultRegistry() *Registry {
+	registry := NewRegistry()
+
+	registry.Register("ADT", "", "", pipeline(convertPatientStep, convertEncounterStep, convertProceduresStep,
+		convertConditionsStep, convertAllergiesStep, convertObservationsStep))
+	registry.Register("DFT", "", "", pipeline(convertPatientStep, convertEncounterStep, convertProceduresStep,
+		convertConditionsStep, convertChargesStep))
+	registry.Register("ORU", "", "", pipeline(convertPatientStep, convertDocumentsStep, convertObservationsStep,
+		convertServiceRequestsStep, convertSpecimensStep, convertDiagnosticReportStep))
+	registry.Register("VXU", "", "", pipeline(convertPatientStep, convertImmunizationsStep))
+	registry.Register("SIU", "", "", pipeline(convertPatientStep, convertAppointmentStep))
+	registry.Register("MDM", "", "", pipeline(convertPatientStep, convertEncounterStep, convertDocumentsStep))
+
+	orders := pipeline(convertPatientStep, convertObservationsStep, convertServiceRequestsStep, convertSpecimensStep)
+	for _, messageCode := range []string{"ORM", "OML", "OMG"} {
+		registry.Register(messageCode, "", "", orders)
+	}
+
+	pharmacy := pipeline(convertPatientStep, convertAllergiesStep, convertPharmacyStep, convertObservationsStep)
+	for _, messageCode := range []string{"RDE", "RDS", "RAS", "OMP"} {
+		registry.Register(messageCode, "", "", pharmacy)
+	}
+
+	return registry
+}
@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToBundleRoutesByMessageType(t *testing.T) {
+	raw := "MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"PV1|1|O|CLINIC\r" +
+		"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|70-100||||F"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	bundle, err := ConvertToBundle(msg)
+	if err != nil {
+		t.Fatalf("ConvertToBundle() returned error: %v", err)
+	}
+
+	for _, entry := range bundle.Entry {
+		if _, ok := entry.Resource.(*fhir.Encounter); ok {
+			t.Errorf("Expected no Encounter from an ORU message")
+		}
+	}
+}
+
+func TestConvertToBundleUnsupportedMessageType(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ACK^A01|MSG001|P|2.5\rMSA|AA|MSG000")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	_, err = ConvertToBundle(msg)
+	if !errors.Is(err, ErrUnsupportedMessageType) {
+		t.Errorf("Expected ErrUnsupportedMessageType, got %v", err)
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	registry := NewRegistry()
+	var called string
+	handler := func(name string) Handler {
+		return func(cc *ConversionContext) error {
+			called = name
+			return nil
+		}
+	}
+	registry.Register("ZPM", "", "", handler("code"))
+	registry.Register("ZPM", "Z01", "", handler("trigger"))
+	registry.Register("ZPM", "Z01", "ZPM_Z01", handler("structure"))
+
+	tests := []struct {
+		msh  string
+		want string
+	}{
+		{"ZPM^Z01^ZPM_Z01", "structure"},
+		{"ZPM^Z01", "trigger"},
+		{"ZPM^Z02", "code"},
+	}
+
+	for _, tt := range tests {
+		msg, err := hl7.Parse("MSH|^~\\&|APP|FAC|EHR|FAC2|20231115120000||" + tt.msh + "|MSG001|P|2.5")
+		if err != nil {
+			t.Fatalf("Parse() returned error: %v", err)
+		}
+
+		opts := DefaultConvertOptions()
+		opts.Registry = registry
+		if _, err := ConvertToBundleWithOptions(msg, opts); err != nil {
+			t.Fatalf("ConvertToBundleWithOptions(%s) returned error: %v", tt.msh, err)
+		}
+		if called != tt.want {
+			t.Errorf("%s: expected %s handler, got %s", tt.msh, tt.want, called)
+		}
+	}
+}
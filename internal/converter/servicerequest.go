@@ -0,0 +1,208 @@
+package converter
+
+import (
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConvertToServiceRequests converts ORC/OBR order groups to FHIR ServiceRequests
+func ConvertToServiceRequests(cc *ConversionContext) ([]*fhir.ServiceRequest, []*fhir.Practitioner, error) {
+	msg := cc.Message
+	var requests []*fhir.ServiceRequest
+	var practitioners []*fhir.Practitioner
+
+	orders := orderControls(msg, "OBR")
+	groups := msg.GetGroups("OBR", "TQ1")
+
+	for _, group := range groups {
+		obr := group.Head
+		orc := orders[obr]
+
+		request := &fhir.ServiceRequest{
+			ResourceType: "ServiceRequest",
+			ID:           serviceRequestID(obr, orc),
+			Identifier:   buildServiceRequestIdentifiers(obr, orc),
+			Status:       mapServiceRequestStatus(obr, orc),
+			Intent:       mapServiceRequestIntent(orc),
+			Priority:     mapServiceRequestPriority(obr, group.GetSegment("TQ1")),
+			Code:         getOBRCode(obr),
+			Subject:      cc.PatientReference(),
+		}
+
+		//OBR-6 Requested DateTime, overridden by TQ1-7 Start DateTime
+		requested := obr.GetField(6).GetCompontent(1)
+		requestedSegment, requestedField := obr, 6
+		if tq1 := group.GetSegment("TQ1"); tq1 != nil && tq1.GetField(7).GetCompontent(1) != "" {
+			requested = tq1.GetField(7).GetCompontent(1)
+			requestedSegment, requestedField = tq1, 7
+		}
+		if requested != "" {
+			request.OccurrenceDateTime = checkedDateTime(cc, requestedSegment, requestedField, requested)
+		}
+
+		//ORC-9 Transaction DateTime
+		if orc != nil {
+			authored := orc.GetField(9).GetCompontent(1)
+			if authored != "" {
+				request.AuthoredOn = checkedDateTime(cc, orc, 9, authored)
+			}
+		}
+
+		//ORC-12 Ordering Provider, falling back to OBR-16
+		var requester *fhir.Practitioner
+		requesterSegment := orc
+		if orc != nil {
+			requester = buildOrderingProvider(orc)
+		}
+		if requester == nil {
+			requesterSegment = obr
+			if field := obr.GetField(16); field != nil && len(field.Repetitions) > 0 {
+				requester = buildPractitioner(field.Repetitions[0])
+			}
+		}
+		if requester != nil {
+			cc.RecordSegment(requester, requesterSegment)
+			practitioners = addPractitioner(practitioners, requester)
+			request.Requester = &fhir.Reference{
+				Reference: "Practitioner/" + requester.ID,
+				Display:   practitionerDisplay(requester),
+			}
+		}
+
+		//OBR-31 Reason for Study
+		if field := obr.GetField(31); field != nil {
+			for _, rep := range field.Repetitions {
+				if reason := buildCodeableConceptFromRep(rep); reason != nil {
+					request.ReasonCode = append(request.ReasonCode, *reason)
+				}
+			}
+		}
+
+		//DG1 diagnoses sent with the order
+		for _, dg1 := range msg.GetSegments("DG1") {
+			if reason := buildDiagnosisCode(dg1); reason != nil {
+				request.ReasonCode = append(request.ReasonCode, *reason)
+			}
+		}
+
+		cc.RecordSegment(request, obr)
+		requests = append(requests, request)
+	}
+
+	return requests, practitioners, nil
+}
+
+// serviceRequestID builds the ServiceRequest ID from the placer order number, falling back to the filler number
+func serviceRequestID(obr, orc *hl7.Segment) string {
+	for _, field := range orderNumberFields(obr, orc) {
+		value := field.GetCompontent(1)
+		if value != "" {
+			return "servicerequest-" + value
+		}
+	}
+	return "servicerequest-" + obr.GetField(1).GetCompontent(1)
+}
+
+// orderNumberFields returns the placer and filler order number fields, ORC before OBR
+func orderNumberFields(obr, orc *hl7.Segment) []*hl7.Field {
+	var fields []*hl7.Field
+	if orc != nil {
+		fields = append(fields, orc.GetField(2))
+	}
+	fields = append(fields, obr.GetField(2))
+	if orc != nil {
+		fields = append(fields, orc.GetField(3))
+	}
+	fields = append(fields, obr.GetField(3))
+	return fields
+}
+
+// buildServiceRequestIdentifiers extracts placer and filler order numbers from ORC-2/3 and OBR-2/3
+func buildServiceRequestIdentifiers(obr, orc *hl7.Segment) []fhir.Identifier {
+	if orc != nil {
+		if identifiers := buildOrderIdentifiers(orc); len(identifiers) > 0 {
+			return identifiers
+		}
+	}
+
+	var identifiers []fhir.Identifier
+	if placer := buildOrderIdentifier(obr.GetField(2), "PLAC"); placer != nil {
+		identifiers = append(identifiers, *placer)
+	}
+	if filler := buildOrderIdentifier(obr.GetField(3), "FILL"); filler != nil {
+		identifiers = append(identifiers, *filler)
+	}
+	return identifiers
+}
+
+// mapServiceRequestStatus converts ORC-1/ORC-5, or OBR-25 when there is no ORC
+func mapServiceRequestStatus(obr, orc *hl7.Segment) string {
+	if orc == nil {
+		switch obr.GetField(25).GetCompontent(1) {
+		case "F", "C":
+			return "completed"
+		case "X":
+			return "revoked"
+		default:
+			return "active"
+		}
+	}
+
+	switch orc.GetField(1).GetCompontent(1) {
+	case "CA", "CR", "OC", "DC", "DR", "OD": // Cancelled or discontinued
+		return "revoked"
+	case "HD", "HR", "OH": // On hold
+		return "on-hold"
+	}
+
+	switch orc.GetField(5).GetCompontent(1) {
+	case "CM":
+		return "completed"
+	case "CA", "DC":
+		return "revoked"
+	case "HD":
+		return "on-hold"
+	default:
+		return "active"
+	}
+}
+
+// mapServiceRequestIntent converts ORC-1 order control to a ServiceRequest intent
+func mapServiceRequestIntent(orc *hl7.Segment) string {
+	if orc == nil {
+		return "order"
+	}
+
+	switch orc.GetField(1).GetCompontent(1) {
+	case "NW":
+		return "original-order"
+	case "SN": // Send order number: the order originated at the filler
+		return "filler-order"
+	default:
+		return "order"
+	}
+}
+
+// mapServiceRequestPriority converts TQ1-9, or the priority component of OBR-27
+func mapServiceRequestPriority(obr, tq1 *hl7.Segment) string {
+	priority := ""
+	if tq1 != nil {
+		priority = tq1.GetField(9).GetCompontent(1)
+	}
+	if priority == "" {
+		priority = obr.GetField(27).GetCompontent(6)
+	}
+
+	switch priority {
+	case "S":
+		return "stat"
+	case "A":
+		return "asap"
+	case "P", "C", "T": // Preop, callback, timing critical
+		return "urgent"
+	case "R":
+		return "routine"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,116 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToServiceRequests(t *testing.T) {
+	cc := newSampleContext(t, "sample-elr.hl7")
+	requests, practitioners, err := ConvertToServiceRequests(cc)
+	if err != nil {
+		t.Fatalf("ConvertToServiceRequests() returned error: %v", err)
+	}
+	if len(requests) != 1 || len(practitioners) != 1 {
+		t.Fatalf("Expected 1 request and 1 practitioner, got %d and %d", len(requests), len(practitioners))
+	}
+
+	request := requests[0]
+	if len(request.Identifier) != 2 || request.Code == nil || request.Requester == nil {
+		t.Fatalf("Expected every sample field to be converted, got %+v", request)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id from ORC-2", request.ID, "servicerequest-ORD123456"},
+		{"status from ORC-1", request.Status, "active"},
+		{"intent from ORC-1", request.Intent, "order"},
+		{"no priority", request.Priority, ""},
+		{"subject", request.Subject.Reference, "Patient/583295"},
+		{"ORC-2 placer number", request.Identifier[0].Value, "ORD123456"},
+		{"ORC-2 assigner", request.Identifier[0].Assigner.Display, "EMR"},
+		{"ORC-3 filler type", request.Identifier[1].Type.Coding[0].Code, "FILL"},
+		{"OBR-4 code", request.Code.Coding[0].Code, "94500-6"},
+		{"OBR-4 LOINC system", request.Code.Coding[0].System, "http://loinc.org"},
+		{"OBR-6 has no occurrence", request.OccurrenceDateTime, ""},
+		{"OBR-16 requester", request.Requester.Reference, "Practitioner/practitioner-1234567"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, test.got)
+		}
+	}
+}
+
+func TestConvertToServiceRequestsOrderFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		segments   string
+		id         string
+		status     string
+		intent     string
+		priority   string
+		occurrence string
+	}{
+		{
+			name:       "new order with TQ1",
+			segments:   "ORC|NW|ORD1^EMR|||||||20231115080000\rOBR|1|ORD1^EMR||CBC^Complete blood count^L|||20231115090000\rTQ1|1||||||20231116070000||S",
+			id:         "servicerequest-ORD1",
+			status:     "active",
+			intent:     "original-order",
+			priority:   "stat",
+			occurrence: "2023-11-16T07:00:00",
+		},
+		{
+			name:       "filler order",
+			segments:   "ORC|SN||FIL1^LAB\rOBR|1||FIL1^LAB|CBC^Complete blood count^L|||||||||||||||||||||||^^^^^A",
+			id:         "servicerequest-FIL1",
+			status:     "active",
+			intent:     "filler-order",
+			priority:   "asap",
+			occurrence: "",
+		},
+		{
+			name:     "cancelled",
+			segments: "ORC|CA|ORD2^EMR\rOBR|1|ORD2^EMR||CBC^Complete blood count^L",
+			id:       "servicerequest-ORD2",
+			status:   "revoked",
+			intent:   "order",
+		},
+		{
+			name:     "completed without ORC",
+			segments: "OBR|1|ORD3^EMR||CBC^Complete blood count^L|||||||||||||||||||||F",
+			id:       "servicerequest-ORD3",
+			status:   "completed",
+			intent:   "order",
+		},
+	}
+
+	for _, test := range tests {
+		msg, err := hl7.Parse("MSH|^~\\&|EMR|HOSPITAL|LAB|HOSPITAL|20231115080000||ORM^O01|MSG001|P|2.5\r" +
+			"PID|1||583295^^^ADT1^MR||DOE^JOHN||19800115|M\r" + test.segments)
+		if err != nil {
+			t.Fatalf("Parse() returned error: %v", err)
+		}
+
+		requests, _, err := ConvertToServiceRequests(newTestContext(msg, &fhir.Patient{ResourceType: "Patient", ID: "583295"}))
+		if err != nil || len(requests) != 1 {
+			t.Fatalf("%s: expected one request, got %d (%v)", test.name, len(requests), err)
+		}
+
+		request := requests[0]
+		got := []string{request.ID, request.Status, request.Intent, request.Priority, request.OccurrenceDateTime}
+		want := []string{test.id, test.status, test.intent, test.priority, test.occurrence}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: expected %v, got %v", test.name, want, got)
+				break
+			}
+		}
+	}
+}
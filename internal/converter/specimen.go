@@ -0,0 +1,231 @@
+package converter
+
+import (
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// ConvertToSpecimens converts SPM segments, or OBR-15 when there is no SPM, to FHIR Specimens
+func ConvertToSpecimens(cc *ConversionContext) ([]*fhir.Specimen, error) {
+	msg := cc.Message
+	var specimens []*fhir.Specimen
+
+	orders := orderControls(msg, "OBR")
+	for _, group := range specimenGroups(msg) {
+		obr := group.Head
+		request := fhir.Reference{
+			Reference: "ServiceRequest/" + serviceRequestID(obr, orders[obr]),
+		}
+
+		spmSegments := group.GetSegments("SPM")
+		for _, spm := range spmSegments {
+			specimen := buildSpecimenFromSPM(cc, obr, spm)
+			specimen.Subject = cc.PatientReference()
+			specimen.Request = []fhir.Reference{request}
+			cc.RecordSegment(specimen, spm)
+			specimens = append(specimens, specimen)
+		}
+
+		if len(spmSegments) == 0 {
+			specimen := buildSpecimenFromOBR(cc, obr)
+			if specimen != nil {
+				specimen.Subject = cc.PatientReference()
+				specimen.Request = []fhir.Reference{request}
+				cc.RecordSegment(specimen, obr)
+				specimens = append(specimens, specimen)
+			}
+		}
+	}
+
+	return specimens, nil
+}
+
+// specimenGroups splits the message into OBR groups with their OBX and SPM segments
+func specimenGroups(msg *hl7.Message) []*hl7.SegmentGroup {
+	return msg.GetGroups("OBR", "OBX", "SPM")
+}
+
+// specimenReferences returns references to the specimens of an OBR group
+func specimenReferences(group *hl7.SegmentGroup) []fhir.Reference {
+	var references []fhir.Reference
+
+	spmSegments := group.GetSegments("SPM")
+	for _, spm := range spmSegments {
+		references = append(references, fhir.Reference{
+			Reference: "Specimen/" + specimenID(group.Head, spm),
+		})
+	}
+
+	if len(spmSegments) == 0 && group.Head.GetField(15).GetSubcomponent(1, 1) != "" {
+		references = append(references, fhir.Reference{
+			Reference: "Specimen/" + specimenID(group.Head, nil),
+		})
+	}
+
+	return references
+}
+
+// specimenReferencesByOBX maps each OBX to the first specimen of its OBR group
+func specimenReferencesByOBX(msg *hl7.Message) map[*hl7.Segment]*fhir.Reference {
+	references := map[*hl7.Segment]*fhir.Reference{}
+
+	for _, group := range specimenGroups(msg) {
+		groupReferences := specimenReferences(group)
+		if len(groupReferences) == 0 {
+			continue
+		}
+		for _, obx := range group.GetSegments("OBX") {
+			references[obx] = &groupReferences[0]
+		}
+	}
+
+	return references
+}
+
+// specimenID builds the Specimen ID from SPM-2, falling back to the OBR and SPM set IDs
+func specimenID(obr, spm *hl7.Segment) string {
+	if spm == nil {
+		return "specimen-" + obr.GetField(1).GetCompontent(1)
+	}
+
+	idField := spm.GetField(2)
+	for _, component := range []int{1, 2} {
+		id := idField.GetSubcomponent(component, 1)
+		if id != "" {
+			return "specimen-" + id
+		}
+	}
+
+	return "specimen-" + obr.GetField(1).GetCompontent(1) + "-" + spm.GetField(1).GetCompontent(1)
+}
+
+// buildSpecimenFromSPM converts an SPM segment to a FHIR Specimen
+func buildSpecimenFromSPM(cc *ConversionContext, obr, spm *hl7.Segment) *fhir.Specimen {
+	specimen := &fhir.Specimen{
+		ResourceType: "Specimen",
+		ID:           specimenID(obr, spm),
+	}
+
+	//SPM-2 Specimen ID: placer assigned ^ filler assigned
+	idField := spm.GetField(2)
+	if placer := buildEIPIdentifier(idField, 1); placer != nil {
+		specimen.Identifier = []fhir.Identifier{*placer}
+	}
+	specimen.AccessionIdentifier = buildEIPIdentifier(idField, 2)
+
+	//SPM-4 Specimen Type
+	specimen.Type = buildCodeableConcept(cc, spm.GetField(4))
+
+	collection := &fhir.SpecimenCollection{
+		//SPM-7 Specimen Collection Method
+		Method: buildCodeableConcept(cc, spm.GetField(7)),
+		//SPM-8 Specimen Source Site
+		BodySite: buildCodeableConcept(cc, spm.GetField(8)),
+	}
+
+	//SPM-17 Specimen Collection DateTime (start^end)
+	collectedField := spm.GetField(17)
+	start := collectedField.GetSubcomponent(1, 1)
+	end := collectedField.GetSubcomponent(2, 1)
+	if end != "" {
+		collection.CollectedPeriod = &fhir.Period{
+			Start: checkedDateTime(cc, spm, 17, start),
+			End:   checkedDateTime(cc, spm, 17, end),
+		}
+	} else if start != "" {
+		collection.CollectedDateTime = checkedDateTime(cc, spm, 17, start)
+	}
+
+	if collection.Method != nil || collection.BodySite != nil || collection.CollectedPeriod != nil || collection.CollectedDateTime != "" {
+		specimen.Collection = collection
+	}
+
+	//SPM-18 Specimen Received DateTime
+	received := spm.GetField(18).GetCompontent(1)
+	if received != "" {
+		specimen.ReceivedTime = checkedDateTime(cc, spm, 18, received)
+	}
+
+	cc.RecordSource(specimen, "identifier", spm, 2)
+	cc.RecordSource(specimen, "accessionIdentifier", spm, 2)
+	cc.RecordSource(specimen, "type", spm, 4)
+	cc.RecordSource(specimen, "collection.method", spm, 7)
+	cc.RecordSource(specimen, "collection.bodySite", spm, 8)
+	cc.RecordSource(specimen, "collection.collectedDateTime", spm, 17)
+	cc.RecordSource(specimen, "collection.collectedPeriod", spm, 17)
+	cc.RecordSource(specimen, "receivedTime", spm, 18)
+
+	return specimen
+}
+
+// buildSpecimenFromOBR converts the deprecated OBR-15 specimen source to a FHIR Specimen
+func buildSpecimenFromOBR(cc *ConversionContext, obr *hl7.Segment) *fhir.Specimen {
+	sourceField := obr.GetField(15)
+
+	//OBR-15.1 Specimen Source Name or Code (code&text&system)
+	code := sourceField.GetSubcomponent(1, 1)
+	if code == "" {
+		return nil
+	}
+
+	specimen := &fhir.Specimen{
+		ResourceType: "Specimen",
+		ID:           specimenID(obr, nil),
+		Type: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{
+				System:  mapCodeSystem(sourceField.GetSubcomponent(1, 3)),
+				Code:    code,
+				Display: sourceField.GetSubcomponent(1, 2),
+			}},
+			Text: sourceField.GetSubcomponent(1, 2),
+		},
+	}
+
+	collection := &fhir.SpecimenCollection{}
+
+	//OBR-15.3 Collection Method
+	method := sourceField.GetCompontent(3)
+	if method != "" {
+		collection.Method = &fhir.CodeableConcept{Text: method}
+	}
+
+	//OBR-15.4 Body Site (code&text&system)
+	site := sourceField.GetSubcomponent(4, 1)
+	if site != "" {
+		collection.BodySite = &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{
+				System:  mapCodeSystem(sourceField.GetSubcomponent(4, 3)),
+				Code:    site,
+				Display: sourceField.GetSubcomponent(4, 2),
+			}},
+			Text: sourceField.GetSubcomponent(4, 2),
+		}
+	}
+
+	//OBR-7 Observation DateTime is the collection time
+	collected := obr.GetField(7).GetCompontent(1)
+	if collected != "" {
+		collection.CollectedDateTime = checkedDateTime(cc, obr, 7, collected)
+	}
+
+	if collection.Method != nil || collection.BodySite != nil || collection.CollectedDateTime != "" {
+		specimen.Collection = collection
+	}
+
+	//OBR-14 Specimen Received DateTime
+	received := obr.GetField(14).GetCompontent(1)
+	if received != "" {
+		specimen.ReceivedTime = checkedDateTime(cc, obr, 14, received)
+	}
+
+	return specimen
+}
+
+// buildEIPIdentifier converts one half of an EIP field, an EI in subcomponents (entity&namespace&universalID&type)
+func buildEIPIdentifier(field *hl7.Field, component int) *fhir.Identifier {
+	return buildEIIdentifier(
+		field.GetSubcomponent(component, 1),
+		field.GetSubcomponent(component, 2),
+		field.GetSubcomponent(component, 3),
+		field.GetSubcomponent(component, 4))
+}
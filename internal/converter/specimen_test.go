@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestConvertToSpecimens(t *testing.T) {
+	cc := newSampleContext(t, "sample-elr.hl7")
+	specimens, err := ConvertToSpecimens(cc)
+	if err != nil {
+		t.Fatalf("ConvertToSpecimens() returned error: %v", err)
+	}
+	if len(specimens) != 1 {
+		t.Fatalf("Expected 1 specimen, got %d", len(specimens))
+	}
+
+	specimen := specimens[0]
+	if len(specimen.Identifier) != 1 || specimen.AccessionIdentifier == nil || specimen.Type == nil ||
+		specimen.Collection == nil || specimen.Collection.Method == nil || specimen.Collection.BodySite == nil || len(specimen.Request) != 1 {
+		t.Fatalf("Expected every sample field to be converted, got %+v", specimen)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id from SPM-2", specimen.ID, "specimen-SP-1001"},
+		{"subject", specimen.Subject.Reference, "Patient/583295"},
+		{"request", specimen.Request[0].Reference, "ServiceRequest/servicerequest-ORD123456"},
+		{"SPM-2.1 placer identifier", specimen.Identifier[0].Value, "SP-1001"},
+		{"SPM-2.1 assigner", specimen.Identifier[0].Assigner.Display, "EMR"},
+		{"SPM-2.2 accession identifier", specimen.AccessionIdentifier.Value, "ACC-2023-55"},
+		{"SPM-2.2 namespace is not a system", specimen.AccessionIdentifier.System, ""},
+		{"SPM-4 type", specimen.Type.Coding[0].Code, "258500001"},
+		{"SPM-4 SNOMED system", specimen.Type.Coding[0].System, "http://snomed.info/sct"},
+		{"SPM-7 collection method", specimen.Collection.Method.Coding[0].Code, "EMR"},
+		{"SPM-8 body site", specimen.Collection.BodySite.Coding[0].Code, "71836000"},
+		{"SPM-17 collected", specimen.Collection.CollectedDateTime, "2023-11-15T09:00:00"},
+		{"SPM-18 received", specimen.ReceivedTime, "2023-11-15T10:00:00"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, test.got)
+		}
+	}
+}
+
+func TestConvertToSpecimensFromOBR(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|HOSPITAL|EMR|HOSPITAL|20231115143000||ORU^R01|MSG001|P|2.3\r" +
+		"PID|1||583295^^^ADT1^MR||DOE^JOHN||19800115|M\r" +
+		"OBR|1|ORD1^EMR||CBC^Complete blood count^L|||20231115090000|||||||20231115100000|BLD&Blood&HL70070^^Venipuncture^LA&Left arm&HL70163\r" +
+		"OBX|1|NM|718-7^Hemoglobin^LN||13.5|g/dL|||||F")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	specimens, err := ConvertToSpecimens(newTestContext(msg, &fhir.Patient{ResourceType: "Patient", ID: "583295"}))
+	if err != nil || len(specimens) != 1 {
+		t.Fatalf("Expected one specimen from OBR-15, got %d (%v)", len(specimens), err)
+	}
+
+	specimen := specimens[0]
+	if specimen.Type == nil || specimen.Collection == nil || specimen.Collection.Method == nil || specimen.Collection.BodySite == nil {
+		t.Fatalf("Expected the OBR-15 type, method and site, got %+v", specimen)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id from OBR-1", specimen.ID, "specimen-1"},
+		{"request", specimen.Request[0].Reference, "ServiceRequest/servicerequest-ORD1"},
+		{"OBR-15.1 type", specimen.Type.Coding[0].Code, "BLD"},
+		{"OBR-15.1 system", specimen.Type.Coding[0].System, "http://terminology.hl7.org/CodeSystem/v2-0070"},
+		{"OBR-15.3 method", specimen.Collection.Method.Text, "Venipuncture"},
+		{"OBR-15.4 body site", specimen.Collection.BodySite.Coding[0].Code, "LA"},
+		{"OBR-7 collected", specimen.Collection.CollectedDateTime, "2023-11-15T09:00:00"},
+		{"OBR-14 received", specimen.ReceivedTime, "2023-11-15T10:00:00"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, test.got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mourice12/hl7-to-fhir/internal/mapping"
+)
+
+// Conversion modes
+const (
+	// ModeLenient converts best-effort, reporting issues alongside the bundle (the default)
+	ModeLenient = "lenient"
+	// ModeStrict fails the conversion on any warning or error: dropped values, missing required segments,
+	// unconverted Z-segments. Information-level issues, such as local coding systems and standard segments the
+	// converter leaves out, are reported without failing it.
+	ModeStrict = "strict"
+)
+
+// ErrUnknownMode is returned when ConvertOptions.Mode is neither lenient nor strict
+var ErrUnknownMode = errors.New("unknown conversion mode")
+
+// StrictError is returned in strict mode when the conversion found issues that fail it
+type StrictError struct {
+	Issues []Issue // the warnings and errors
+}
+
+// Error describes the first issue and counts the rest
+func (e *StrictError) Error() string {
+	if len(e.Issues) == 0 {
+		return "strict conversion failed"
+	}
+
+	first := e.Issues[0]
+	message := "strict conversion failed: " + first.Message
+	if first.Location != "" {
+		message = "strict conversion failed at " + first.Location + ": " + first.Message
+	}
+	if len(e.Issues) > 1 {
+		message += fmt.Sprintf(" (and %d more issues)", len(e.Issues)-1)
+	}
+	return message
+}
+
+// strictFailures returns the issues that fail a strict conversion: everything above information level
+func strictFailures(issues []Issue) []Issue {
+	var failures []Issue
+	for _, issue := range issues {
+		if issue.Severity != SeverityInformation {
+			failures = append(failures, issue)
+		}
+	}
+	return failures
+}
+
+// validateMode checks a ConvertOptions.Mode value
+func validateMode(mode string) error {
+	switch mode {
+	case "", ModeLenient, ModeStrict:
+		return nil
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownMode, mode)
+	}
+}
+
+// requiredSegments lists the segments the built-in handlers need for each message code
+var requiredSegments = map[string][]string{
+	"ADT": {"PID"},
+	"DFT": {"PID", "FT1"},
+	"ORU": {"PID", "OBR"},
+	"ORM": {"PID", "ORC"},
+	"OML": {"PID", "ORC"},
+	"OMG": {"PID", "ORC"},
+	"VXU": {"PID", "RXA"},
+	"SIU": {"PID", "SCH"},
+	"MDM": {"PID", "TXA"},
+	"RDE": {"PID", "RXE"},
+	"OMP": {"PID", "RXO"},
+	"RDS": {"PID", "RXD"},
+	"RAS": {"PID", "RXA"},
+}
+
+// convertedSegments are the segments the built-in conversion reads
+var convertedSegments = map[string]bool{
+	"MSH": true, "EVN": true, "PID": true, "PV1": true, "MRG": true, "AL1": true, "DG1": true, "PR1": true,
+	"ORC": true, "OBR": true, "OBX": true, "SPM": true, "TQ1": true, "FT1": true, "TXA": true,
+	"RXE": true, "RXO": true, "RXR": true, "RXD": true, "RXA": true,
+	"SCH": true, "AIS": true, "AIG": true, "AIL": true, "AIP": true,
+}
+
+// checkRequiredSegments reports the required segments a message lacks
+func checkRequiredSegments(cc *ConversionContext) {
+	msg := cc.Message
+	messageCode, _ := messageType(msg)
+	for _, name := range requiredSegments[messageCode] {
+		if msg.GetSegment(name) == nil {
+			cc.Report(name, SeverityError, IssueRequired, "%s message has no %s segment", messageCode, name)
+		}
+	}
+}
+
+// checkConvertedSegments reports segments that neither the built-in conversion, a segment hook nor a mapping reads
+func checkConvertedSegments(cc *ConversionContext, registry *Registry, set *mapping.Set) {
+	handled := map[string]bool{}
+	if set != nil {
+		for _, resource := range set.Resources {
+			handled[resource.Segment] = true
+		}
+		for _, segment := range set.Segments {
+			handled[segment.Segment] = true
+		}
+	}
+
+	reported := map[string]bool{}
+	for i := range cc.Message.Segments {
+		segment := &cc.Message.Segments[i]
+		name := segment.Name
+		if convertedSegments[name] || handled[name] || reported[name] || len(registry.segmentHooksFor(name)) > 0 {
+			continue
+		}
+		reported[name] = true
+
+		severity := SeverityInformation
+		if strings.HasPrefix(name, "Z") {
+			severity = SeverityWarning
+		}
+		cc.ReportSegment(segment, 0, severity, IssueNotSupported, "%s segments are not converted", name)
+	}
+}
@@ -0,0 +1,127 @@
+package converter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestStrictModeFailsOnIssues(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800101|M\r" +
+		"OBR|1|ORD123|LAB987|2345-7^Glucose^LN|||20231115100000\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||high|mg/dL|||||F\r" +
+		"ZXT|1|custom")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	//Lenient converts best-effort and reports the issues
+	opts := DefaultConvertOptions()
+	bundle, issues, err := ConvertToBundleWithIssues(msg, opts)
+	if err != nil || bundle == nil {
+		t.Fatalf("Expected a lenient conversion, got error %v", err)
+	}
+	locations := map[string]string{}
+	for _, issue := range issues {
+		locations[issue.Location] = issue.Code
+	}
+	if locations["ZXT[1]"] != IssueNotSupported {
+		t.Errorf("Expected the unconverted ZXT segment to be reported, got %v", issues)
+	}
+
+	//Strict fails with the first issue's location
+	opts.Mode = ModeStrict
+	bundle, _, err = ConvertToBundleWithIssues(msg, opts)
+	var strictErr *StrictError
+	if !errors.As(err, &strictErr) || bundle != nil {
+		t.Fatalf("Expected a StrictError and no bundle, got %v", err)
+	}
+	if len(strictErr.Issues) != len(strictFailures(issues)) || !strings.Contains(err.Error(), "at ZXT[1]:") {
+		t.Errorf("Expected the error to locate ZXT[1] and carry the warnings, got %v", err)
+	}
+}
+
+func TestStrictModeSamples(t *testing.T) {
+	files, err := filepath.Glob("../../testdata/sample*.hl7")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Expected sample messages, got %v (%v)", files, err)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.Mode = ModeStrict
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			msg := parseSampleFile(t, file)
+			bundle, issues, err := ConvertToBundleWithIssues(msg, opts)
+			if err != nil || bundle == nil {
+				t.Fatalf("Expected the sample to convert in strict mode, got %v", err)
+			}
+			for _, issue := range issues {
+				if issue.Severity != SeverityInformation {
+					t.Errorf("Unexpected %s issue %+v", issue.Severity, issue)
+				}
+			}
+		})
+	}
+}
+
+func TestStrictModeInformationIssues(t *testing.T) {
+	//NK1 is not converted and 99LOC is a local coding system: both are information only
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|FAC1|CERNER|FAC2|20231115||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+		"NK1|1|Doe^Jane|SPO\r" +
+		"OBX|1|CWE|X1^Local test^LN||POS^Positive^99LOC||||||F")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.Mode = ModeStrict
+	bundle, issues, err := ConvertToBundleWithIssues(msg, opts)
+	if err != nil || bundle == nil {
+		t.Fatalf("Expected information issues not to fail strict mode, got %v", err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("Expected the NK1 and 99LOC issues to be reported, got %v", issues)
+	}
+}
+
+func TestStrictModeMissingRequiredSegment(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John||19800101|M")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	opts := DefaultConvertOptions()
+	opts.Mode = ModeStrict
+	_, _, err = ConvertToBundleWithIssues(msg, opts)
+	var strictErr *StrictError
+	if !errors.As(err, &strictErr) || strictErr.Issues[0].Location != "OBR" || strictErr.Issues[0].Code != IssueRequired {
+		t.Errorf("Expected a required OBR error, got %v", err)
+	}
+
+	opts.Mode = "relaxed"
+	if _, _, err := ConvertToBundleWithIssues(msg, opts); !errors.Is(err, ErrUnknownMode) {
+		t.Errorf("Expected ErrUnknownMode, got %v", err)
+	}
+}
+
+// parseSampleFile parses a sample message from testdata
+func parseSampleFile(t *testing.T, file string) *hl7.Message {
+	t.Helper()
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile() returned error: %v", err)
+	}
+	msg, err := hl7.Parse(string(raw))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	return msg
+}
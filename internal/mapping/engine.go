@@ -0,0 +1,560 @@
+package mapping
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// Resource is a FHIR resource built by a mapping, as decoded JSON
+type Resource struct {
+	Type    string
+	Data    map[string]interface{}
+	Segment *hl7.Segment // the segment occurrence the resource was built from
+}
+
+// knownTransforms are the transforms a rule may name
+var knownTransforms = map[string]bool{
+	"date":       true,
+	"datetime":   true,
+	"instant":    true,
+	"number":     true,
+	"quantity":   true,
+	"comparator": true,
+	"query":      true,
+	"prefix":     true,
+	"suffix":     true,
+	"upper":      true,
+	"lower":      true,
+	"boolean":    true,
+}
+
+// placeholder matches {PATH} and optional {?PATH} template placeholders
+var placeholder = regexp.MustCompile(`\{(\??)([^{}]+)\}`)
+
+// scope is where HL7 paths are resolved: the message, the current segment and the current repetition
+type scope struct {
+	msg     *hl7.Message
+	segment *hl7.Segment
+	rep     *hl7.Repetition
+}
+
+// Apply builds the resources of every mapping in the set from the message
+func (s *Set) Apply(msg *hl7.Message) ([]Resource, error) {
+	var resources []Resource
+
+	for _, mapping := range s.Resources {
+		segments := msg.GetSegments(mapping.Segment)
+		if !mapping.Repeat && len(segments) > 1 {
+			segments = segments[:1]
+		}
+
+		for _, segment := range segments {
+			sc := scope{msg: msg, segment: segment}
+			if !s.holds(mapping.When, sc) {
+				continue
+			}
+
+			data := map[string]interface{}{"resourceType": mapping.Resource}
+			if id := s.template(mapping.ID, sc); id != "" {
+				data["id"] = id
+			}
+			s.applyRules(data, mapping.Rules, sc)
+
+			resources = append(resources, Resource{Type: mapping.Resource, Data: data, Segment: segment})
+		}
+	}
+
+	return resources, nil
+}
+
+// Extend applies the mappings of segment's name for resourceType to data, a resource built elsewhere as decoded
+// JSON, and reports whether any rule was applied
+func (s *Set) Extend(msg *hl7.Message, segment *hl7.Segment, resourceType string, data map[string]interface{}) bool {
+	applied := false
+
+	for _, mapping := range s.Segments {
+		if mapping.Segment != segment.Name || mapping.Resource != resourceType {
+			continue
+		}
+
+		sc := scope{msg: msg, segment: segment}
+		if !s.holds(mapping.When, sc) {
+			continue
+		}
+		s.applyRules(data, mapping.Rules, sc)
+		applied = true
+	}
+
+	return applied
+}
+
+// applyRules sets the targets of rules on obj
+func (s *Set) applyRules(obj map[string]interface{}, rules []Rule, sc scope) {
+	for _, rule := range rules {
+		switch {
+		case rule.Each != "":
+			for _, item := range s.items(rule.Each, rule.Group, sc) {
+				if !s.holds(rule.When, item) {
+					continue
+				}
+				if rule.Target == "" {
+					s.applyRules(obj, rule.Rules, item)
+					continue
+				}
+				child := map[string]interface{}{}
+				s.applyRules(child, rule.Rules, item)
+				if len(child) > 0 {
+					setPath(obj, appendTarget(rule.Target), child)
+				}
+			}
+		case len(rule.Rules) > 0:
+			if !s.holds(rule.When, sc) {
+				continue
+			}
+			child := map[string]interface{}{}
+			s.applyRules(child, rule.Rules, sc)
+			if len(child) > 0 {
+				setPath(obj, rule.Target, child)
+			}
+		default:
+			if !s.holds(rule.When, sc) {
+				continue
+			}
+			if value, ok := s.value(rule, sc); ok {
+				setPath(obj, rule.Target, value)
+			}
+		}
+	}
+}
+
+// value evaluates a rule's source, constant or concat, then its lookup and transform
+func (s *Set) value(rule Rule, sc scope) (interface{}, bool) {
+	var raw string
+	switch {
+	case rule.Source != "":
+		raw = sc.resolve(rule.Source)
+	case rule.Value != "":
+		raw = s.template(rule.Value, sc)
+	case len(rule.Concat) > 0:
+		var parts []string
+		for _, part := range rule.Concat {
+			if !s.holds(part.When, sc) {
+				continue
+			}
+			if value, ok := s.value(part, sc); ok {
+				parts = append(parts, toString(value))
+			}
+		}
+		raw = strings.Join(parts, "")
+	}
+
+	if rule.Lookup != "" {
+		table := s.Lookups[rule.Lookup]
+		mapped, ok := table[raw]
+		if !ok {
+			mapped = table["*"]
+		}
+		raw = mapped
+	}
+
+	if raw == "" {
+		return nil, false
+	}
+	return transform(rule.Transform, rule.Arg, raw)
+}
+
+// template replaces {PATH} placeholders; an empty required placeholder empties the whole value
+func (s *Set) template(text string, sc scope) string {
+	missing := false
+	result := placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		parts := placeholder.FindStringSubmatch(match)
+		value := sc.resolve(parts[2])
+		if value == "" && parts[1] == "" {
+			missing = true
+		}
+		return value
+	})
+	if missing {
+		return ""
+	}
+	return result
+}
+
+// holds reports whether all conditions hold
+func (s *Set) holds(conditions []Condition, sc scope) bool {
+	for _, condition := range conditions {
+		if condition.check(sc) == condition.Not {
+			return false
+		}
+	}
+	return true
+}
+
+// check evaluates one condition, ignoring Not
+func (c Condition) check(sc scope) bool {
+	if len(c.Any) > 0 {
+		present := false
+		for _, path := range c.Any {
+			if sc.resolve(path) != "" {
+				present = true
+			}
+		}
+		return c.Present == nil || present == *c.Present
+	}
+
+	value := sc.resolve(c.Source)
+	if c.Transform != "" {
+		transformed, ok := transform(c.Transform, "", value)
+		value = ""
+		if ok {
+			value = toString(transformed)
+		}
+	}
+	switch {
+	case c.Present != nil:
+		return (value != "") == *c.Present
+	case c.Equals != "":
+		return value == c.Equals
+	case len(c.In) > 0:
+		for _, candidate := range c.In {
+			if value == candidate {
+				return true
+			}
+		}
+		return false
+	default:
+		return value != ""
+	}
+}
+
+// items returns the scopes to repeat nested rules over: each segment with a name, in the message or in a group, or
+// each repetition of a field
+func (s *Set) items(each, group string, sc scope) []scope {
+	var items []scope
+
+	if !strings.Contains(each, "-") {
+		segments := sc.msg.GetSegments(each)
+		if group != "" {
+			segments = groupSegments(sc.msg, sc.segment, group, each)
+		}
+		for _, segment := range segments {
+			items = append(items, scope{msg: sc.msg, segment: segment})
+		}
+		return items
+	}
+
+	segment, fieldIndex, _, _, _ := sc.locate(each)
+	if segment == nil {
+		return nil
+	}
+	field := segment.GetField(fieldIndex)
+	if field == nil {
+		return nil
+	}
+	for i := range field.Repetitions {
+		items = append(items, scope{msg: sc.msg, segment: segment, rep: &field.Repetitions[i]})
+	}
+	return items
+}
+
+// groupSegments returns the segments with a name in the group headed by the last head segment at or before current,
+// up to the next head segment
+func groupSegments(msg *hl7.Message, current *hl7.Segment, head, name string) []*hl7.Segment {
+	var segments []*hl7.Segment
+	inGroup, passed := false, false
+
+	for i := range msg.Segments {
+		segment := &msg.Segments[i]
+		if segment.Name == head {
+			if passed {
+				return segments
+			}
+			segments, inGroup = nil, true
+		}
+		if segment == current {
+			passed = true
+		}
+		if inGroup && segment.Name == name {
+			segments = append(segments, segment)
+		}
+	}
+
+	if !passed {
+		return nil
+	}
+	return segments
+}
+
+// resolve returns the value at an HL7 path: SEG-field[rep].component.subcomponent, or .component.subcomponent
+// relative to the current repetition; alternatives separated by | give the first that has a value
+func (sc scope) resolve(path string) string {
+	if strings.Contains(path, "|") {
+		for _, alternative := range strings.Split(path, "|") {
+			if value := sc.resolve(alternative); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	if strings.HasPrefix(path, ".") {
+		if sc.rep == nil {
+			return ""
+		}
+		component, subcomponent := parsePosition(strings.TrimPrefix(path, "."))
+		return repetitionValue(sc.rep, component, subcomponent)
+	}
+
+	segment, fieldIndex, repIndex, component, subcomponent := sc.locate(path)
+	if segment == nil {
+		return ""
+	}
+	field := segment.GetField(fieldIndex)
+	if field == nil {
+		return ""
+	}
+	rep := field.GetRepetition(repIndex)
+	if rep == nil {
+		return ""
+	}
+	return repetitionValue(rep, component, subcomponent)
+}
+
+// locate parses an absolute path; the segment is the current one when the name matches, else the nearest one before
+// it, such as the OBR of an OBX, else the first in the message
+func (sc scope) locate(path string) (*hl7.Segment, int, int, int, int) {
+	dash := strings.Index(path, "-")
+	if dash < 0 {
+		return nil, 0, 0, 0, 0
+	}
+
+	name := path[:dash]
+	segment := sc.segment
+	if segment == nil || segment.Name != name {
+		segment = precedingSegment(sc.msg, sc.segment, name)
+	}
+
+	rest := path[dash+1:]
+	position := ""
+	if dot := strings.Index(rest, "."); dot >= 0 {
+		rest, position = rest[:dot], rest[dot+1:]
+	}
+
+	repIndex := 1
+	if open := strings.Index(rest, "["); open >= 0 && strings.HasSuffix(rest, "]") {
+		repIndex, _ = strconv.Atoi(rest[open+1 : len(rest)-1])
+		rest = rest[:open]
+	}
+
+	fieldIndex, err := strconv.Atoi(rest)
+	if err != nil {
+		return nil, 0, 0, 0, 0
+	}
+
+	component, subcomponent := parsePosition(position)
+	return segment, fieldIndex, repIndex, component, subcomponent
+}
+
+// precedingSegment returns the last segment with a name before current, or the first in the message when none is
+func precedingSegment(msg *hl7.Message, current *hl7.Segment, name string) *hl7.Segment {
+	var found *hl7.Segment
+	for i := range msg.Segments {
+		segment := &msg.Segments[i]
+		if segment == current {
+			break
+		}
+		if segment.Name == name {
+			found = segment
+		}
+	}
+	if found == nil || current == nil {
+		return msg.GetSegment(name)
+	}
+	return found
+}
+
+// parsePosition parses "component.subcomponent", defaulting both to 1
+func parsePosition(position string) (int, int) {
+	component, subcomponent := 1, 1
+	parts := strings.SplitN(position, ".", 2)
+	if n, err := strconv.Atoi(parts[0]); err == nil {
+		component = n
+	}
+	if len(parts) == 2 {
+		if n, err := strconv.Atoi(parts[1]); err == nil {
+			subcomponent = n
+		}
+	}
+	return component, subcomponent
+}
+
+// repetitionValue returns a subcomponent of a repetition, or "" when it is absent
+func repetitionValue(rep *hl7.Repetition, component, subcomponent int) string {
+	if component < 1 || component > len(rep.Components) {
+		return ""
+	}
+	subcomponents := rep.Components[component-1].Subcomponents
+	if subcomponent < 1 || subcomponent > len(subcomponents) {
+		return ""
+	}
+	return subcomponents[subcomponent-1]
+}
+
+// transform converts a raw value
+func transform(name, arg, raw string) (interface{}, bool) {
+	switch name {
+	case "date":
+		return nonEmpty(fhir.FormatDate(raw))
+	case "datetime":
+		return nonEmpty(fhir.FormatDateTime(raw))
+	case "instant":
+		return nonEmpty(fhir.FormatInstant(raw))
+	case "number":
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, false
+		}
+		return value, true
+	case "quantity":
+		_, number := splitComparator(raw)
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return nil, false
+		}
+		return value, true
+	case "comparator":
+		comparator, _ := splitComparator(raw)
+		return nonEmpty(comparator)
+	case "query":
+		return url.QueryEscape(raw), true
+	case "prefix":
+		return arg + raw, true
+	case "suffix":
+		return raw + arg, true
+	case "upper":
+		return strings.ToUpper(raw), true
+	case "lower":
+		return strings.ToLower(raw), true
+	case "boolean":
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, false
+		}
+		return value, true
+	default:
+		return raw, true
+	}
+}
+
+// splitComparator splits a leading <, <=, > or >= off a value such as <5
+func splitComparator(value string) (string, string) {
+	for _, comparator := range []string{"<=", ">=", "<", ">"} {
+		if strings.HasPrefix(value, comparator) {
+			return comparator, strings.TrimSpace(value[len(comparator):])
+		}
+	}
+	return "", value
+}
+
+// nonEmpty reports an empty string as no value
+func nonEmpty(value string) (interface{}, bool) {
+	return value, value != ""
+}
+
+// toString formats a rule value for concat
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// appendTarget makes an each target append to a list
+func appendTarget(target string) string {
+	if strings.HasSuffix(target, "]") {
+		return target
+	}
+	return target + "[]"
+}
+
+// setPath sets value at a FHIR path such as code.coding[0].system or name[].given[]
+func setPath(obj map[string]interface{}, path string, value interface{}) {
+	tokens := strings.Split(path, ".")
+	current := obj
+
+	for i, token := range tokens {
+		name, index := parseToken(token)
+		last := i == len(tokens)-1
+
+		if index == noIndex {
+			if last {
+				current[name] = value
+				return
+			}
+			child, ok := current[name].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				current[name] = child
+			}
+			current = child
+			continue
+		}
+
+		list, _ := current[name].([]interface{})
+		if index == appendIndex {
+			index = len(list)
+			if !last {
+				//An appended object is reused by the following tokens of the same path only
+				list = append(list, map[string]interface{}{})
+			} else {
+				list = append(list, nil)
+			}
+		}
+		for len(list) <= index {
+			list = append(list, map[string]interface{}{})
+		}
+		current[name] = list
+
+		if last {
+			list[index] = value
+			return
+		}
+		child, ok := list[index].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			list[index] = child
+		}
+		current = child
+	}
+}
+
+const (
+	noIndex     = -1
+	appendIndex = -2
+)
+
+// parseToken splits name[index] into its name and index
+func parseToken(token string) (string, int) {
+	open := strings.Index(token, "[")
+	if open < 0 || !strings.HasSuffix(token, "]") {
+		return token, noIndex
+	}
+	inner := token[open+1 : len(token)-1]
+	if inner == "" {
+		return token[:open], appendIndex
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return token[:open], noIndex
+	}
+	return token[:open], index
+}
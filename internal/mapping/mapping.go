@@ -0,0 +1,224 @@
+// Package mapping converts HL7 v2 messages to FHIR resources from declarative JSON rules.
+package mapping
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//go:embed defaults/*.json
+var defaultFiles embed.FS
+
+// Set is a collection of resource mappings and the lookup tables they use
+type Set struct {
+	Resources []ResourceMapping            `json:"resources"`
+	Segments  []SegmentMapping             `json:"segments,omitempty"`
+	Lookups   map[string]map[string]string `json:"lookups,omitempty"`
+}
+
+// ResourceMapping builds one FHIR resource per matching segment
+type ResourceMapping struct {
+	Resource string      `json:"resource"`         // FHIR resource type, e.g. Patient
+	Segment  string      `json:"segment"`          // segment the resource is built from, e.g. PID
+	Repeat   bool        `json:"repeat,omitempty"` // one resource per segment occurrence instead of the first only
+	When     []Condition `json:"when,omitempty"`   // all must hold for a resource to be built
+	ID       string      `json:"id,omitempty"`     // id template, e.g. condition-{DG1-1}
+	Rules    []Rule      `json:"rules"`
+}
+
+// SegmentMapping adds elements from a custom segment, such as ZPI, to a resource the converter has already built
+type SegmentMapping struct {
+	Segment  string      `json:"segment"`        // segment the values come from, e.g. ZPI
+	Resource string      `json:"resource"`       // resource type the rules apply to, e.g. Patient
+	When     []Condition `json:"when,omitempty"` // all must hold for a segment occurrence to be applied
+	Rules    []Rule      `json:"rules"`
+}
+
+// Rule sets one FHIR element from an HL7 value, a constant or a group of nested rules
+type Rule struct {
+	Target    string      `json:"target"`              // FHIR path, e.g. name[].given[] or code.coding[0].system
+	Source    string      `json:"source,omitempty"`    // HL7 path, e.g. PID-5.2, or .2 inside each
+	Value     string      `json:"value,omitempty"`     // constant or template with {PID-3.1} placeholders
+	Concat    []Rule      `json:"concat,omitempty"`    // values joined into one string
+	Lookup    string      `json:"lookup,omitempty"`    // lookup table applied to the value; "*" is the default row
+	Transform string      `json:"transform,omitempty"` // date, datetime, instant, number, quantity, comparator, boolean, query, prefix, suffix, upper, lower
+	Arg       string      `json:"arg,omitempty"`       // argument of the transform
+	When      []Condition `json:"when,omitempty"`      // all must hold; inside each they filter the items
+	Each      string      `json:"each,omitempty"`      // field (PID-3) or segment (OBX) to repeat the nested rules over; with no target, on this object
+	Group     string      `json:"group,omitempty"`     // with a segment each, only those in the group the nearest segment of this name heads
+	Rules     []Rule      `json:"rules,omitempty"`     // nested rules building an object at Target
+}
+
+// Condition tests an HL7 value
+type Condition struct {
+	Source    string   `json:"source,omitempty"`
+	Transform string   `json:"transform,omitempty"` // applied to the value before it is tested, e.g. quantity
+	Any       []string `json:"any,omitempty"`       // with present, holds when any of these paths has a value
+	Equals    string   `json:"equals,omitempty"`
+	In        []string `json:"in,omitempty"`
+	Present   *bool    `json:"present,omitempty"`
+	Not       bool     `json:"not,omitempty"`
+}
+
+// Defaults returns the built-in mappings for PID, PV1, DG1, AL1, OBR and OBX
+func Defaults() (*Set, error) {
+	return load(defaultFiles, "defaults")
+}
+
+// LoadDir loads every .json mapping file in dir, in name order
+func LoadDir(dir string) (*Set, error) {
+	return load(os.DirFS(dir), ".")
+}
+
+// LoadDirOverDefaults loads dir on top of the built-in mappings
+func LoadDirOverDefaults(dir string) (*Set, error) {
+	defaults, err := Defaults()
+	if err != nil {
+		return nil, err
+	}
+	site, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return Merge(defaults, site), nil
+}
+
+// Merge overlays override on base: its mappings replace base mappings of the same resource type,
+// its segment mappings replace those of the same segment and resource, and its lookup rows replace
+// base rows with the same key
+func Merge(base, override *Set) *Set {
+	replaced := map[string]bool{}
+	for _, resource := range override.Resources {
+		replaced[resource.Resource] = true
+	}
+	replacedSegments := map[string]bool{}
+	for _, segment := range override.Segments {
+		replacedSegments[segment.Segment+"|"+segment.Resource] = true
+	}
+
+	merged := &Set{Lookups: map[string]map[string]string{}}
+	for _, resource := range base.Resources {
+		if !replaced[resource.Resource] {
+			merged.Resources = append(merged.Resources, resource)
+		}
+	}
+	merged.Resources = append(merged.Resources, override.Resources...)
+
+	for _, segment := range base.Segments {
+		if !replacedSegments[segment.Segment+"|"+segment.Resource] {
+			merged.Segments = append(merged.Segments, segment)
+		}
+	}
+	merged.Segments = append(merged.Segments, override.Segments...)
+
+	for _, set := range []*Set{base, override} {
+		for name, table := range set.Lookups {
+			if merged.Lookups[name] == nil {
+				merged.Lookups[name] = map[string]string{}
+			}
+			for key, value := range table {
+				merged.Lookups[name][key] = value
+			}
+		}
+	}
+	return merged
+}
+
+// ResourceTypes returns the resource types the set builds
+func (s *Set) ResourceTypes() []string {
+	seen := map[string]bool{}
+	var types []string
+	for _, resource := range s.Resources {
+		if !seen[resource.Resource] {
+			seen[resource.Resource] = true
+			types = append(types, resource.Resource)
+		}
+	}
+	return types
+}
+
+// load reads and validates the .json files of a directory
+func load(fsys fs.FS, dir string) (*Set, error) {
+	names, err := fs.Glob(fsys, filepath.ToSlash(filepath.Join(dir, "*.json")))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	set := &Set{Lookups: map[string]map[string]string{}}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var file Set
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("mapping file %s: %w", name, err)
+		}
+		set = Merge(set, &file)
+	}
+
+	if err := set.validate(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// validate checks that every mapping names its resource and segment and uses known transforms and lookups
+func (s *Set) validate() error {
+	for _, resource := range s.Resources {
+		if resource.Resource == "" || resource.Segment == "" {
+			return fmt.Errorf("mapping needs resource and segment: %+v", resource)
+		}
+		if err := s.validateRules(resource.Resource, resource.Rules, false); err != nil {
+			return err
+		}
+	}
+	for _, segment := range s.Segments {
+		if segment.Resource == "" || segment.Segment == "" {
+			return fmt.Errorf("segment mapping needs resource and segment: %+v", segment)
+		}
+		if err := s.validateRules(segment.Resource, segment.Rules, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRules checks rules recursively; concat parts produce a value and need no target, nor does an each that sets
+// the enclosing object
+func (s *Set) validateRules(resourceType string, rules []Rule, part bool) error {
+	for _, rule := range rules {
+		if rule.Target == "" && !part && rule.Each == "" {
+			return fmt.Errorf("%s mapping: rule without target", resourceType)
+		}
+		if rule.Lookup != "" && s.Lookups[rule.Lookup] == nil {
+			return fmt.Errorf("%s mapping: unknown lookup %q for %s", resourceType, rule.Lookup, rule.Target)
+		}
+		if rule.Transform != "" && !knownTransforms[rule.Transform] {
+			return fmt.Errorf("%s mapping: unknown transform %q for %s", resourceType, rule.Transform, rule.Target)
+		}
+		for _, condition := range rule.When {
+			if condition.Transform != "" && !knownTransforms[condition.Transform] {
+				return fmt.Errorf("%s mapping: unknown transform %q in a condition for %s", resourceType, condition.Transform, rule.Target)
+			}
+		}
+		if strings.ContainsAny(rule.Source, " {}") {
+			return fmt.Errorf("%s mapping: invalid source %q", resourceType, rule.Source)
+		}
+		if err := s.validateRules(resourceType, rule.Rules, false); err != nil {
+			return err
+		}
+		if err := s.validateRules(resourceType, rule.Concat, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestDefaultsApply(t *testing.T) {
+	raw := "MSH|^~\\&|ADT|FAC1|EHR|FAC2|20231115120000||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN~67890^^^SSN||Doe^John^A||19800115|M\r" +
+		"PV1|1|I|WARD1^101^A"
+
+	msg, err := hl7.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	set, err := Defaults()
+	if err != nil {
+		t.Fatalf("Defaults() returned error: %v", err)
+	}
+
+	resources, err := set.Apply(msg)
+	if err != nil {
+		t.Fatalf("Apply() returned error: %v", err)
+	}
+
+	var patient map[string]interface{}
+	for _, resource := range resources {
+		if resource.Type == "Patient" {
+			patient = resource.Data
+		}
+	}
+	if patient == nil {
+		t.Fatal("expected a Patient")
+	}
+
+	if patient["birthDate"] != "1980-01-15" {
+		t.Errorf("birthDate = %v, want 1980-01-15", patient["birthDate"])
+	}
+	if patient["gender"] != "male" {
+		t.Errorf("gender = %v, want male", patient["gender"])
+	}
+	identifiers, _ := patient["identifier"].([]interface{})
+	if len(identifiers) != 2 {
+		t.Errorf("expected 2 identifiers, got %d", len(identifiers))
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	obj := map[string]interface{}{}
+	setPath(obj, "name[].family", "Doe")
+	setPath(obj, "name[0].given[]", "John")
+	setPath(obj, "name[0].given[]", "A")
+	setPath(obj, "code.coding[0].system", "http://loinc.org")
+
+	want := map[string]interface{}{
+		"name": []interface{}{
+			map[string]interface{}{"family": "Doe", "given": []interface{}{"John", "A"}},
+		},
+		"code": map[string]interface{}{
+			"coding": []interface{}{map[string]interface{}{"system": "http://loinc.org"}},
+		},
+	}
+	if !reflect.DeepEqual(obj, want) {
+		t.Errorf("setPath() built %v, want %v", obj, want)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|ADT|FAC1|EHR|FAC2|20231115120000||ADT^A01|MSG001|P|2.5\rDG1|1||I10^Hypertension^I10")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	set := &Set{}
+	sc := scope{msg: msg}
+	if got := set.template("condition-{DG1-1}", sc); got != "condition-1" {
+		t.Errorf("template() = %q, want condition-1", got)
+	}
+	if got := set.template("{DG1-3.1}-{?DG1-3.9}", sc); got != "I10-" {
+		t.Errorf("optional placeholder: got %q, want I10-", got)
+	}
+	if got := set.template("{DG1-3.1}-{DG1-3.9}", sc); got != "" {
+		t.Errorf("missing placeholder: got %q, want empty", got)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"OBR|1||F1\rOBX|1|NM|A\rSPM|1|S1\rOBR|2\rOBX|1|NM|B\rOBX|2|NM|C\rSPM|1|S2\rSPM|2|^S3")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	set := &Set{}
+	obx := msg.GetSegments("OBX")[2]
+	sc := scope{msg: msg, segment: obx}
+
+	//Other segments resolve to the nearest one before the current segment, alternatives to the first with a value
+	if got := sc.resolve("OBR-1"); got != "2" {
+		t.Errorf("OBR-1 from the third OBX: got %q, want 2", got)
+	}
+	if got := sc.resolve("OBR-3|OBR-1"); got != "2" {
+		t.Errorf("OBR-3|OBR-1: got %q, want 2", got)
+	}
+
+	var specimens []string
+	for _, item := range set.items("SPM", "OBR", sc) {
+		specimens = append(specimens, item.resolve("SPM-2.1|SPM-2.2"))
+	}
+	if want := []string{"S2", "S3"}; !reflect.DeepEqual(specimens, want) {
+		t.Errorf("SPM in the OBR group: got %v, want %v", specimens, want)
+	}
+
+	obj := map[string]interface{}{}
+	set.applyRules(obj, []Rule{{
+		Each:  "SPM",
+		Group: "OBR",
+		Rules: []Rule{{Target: "specimen.reference", Value: "Specimen/{SPM-2.1|SPM-2.2}"}},
+	}}, scope{msg: msg, segment: msg.GetSegments("OBX")[0]})
+	want := map[string]interface{}{"specimen": map[string]interface{}{"reference": "Specimen/S1"}}
+	if !reflect.DeepEqual(obj, want) {
+		t.Errorf("each without a target built %v, want %v", obj, want)
+	}
+}
+
+func TestDefaultsObservationValues(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN||Doe^John\r" +
+		"OBR|1|ORD123\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL\r" +
+		"OBX|2|NM|2160-0^Creatinine^LN||pending|mg/dL\r" +
+		"OBX|3|NM|1975-2^Bilirubin^LN||<5|mg/dL")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	set, err := Defaults()
+	if err != nil {
+		t.Fatalf("Defaults() returned error: %v", err)
+	}
+	resources, err := set.Apply(msg)
+	if err != nil {
+		t.Fatalf("Apply() returned error: %v", err)
+	}
+
+	values := map[string]map[string]interface{}{}
+	for _, resource := range resources {
+		if resource.Type == "Observation" {
+			values[resource.Data["id"].(string)] = resource.Data
+		}
+	}
+
+	//A number is a quantity, a number after a comparator keeps the comparator, anything else is kept as text
+	tests := []struct {
+		id       string
+		quantity interface{}
+		text     interface{}
+	}{
+		{"observation-1", map[string]interface{}{"value": 95.0, "unit": "mg/dL"}, nil},
+		{"observation-2", nil, "pending"},
+		{"observation-3", map[string]interface{}{"comparator": "<", "value": 5.0, "unit": "mg/dL"}, nil},
+	}
+	for _, test := range tests {
+		obs := values[test.id]
+		if obs == nil {
+			t.Fatalf("%s: expected an Observation, got %v", test.id, values)
+		}
+		if !reflect.DeepEqual(obs["valueQuantity"], test.quantity) {
+			t.Errorf("%s: valueQuantity = %v, want %v", test.id, obs["valueQuantity"], test.quantity)
+		}
+		if obs["valueString"] != test.text {
+			t.Errorf("%s: valueString = %v, want %v", test.id, obs["valueString"], test.text)
+		}
+	}
+}
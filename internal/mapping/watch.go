@@ -0,0 +1,54 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Watch polls dir every interval and calls reload with the mappings loaded over the defaults whenever
+// a .json file is added, removed or modified. It returns a function that stops watching.
+func Watch(dir string, interval time.Duration, reload func(*Set, error)) func() {
+	stop := make(chan struct{})
+	last := fingerprint(dir)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				current := fingerprint(dir)
+				if current == last {
+					continue
+				}
+				last = current
+				reload(LoadDirOverDefaults(dir))
+			}
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
+// fingerprint summarises the names, sizes and modification times of the mapping files in dir
+func fingerprint(dir string) string {
+	names, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, name+"|"+info.ModTime().String()+"|"+strconv.FormatInt(info.Size(), 10))
+	}
+	return strings.Join(parts, "\n")
+}
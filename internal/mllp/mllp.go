@@ -0,0 +1,224 @@
+// Package mllp receives HL7 v2 messages over the Minimal Lower Layer Protocol and answers them with ACKs.
+package mllp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// MLLP framing bytes
+const (
+	startBlock     = 0x0b
+	endBlock       = 0x1c
+	carriageReturn = 0x0d
+)
+
+// ACK acknowledgment codes (MSA-1)
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// Handler processes one received message and returns the acknowledgment to send back
+type Handler func(raw string) string
+
+// Server accepts MLLP connections and passes every framed message to Handler
+type Server struct {
+	Addr           string
+	Handler        Handler
+	MaxMessageSize int           // larger messages are rejected with an AR without reaching Handler; zero means no limit
+	ReadTimeout    time.Duration // time allowed to receive each message before the connection is closed; zero means no limit
+}
+
+// ListenAndServe listens on Addr and serves connections until the listener fails
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener, one goroutine per connection
+func (s *Server) Serve(listener net.Listener) error {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn reads messages from a connection until it is closed
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		//A client that stalls before or inside a frame does not hold the connection forever
+		if s.ReadTimeout > 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(s.ReadTimeout)); err != nil {
+				log.Printf("MLLP %s: %v", conn.RemoteAddr(), err)
+				return
+			}
+		}
+
+		raw, err := ReadMessageLimit(reader, s.MaxMessageSize)
+		if errors.Is(err, hl7.ErrTooLarge) {
+			log.Printf("MLLP %s: %v", conn.RemoteAddr(), err)
+			if err := WriteMessage(conn, Ack(nil, AckReject, err.Error())); err != nil {
+				log.Printf("MLLP %s: %v", conn.RemoteAddr(), err)
+				return
+			}
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("MLLP %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		if err := WriteMessage(conn, s.Handler(raw)); err != nil {
+			log.Printf("MLLP %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// ReadMessage reads one framed message: <VT>message<FS><CR>
+func ReadMessage(reader *bufio.Reader) (string, error) {
+	return ReadMessageLimit(reader, 0)
+}
+
+// ReadMessageLimit reads one framed message of at most maxSize bytes; a larger message is read to its end
+// and discarded, so the connection can go on, and reported as hl7.ErrTooLarge. Zero means no limit.
+func ReadMessageLimit(reader *bufio.Reader, maxSize int) (string, error) {
+	//Skip anything before the start block
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == startBlock {
+			break
+		}
+	}
+
+	var data []byte
+	size := 0
+	for {
+		chunk, err := reader.ReadSlice(endBlock)
+		size += len(chunk)
+		if maxSize <= 0 || size <= maxSize+1 {
+			data = append(data, chunk...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("connection closed inside a message: %w", io.ErrUnexpectedEOF)
+			}
+			return "", err
+		}
+		break
+	}
+
+	//The carriage return after the end block
+	if b, err := reader.ReadByte(); err == nil && b != carriageReturn {
+		reader.UnreadByte()
+	}
+
+	if maxSize > 0 && size-1 > maxSize {
+		return "", fmt.Errorf("%w: %d bytes, limit %d", hl7.ErrTooLarge, size-1, maxSize)
+	}
+	return string(data[:len(data)-1]), nil
+}
+
+// WriteMessage writes one framed message
+func WriteMessage(w io.Writer, message string) error {
+	frame := make([]byte, 0, len(message)+3)
+	frame = append(frame, startBlock)
+	frame = append(frame, message...)
+	frame = append(frame, endBlock, carriageReturn)
+	_, err := w.Write(frame)
+	return err
+}
+
+// Ack builds the acknowledgment of msg with an MSA-1 code and optional MSA-3 text; msg may be nil
+// when the message could not be parsed
+func Ack(msg *hl7.Message, code, text string) string {
+	var msh *hl7.Segment
+	if msg != nil {
+		msh = msg.GetSegment("MSH")
+	}
+
+	field := func(index int) string {
+		if msh == nil {
+			return ""
+		}
+		return msh.GetField(index).GetCompontent(1)
+	}
+
+	//Sender and receiver swap, the trigger event is echoed
+	header := []string{
+		"MSH", "^~\\&",
+		field(5), field(6), field(3), field(4),
+		time.Now().UTC().Format("20060102150405"),
+		"",
+		"ACK^" + triggerEvent(msh) + "^ACK",
+		"ACK" + field(10),
+		firstNonEmpty(field(11), "P"),
+		firstNonEmpty(field(12), "2.5"),
+	}
+	acknowledgment := []string{"MSA", code, field(10)}
+	if text != "" {
+		acknowledgment = append(acknowledgment, escape(text))
+	}
+
+	return strings.Join(header, "|") + "\r" + strings.Join(acknowledgment, "|")
+}
+
+// triggerEvent returns MSH-9.2
+func triggerEvent(msh *hl7.Segment) string {
+	if msh == nil {
+		return ""
+	}
+	return msh.GetField(9).GetCompontent(2)
+}
+
+// escape replaces the default delimiters in text with HL7 escape sequences
+func escape(text string) string {
+	replacer := strings.NewReplacer(
+		"\\", "\\E\\",
+		"|", "\\F\\",
+		"^", "\\S\\",
+		"&", "\\T\\",
+		"~", "\\R\\",
+		"\r", " ",
+		"\n", " ",
+	)
+	return replacer.Replace(text)
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package mllp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func TestReadWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	messages := []string{"MSH|^~\\&|A|B|C|D|20231115120000||ADT^A01|1|P|2.5", "MSH|^~\\&|A|B|C|D|20231115120000||ADT^A08|2|P|2.5"}
+	for _, message := range messages {
+		if err := WriteMessage(&buf, message); err != nil {
+			t.Fatalf("WriteMessage() returned error: %v", err)
+		}
+	}
+
+	reader := bufio.NewReader(&buf)
+	for _, want := range messages {
+		got, err := ReadMessage(reader)
+		if err != nil {
+			t.Fatalf("ReadMessage() returned error: %v", err)
+		}
+		if got != want {
+			t.Errorf("ReadMessage() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestReadMessageLimit(t *testing.T) {
+	var buf bytes.Buffer
+	WriteMessage(&buf, "MSH|^~\\&|A|B|C|D|20231115120000||ORU^R01|1|P|2.5\rOBX|1|ED|||"+strings.Repeat("A", 10000))
+	WriteMessage(&buf, "MSH|^~\\&|A|B|C|D|20231115120000||ADT^A08|2|P|2.5")
+
+	//The oversized message is skipped and the next one still reads
+	reader := bufio.NewReader(&buf)
+	if _, err := ReadMessageLimit(reader, 1000); !errors.Is(err, hl7.ErrTooLarge) {
+		t.Fatalf("Expected ErrTooLarge, got %v", err)
+	}
+	got, err := ReadMessageLimit(reader, 1000)
+	if err != nil || !strings.HasSuffix(got, "ADT^A08|2|P|2.5") {
+		t.Errorf("Expected the next message after the oversized one, got %q, %v", got, err)
+	}
+}
+
+func TestAck(t *testing.T) {
+	msg, err := hl7.Parse("MSH|^~\\&|LAB|FAC1|EHR|FAC2|20231115120000||ORU^R01|MSG001|P|2.5")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	ack, err := hl7.Parse(Ack(msg, AckError, "bad value | here"))
+	if err != nil {
+		t.Fatalf("Parse(Ack()) returned error: %v", err)
+	}
+
+	msh := ack.GetSegment("MSH")
+	if msh.GetField(3).GetCompontent(1) != "EHR" || msh.GetField(5).GetCompontent(1) != "LAB" {
+		t.Errorf("Expected sender and receiver to swap")
+	}
+	msa := ack.GetSegment("MSA")
+	if msa.GetField(1).GetCompontent(1) != AckError || msa.GetField(2).GetCompontent(1) != "MSG001" {
+		t.Errorf("Unexpected MSA %v", msa)
+	}
+	if !strings.Contains(Ack(msg, AckError, "a|b"), "a\\F\\b") {
+		t.Errorf("Expected delimiters in the text to be escaped")
+	}
+}
+
+func TestServerReadTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() returned error: %v", err)
+	}
+	server := &Server{
+		Handler:     func(raw string) string { return Ack(nil, AckAccept, "") },
+		ReadTimeout: 200 * time.Millisecond,
+	}
+	go server.Serve(listener)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	//A complete message is answered, the deadline applies to each frame
+	time.Sleep(100 * time.Millisecond)
+	WriteMessage(conn, "MSH|^~\\&|A|B|C|D|20231115120000||ADT^A01|1|P|2.5")
+	if _, err := ReadMessage(reader); err != nil {
+		t.Fatalf("Expected an ACK, got %v", err)
+	}
+
+	//A client that stalls inside a frame is disconnected
+	conn.Write([]byte{startBlock, 'M', 'S', 'H'})
+	if _, err := ReadMessage(reader); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the server to close the stalled connection, got %v", err)
+	}
+}
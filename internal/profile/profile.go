@@ -0,0 +1,185 @@
+// Package profile holds per-sender conversion settings selected by MSH-3 Sending Application and MSH-4 Sending Facility.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Profile overrides conversion settings for messages from one or more senders
+type Profile struct {
+	Name    string   `json:"name"`    // defaults to the file name without .json
+	Senders []Sender `json:"senders"` // senders the profile is selected for
+	// IdentifierSystems sets the system of identifiers by their assigning authority (CX-4) or EI namespace, e.g. HOSP
+	IdentifierSystems map[string]IdentifierSystem `json:"identifierSystems,omitempty"`
+	// CodeSystems maps coding system names (CWE-3) that have no standard system, e.g. 99LAB, to system URIs
+	CodeSystems map[string]string `json:"codeSystems,omitempty"`
+	// CodeMap adds a standard coding to concepts coded as system|code, e.g. http://lab.example.org|GLU
+	CodeMap map[string]Coding `json:"codeMap,omitempty"`
+	// Timezone is the IANA zone of HL7 times that carry no offset, e.g. America/Chicago
+	Timezone string `json:"timezone,omitempty"`
+	// IDStrategy names the resource id strategy: hash, uuid or source
+	IDStrategy string `json:"idStrategy,omitempty"`
+	// Resources lists the resource types to keep; empty keeps all
+	Resources []string `json:"resources,omitempty"`
+
+	location *time.Location
+}
+
+// Sender matches MSH-3.1 and MSH-4.1; an empty value matches any
+type Sender struct {
+	Application string `json:"application,omitempty"`
+	Facility    string `json:"facility,omitempty"`
+}
+
+// IdentifierSystem is the system, and optionally the identifier type (HL7 table 0203), for an assigning authority
+type IdentifierSystem struct {
+	System string `json:"system"`
+	Type   string `json:"type,omitempty"` // e.g. MR, PI, PT
+}
+
+// Coding is a standard code a local code translates to
+type Coding struct {
+	System  string `json:"system"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+// Set is the collection of profiles loaded from a directory
+type Set struct {
+	Profiles []*Profile
+}
+
+// LoadDir loads one profile from every .json file in dir, in name order
+func LoadDir(dir string) (*Set, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	set := &Set{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		profile := &Profile{}
+		if err := json.Unmarshal(data, profile); err != nil {
+			return nil, fmt.Errorf("profile file %s: %w", name, err)
+		}
+		if profile.Name == "" {
+			profile.Name = strings.TrimSuffix(filepath.Base(name), ".json")
+		}
+		if seen[profile.Name] {
+			return nil, fmt.Errorf("profile file %s: duplicate profile name %q", name, profile.Name)
+		}
+		seen[profile.Name] = true
+
+		if err := profile.init(); err != nil {
+			return nil, fmt.Errorf("profile file %s: %w", name, err)
+		}
+		set.Profiles = append(set.Profiles, profile)
+	}
+
+	return set, nil
+}
+
+// init validates the profile and loads its timezone
+func (p *Profile) init() error {
+	p.location = time.UTC
+	if p.Timezone != "" {
+		location, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+		p.location = location
+	}
+
+	for authority, system := range p.IdentifierSystems {
+		if system.System == "" {
+			return fmt.Errorf("profile %s: identifier system for %s needs a system", p.Name, authority)
+		}
+	}
+	for key, coding := range p.CodeMap {
+		if !strings.Contains(key, "|") || coding.System == "" || coding.Code == "" {
+			return fmt.Errorf("profile %s: code map entry %q needs a system|code key and a system and code", p.Name, key)
+		}
+	}
+	return nil
+}
+
+// Location returns the timezone of HL7 times without an offset, UTC when none is configured
+func (p *Profile) Location() *time.Location {
+	if p.location == nil {
+		return time.UTC
+	}
+	return p.location
+}
+
+// KeepsResource reports whether the profile keeps resources of a type
+func (p *Profile) KeepsResource(resourceType string) bool {
+	if len(p.Resources) == 0 {
+		return true
+	}
+	for _, kept := range p.Resources {
+		if kept == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// Named returns the profile with a name, or nil
+func (s *Set) Named(name string) *Profile {
+	if s == nil {
+		return nil
+	}
+	for _, profile := range s.Profiles {
+		if profile.Name == name {
+			return profile
+		}
+	}
+	return nil
+}
+
+// Select returns the profile whose sender matches the application and facility most specifically, or nil
+func (s *Set) Select(application, facility string) *Profile {
+	if s == nil {
+		return nil
+	}
+
+	var selected *Profile
+	best := -1
+	for _, profile := range s.Profiles {
+		for _, sender := range profile.Senders {
+			score := sender.match(application, facility)
+			if score > best {
+				selected, best = profile, score
+			}
+		}
+	}
+	return selected
+}
+
+// match scores a sender against MSH-3/MSH-4: -1 for no match, else the number of values that matched exactly
+func (s Sender) match(application, facility string) int {
+	score := 0
+	for _, pair := range [][2]string{{s.Application, application}, {s.Facility, facility}} {
+		switch {
+		case pair[0] == "":
+		case strings.EqualFold(pair[0], pair[1]):
+			score++
+		default:
+			return -1
+		}
+	}
+	return score
+}
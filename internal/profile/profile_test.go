@@ -0,0 +1,60 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDirAndSelect(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lab.json":     `{"senders": [{"application": "LAB"}], "timezone": "America/Chicago"}`,
+		"lab-fac.json": `{"name": "lab-east", "senders": [{"application": "LAB", "facility": "EAST"}], "resources": ["Patient"]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	set, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir() returned error: %v", err)
+	}
+
+	tests := []struct {
+		application, facility string
+		want                  string
+	}{
+		{"LAB", "EAST", "lab-east"},
+		{"lab", "WEST", "lab"},
+		{"ADT", "EAST", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if selected := set.Select(tt.application, tt.facility); selected != nil {
+			got = selected.Name
+		}
+		if got != tt.want {
+			t.Errorf("Select(%s, %s) = %q, want %q", tt.application, tt.facility, got, tt.want)
+		}
+	}
+
+	if set.Named("lab").Location().String() != "America/Chicago" {
+		t.Errorf("Expected America/Chicago timezone, got %s", set.Named("lab").Location())
+	}
+	if set.Named("lab-east").KeepsResource("Observation") {
+		t.Errorf("Expected lab-east to drop Observations")
+	}
+}
+
+func TestLoadDirRejectsUnknownTimezone(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"timezone": "Mars/Olympus"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDir(dir); err == nil {
+		t.Errorf("Expected an error for an unknown timezone")
+	}
+}
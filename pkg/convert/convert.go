@@ -0,0 +1,195 @@
+// Package convert is the public API of the HL7 v2 to FHIR R4 converter.
+//
+// A Converter is built once from Options and converts raw ER7 messages into FHIR transaction or message
+// bundles, together with an Outcome listing what could not be converted cleanly:
+//
+//	converter := convert.NewConverter(convert.Options{Mode: convert.ModeLenient})
+//	bundle, outcome, err := converter.Convert(ctx, raw)
+//
+// The packages under pkg/ follow semantic versioning; see Version.
+package convert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mourice12/hl7-to-fhir/internal/converter"
+	"github.com/mourice12/hl7-to-fhir/internal/mapping"
+	"github.com/mourice12/hl7-to-fhir/internal/profile"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+// Version is the semantic version of the public API in pkg/
+const Version = "1.0.0"
+
+// Bundle types
+const (
+	BundleTransaction = converter.BundleTransaction
+	BundleMessage     = converter.BundleMessage
+)
+
+// Conversion modes
+const (
+	ModeLenient = converter.ModeLenient
+	ModeStrict  = converter.ModeStrict
+)
+
+// Errors returned by Convert, to be checked with errors.Is; strict mode failures are a *StrictError
+var (
+	ErrUnsupportedMessageType = converter.ErrUnsupportedMessageType
+	ErrUnknownProfile         = converter.ErrUnknownProfile
+	ErrUnknownMode            = converter.ErrUnknownMode
+	ErrTooLarge               = hl7.ErrTooLarge // the message exceeds Options.Limits
+	ErrTimeout                = hl7.ErrTimeout  // the context's deadline passed first
+)
+
+// Limits bound the messages Convert accepts: size, segment count, field length and repetitions
+type Limits = hl7.Limits
+
+// DefaultLimits returns limits suited to a server
+func DefaultLimits() Limits {
+	return hl7.DefaultLimits()
+}
+
+// Bundle is a converted FHIR Bundle
+type Bundle = fhir.Bundle
+
+// Issue is a problem found while converting a message, located in the HL7 message, e.g. OBX[3]-5
+type Issue = converter.Issue
+
+// StrictError is returned in strict mode when the conversion found warnings or errors
+type StrictError = converter.StrictError
+
+// Mappings are declarative JSON mappings that replace the built-in conversion of the resource types they build
+type Mappings = mapping.Set
+
+// Profiles are per-sender overrides selected by MSH-3/MSH-4
+type Profiles = profile.Set
+
+// Registry routes messages to handlers by MSH-9
+type Registry = converter.Registry
+
+// Handler converts a message routed to it by a Registry
+type Handler = converter.Handler
+
+// SegmentHook handles a custom segment, such as ZPI, after the built-in conversion
+type SegmentHook = converter.SegmentHook
+
+// ConversionContext is the state of one conversion, passed to handlers and segment hooks
+type ConversionContext = converter.ConversionContext
+
+// Options configure a Converter; the zero value converts to transaction bundles with hashed ids, leniently
+type Options struct {
+	IDStrategy           string    // hash (the default), uuid or source
+	BundleType           string    // BundleTransaction (the default) or BundleMessage
+	IncludeSourceMessage bool      // store the raw message as a Binary referenced by the Provenance
+	Mode                 string    // ModeLenient (the default) or ModeStrict
+	Mappings             *Mappings // nil uses the built-in conversion only
+	Profiles             *Profiles // nil applies no sender profile
+	Profile              string    // forces the named profile instead of selecting one by sender
+	Registry             *Registry // nil uses the built-in handlers
+	Limits               Limits    // the zero value accepts any message; see DefaultLimits
+}
+
+// Outcome lists the issues found while converting a message
+type Outcome struct {
+	Issues []Issue
+}
+
+// OperationOutcome reports the issues as a FHIR OperationOutcome
+func (o *Outcome) OperationOutcome() *fhir.OperationOutcome {
+	return converter.ToOperationOutcome(o.Issues)
+}
+
+// Converter converts HL7 v2 messages to FHIR bundles; it is safe for concurrent use
+type Converter struct {
+	opts   converter.ConvertOptions
+	limits Limits
+	err    error
+}
+
+// NewConverter returns a Converter for the options; invalid options are reported by Convert
+func NewConverter(options Options) *Converter {
+	opts := converter.DefaultConvertOptions()
+	opts.BundleType = options.BundleType
+	opts.IncludeSourceMessage = options.IncludeSourceMessage
+	opts.Mode = options.Mode
+	opts.Mappings = options.Mappings
+	opts.Profiles = options.Profiles
+	opts.Profile = options.Profile
+	opts.Registry = options.Registry
+
+	ids, err := converter.IDStrategyByName(options.IDStrategy)
+	opts.IDs = ids
+	return &Converter{opts: opts, limits: options.Limits, err: err}
+}
+
+// Convert parses a raw ER7 message within the Limits and converts it. The Outcome is returned with the bundle,
+// and also with a *StrictError when strict mode fails. Convert stops with ErrTimeout when ctx's deadline passes
+// and with ctx.Err() when it is cancelled.
+func (c *Converter) Convert(ctx context.Context, raw string) (*Bundle, *Outcome, error) {
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+
+	msg, err := hl7.ParseContext(ctx, raw, c.limits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing HL7: %w", err)
+	}
+	return c.ConvertMessage(ctx, msg)
+}
+
+// ConvertMessage converts a message that is already parsed; the Limits apply to parsing and are not checked
+func (c *Converter) ConvertMessage(ctx context.Context, msg *hl7.Message) (*Bundle, *Outcome, error) {
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+
+	bundle, issues, err := converter.ConvertToBundleContext(ctx, msg, c.opts)
+	if err != nil {
+		var strictErr *StrictError
+		if errors.As(err, &strictErr) {
+			return nil, &Outcome{Issues: issues}, err
+		}
+		return nil, nil, err
+	}
+	return bundle, &Outcome{Issues: issues}, nil
+}
+
+// DefaultMappings returns the built-in PID/PV1/DG1/AL1/OBR/OBX mappings
+func DefaultMappings() (*Mappings, error) {
+	return mapping.Defaults()
+}
+
+// LoadMappings loads the JSON mapping files in dir over the built-in mappings
+func LoadMappings(dir string) (*Mappings, error) {
+	return mapping.LoadDirOverDefaults(dir)
+}
+
+// LoadProfiles loads the per-sender profile JSON files in dir
+func LoadProfiles(dir string) (*Profiles, error) {
+	return profile.LoadDir(dir)
+}
+
+// NewRegistry returns a registry without handlers, for Options.Registry
+func NewRegistry() *Registry {
+	return converter.NewRegistry()
+}
+
+// Register adds a handler for MSH-9.1/9.2/9.3 to the built-in registry; an empty trigger or structure
+// matches any value
+func Register(messageCode, trigger, structure string, handler Handler) {
+	converter.Register(messageCode, trigger, structure, handler)
+}
+
+// NewSegmentHook returns a SegmentHook that calls apply for every occurrence of the named segment
+func NewSegmentHook(name string, apply func(segment *hl7.Segment, cc *ConversionContext) error) SegmentHook {
+	return converter.NewSegmentHook(name, apply)
+}
+
+// RegisterSegmentHook adds a segment hook to the built-in registry
+func RegisterSegmentHook(hook SegmentHook) {
+	converter.RegisterSegmentHook(hook)
+}
@@ -0,0 +1,33 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestConverterErrors(t *testing.T) {
+	raw := "MSH|^~\\&|EPIC|HOSP|EHR|HOSP|20231115120000||ADT^A01|MSG001|P|2.5\rPID|1||12345^^^MRN||Doe^John"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := NewConverter(Options{}).Convert(ctx, raw); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if _, _, err := NewConverter(Options{IDStrategy: "random"}).Convert(context.Background(), raw); err == nil {
+		t.Errorf("Expected an error for an unknown ID strategy")
+	}
+
+	if _, _, err := NewConverter(Options{Mode: "relaxed"}).Convert(context.Background(), raw); !errors.Is(err, ErrUnknownMode) {
+		t.Errorf("Expected ErrUnknownMode, got %v", err)
+	}
+
+	if _, _, err := NewConverter(Options{Limits: Limits{MaxMessageSize: 50}}).Convert(context.Background(), raw); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+
+	if _, _, err := NewConverter(Options{}).Convert(context.Background(), "PID|1"); err == nil {
+		t.Errorf("Expected a parse error for a message without MSH")
+	}
+}
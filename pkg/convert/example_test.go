@@ -0,0 +1,110 @@
+package convert_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/convert"
+	"github.com/mourice12/hl7-to-fhir/pkg/fhir"
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+const admission = "MSH|^~\\&|EPIC|HOSP|EHR|HOSP|20231115120000||ADT^A01|MSG001|P|2.5\r" +
+	"EVN|A01|20231115120000\r" +
+	"PID|1||12345^^^MRN||Doe^John||19800115|M\r" +
+	"PV1|1|I|ICU^0101^01||||||||||||||||V100\r" +
+	"NK1|1|Doe^Jane|SPO"
+
+func ExampleConverter_Convert() {
+	converter := convert.NewConverter(convert.Options{})
+
+	bundle, outcome, err := converter.Convert(context.Background(), admission)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, entry := range bundle.Entry {
+		fmt.Printf("%T %s\n", entry.Resource, entry.Request.Method)
+	}
+	for _, issue := range outcome.Issues {
+		fmt.Println(issue.Location, issue.Message)
+	}
+	// Output:
+	// *fhir.Patient PUT
+	// *fhir.Encounter PUT
+	// *fhir.Provenance PUT
+	// NK1[1] NK1 segments are not converted
+}
+
+func ExampleConverter_Convert_strict() {
+	converter := convert.NewConverter(convert.Options{Mode: convert.ModeStrict})
+
+	//The unconverted NK1 is information only; the custom ZPI segment fails strict mode
+	_, outcome, err := converter.Convert(context.Background(), admission+"\rZPI|1|custom")
+	var strictErr *convert.StrictError
+	if errors.As(err, &strictErr) {
+		fmt.Println(err)
+		for _, issue := range outcome.OperationOutcome().Issue {
+			fmt.Println(issue.Severity, issue.Location)
+		}
+	}
+	// Output:
+	// strict conversion failed at ZPI[1]: ZPI segments are not converted
+	// information [NK1[1]]
+	// warning [ZPI[1]]
+}
+
+func ExampleNewRegistry() {
+	//An in-house message type, converted by a handler that reuses the context's references
+	registry := convert.NewRegistry()
+	registry.Register("ZPT", "", "", func(cc *convert.ConversionContext) error {
+		pid := cc.Message.GetSegment("PID")
+		patient := &fhir.Patient{
+			ResourceType: "Patient",
+			ID:           pid.GetField(3).GetCompontent(1),
+		}
+		if err := cc.AddResource(patient); err != nil {
+			return err
+		}
+		return cc.AddResource(&fhir.Condition{
+			ResourceType: "Condition",
+			ID:           "flagged",
+			Subject:      cc.PatientReference(),
+		})
+	})
+
+	converter := convert.NewConverter(convert.Options{Registry: registry, IDStrategy: "source"})
+	bundle, _, err := converter.Convert(context.Background(),
+		"MSH|^~\\&|APP|HOSP|EHR|HOSP|20231115120000||ZPT^Z01|MSG002|P|2.5\rPID|1||12345")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	condition := bundle.Entry[1].Resource.(*fhir.Condition)
+	fmt.Println(condition.Subject.Reference == bundle.Entry[0].FullURL)
+	// Output:
+	// true
+}
+
+func ExampleConverter_ConvertMessage() {
+	msg, err := hl7.Parse(admission)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	bundle, _, err := convert.NewConverter(convert.Options{BundleType: convert.BundleMessage}).
+		ConvertMessage(context.Background(), msg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(bundle.Type)
+	fmt.Printf("%T\n", bundle.Entry[0].Resource)
+	// Output:
+	// message
+	// *fhir.MessageHeader
+}
@@ -1,7 +1,5 @@
 package fhir
 
-import "fmt"
-
 // NewBundle creates a new transaction bundle
 func NewBundle() *Bundle {
 	return &Bundle{
@@ -12,11 +10,9 @@ func NewBundle() *Bundle {
 }
 
 // AddEntry adds a resource to the bundle
-func (b *Bundle) AddEntry(resourceType, id string, resource interface{}) {
-	entry := BundleEntry{
-		FullURL:  fmt.Sprintf("urn:uuid:%s-%s", resourceType, id),
+func (b *Bundle) AddEntry(resourceType, id string, resource Resource) {
+	b.Entry = append(b.Entry, BundleEntry{
+		FullURL:  "urn:uuid:" + UUIDv5(NamespaceURL, resourceType+"/"+id),
 		Resource: resource,
-	}
-	b.Entry = append(b.Entry, entry)
-
+	})
 }
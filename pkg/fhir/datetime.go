@@ -0,0 +1,59 @@
+package fhir
+
+import "strings"
+
+// FormatDate converts an HL7 date (YYYY[MM[DD]]) to a FHIR date, keeping partial dates partial: YYYY or YYYY-MM
+func FormatDate(hl7Date string) string {
+	switch {
+	case len(hl7Date) >= 8:
+		return hl7Date[0:4] + "-" + hl7Date[4:6] + "-" + hl7Date[6:8]
+	case len(hl7Date) >= 6:
+		return hl7Date[0:4] + "-" + hl7Date[4:6]
+	case len(hl7Date) == 4:
+		return hl7Date
+	default:
+		return ""
+	}
+}
+
+// FormatDateTime converts an HL7 datetime to a FHIR dateTime; values without a time stay dates
+func FormatDateTime(hl7DateTime string) string {
+	if len(hl7DateTime) < 8 {
+		return FormatDate(hl7DateTime)
+	}
+
+	//Basic YYYY-MM-DD
+	result := hl7DateTime[0:4] + "-" + hl7DateTime[4:6] + "-" + hl7DateTime[6:8]
+
+	//add time if present
+	if len(hl7DateTime) >= 12 {
+		result += "T" + hl7DateTime[8:10] + ":" + hl7DateTime[10:12] + ":00"
+	}
+
+	return result
+}
+
+// FormatInstant converts an HL7 datetime with at least minutes to a FHIR instant with seconds. The zone comes from
+// the +/-ZZZZ offset; without one the value is a dateTime without a zone, which a profile timezone completes.
+func FormatInstant(hl7DateTime string) string {
+	value, offset := hl7DateTime, ""
+	if i := strings.IndexAny(value, "+-"); i >= 0 {
+		value, offset = value[:i], value[i:]
+	}
+	if i := strings.Index(value, "."); i >= 0 {
+		value = value[:i]
+	}
+	if len(value) < 12 {
+		return ""
+	}
+
+	seconds := "00"
+	if len(value) >= 14 {
+		seconds = value[12:14]
+	}
+	instant := value[0:4] + "-" + value[4:6] + "-" + value[6:8] + "T" + value[8:10] + ":" + value[10:12] + ":" + seconds
+	if len(offset) == 5 {
+		instant += offset[0:3] + ":" + offset[3:5]
+	}
+	return instant
+}
@@ -0,0 +1,47 @@
+package fhir
+
+import "testing"
+
+func TestFormatDate(t *testing.T) {
+	tests := map[string]string{
+		"19800115":       "1980-01-15",
+		"198001":         "1980-01",
+		"1980":           "1980",
+		"198":            "",
+		"20231115103000": "2023-11-15",
+	}
+	for hl7Date, want := range tests {
+		if got := FormatDate(hl7Date); got != want {
+			t.Errorf("FormatDate(%q) = %q, want %q", hl7Date, got, want)
+		}
+	}
+}
+
+func TestFormatDateTime(t *testing.T) {
+	tests := map[string]string{
+		"20231115103000": "2023-11-15T10:30:00",
+		"202311151030":   "2023-11-15T10:30:00",
+		"20231115":       "2023-11-15",
+		"202311":         "2023-11",
+	}
+	for hl7DateTime, want := range tests {
+		if got := FormatDateTime(hl7DateTime); got != want {
+			t.Errorf("FormatDateTime(%q) = %q, want %q", hl7DateTime, got, want)
+		}
+	}
+}
+
+func TestFormatInstant(t *testing.T) {
+	tests := map[string]string{
+		"20240101120000-0500":    "2024-01-01T12:00:00-05:00",
+		"202401011200+0100":      "2024-01-01T12:00:00+01:00",
+		"20240101120030.1234":    "2024-01-01T12:00:30",
+		"20240101120000.12+0000": "2024-01-01T12:00:00+00:00",
+		"20240101":               "",
+	}
+	for hl7DateTime, want := range tests {
+		if got := FormatInstant(hl7DateTime); got != want {
+			t.Errorf("FormatInstant(%q) = %q, want %q", hl7DateTime, got, want)
+		}
+	}
+}
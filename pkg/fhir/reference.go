@@ -0,0 +1,157 @@
+package fhir
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// relativeReference matches a literal reference to a resource on the same server, Type/id or Type/id/_history/vid
+var relativeReference = regexp.MustCompile(`^[A-Z][A-Za-z]+/[^/?#]+(/_history/[^/?#]+)?$`)
+
+// IsRelativeReference reports whether reference is a relative Type/id reference. Absolute URLs, urns, conditional
+// references (Type?search) and contained references (#id) are not.
+func IsRelativeReference(reference string) bool {
+	return relativeReference.MatchString(reference)
+}
+
+// RewriteReferences replaces every Reference.reference and Attachment.url in a resource using rewrite
+func RewriteReferences(resource Resource, rewrite func(string) string) {
+	walkReferences(reflect.ValueOf(resource), func(reference *string) {
+		*reference = rewrite(*reference)
+	})
+}
+
+// CollectReferences returns every non-empty Reference.reference and Attachment.url in a resource
+func CollectReferences(resource Resource) []string {
+	var references []string
+	walkReferences(reflect.ValueOf(resource), func(reference *string) {
+		references = append(references, *reference)
+	})
+	return references
+}
+
+// RemoveReferences leaves out every Reference.reference and Attachment.url in a resource that remove selects.
+// A Reference with nothing else to say is removed altogether; one with an identifier or display keeps those.
+func RemoveReferences(resource Resource, remove func(string) bool) {
+	removeReferences(reflect.ValueOf(resource), remove)
+}
+
+// UnresolvedReferences lists the relative Type/id references that do not point at an entry of the bundle.
+// Every other reference, such as an absolute URL, a urn or a conditional reference, is resolved by the server.
+func (b *Bundle) UnresolvedReferences() []string {
+	fullURLs := map[string]bool{}
+	for _, entry := range b.Entry {
+		fullURLs[entry.FullURL] = true
+	}
+
+	unresolved := map[string]bool{}
+	for _, entry := range b.Entry {
+		for _, reference := range CollectReferences(entry.Resource) {
+			if fullURLs[reference] || !IsRelativeReference(reference) {
+				continue
+			}
+			unresolved[reference] = true
+		}
+	}
+
+	var references []string
+	for reference := range unresolved {
+		references = append(references, reference)
+	}
+	sort.Strings(references)
+	return references
+}
+
+var (
+	referenceType  = reflect.TypeOf(Reference{})
+	attachmentType = reflect.TypeOf(Attachment{})
+)
+
+// walkReferences visits the reference strings of every Reference and Attachment reachable from value
+func walkReferences(value reflect.Value, visit func(*string)) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			walkReferences(value.Elem(), visit)
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			walkReferences(value.Index(i), visit)
+		}
+	case reflect.Struct:
+		var field reflect.Value
+		switch value.Type() {
+		case referenceType:
+			field = value.FieldByName("Reference")
+		case attachmentType:
+			field = value.FieldByName("URL")
+		}
+		if field.IsValid() && field.String() != "" && field.CanSet() {
+			visit(field.Addr().Interface().(*string))
+		}
+
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				walkReferences(value.Field(i), visit)
+			}
+		}
+	}
+}
+
+// removeReferences clears the selected reference strings reachable from value, dropping empty References
+func removeReferences(value reflect.Value, remove func(string) bool) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return
+		}
+		if value.Elem().Type() == referenceType && value.CanSet() {
+			reference := value.Interface().(*Reference)
+			if remove(reference.Reference) && reference.Identifier == nil && reference.Display == "" {
+				value.Set(reflect.Zero(value.Type()))
+				return
+			}
+		}
+		removeReferences(value.Elem(), remove)
+	case reflect.Interface:
+		if !value.IsNil() {
+			removeReferences(value.Elem(), remove)
+		}
+	case reflect.Slice:
+		if value.Type().Elem() == referenceType && value.CanSet() {
+			kept := reflect.MakeSlice(value.Type(), 0, value.Len())
+			for i := 0; i < value.Len(); i++ {
+				reference := value.Index(i).Interface().(Reference)
+				if remove(reference.Reference) && reference.Identifier == nil && reference.Display == "" {
+					continue
+				}
+				kept = reflect.Append(kept, value.Index(i))
+			}
+			if kept.Len() == 0 {
+				kept = reflect.Zero(value.Type())
+			}
+			value.Set(kept)
+		}
+		for i := 0; i < value.Len(); i++ {
+			removeReferences(value.Index(i), remove)
+		}
+	case reflect.Struct:
+		var field reflect.Value
+		switch value.Type() {
+		case referenceType:
+			field = value.FieldByName("Reference")
+		case attachmentType:
+			field = value.FieldByName("URL")
+		}
+		if field.IsValid() && field.String() != "" && field.CanSet() && remove(field.String()) {
+			field.SetString("")
+		}
+
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				removeReferences(value.Field(i), remove)
+			}
+		}
+	}
+}
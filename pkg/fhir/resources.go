@@ -0,0 +1,184 @@
+package fhir
+
+// Resource is implemented by every resource type this package models. Bundles hold Resources, so the converter
+// reads and assigns ids through these methods and a new type cannot be left out of id assignment or reference
+// rewriting.
+type Resource interface {
+	GetResourceType() string
+	GetID() string
+	SetID(id string)
+	GetIdentifiers() []Identifier // the business identifiers, or nil for types without any
+}
+
+// resourceTypes creates an empty resource for each resource type this package models
+var resourceTypes = map[string]func() Resource{
+	"Account":                  func() Resource { return &Account{} },
+	"AllergyIntolerance":       func() Resource { return &AllergyIntolerance{} },
+	"Appointment":              func() Resource { return &Appointment{} },
+	"Binary":                   func() Resource { return &Binary{} },
+	"ChargeItem":               func() Resource { return &ChargeItem{} },
+	"Condition":                func() Resource { return &Condition{} },
+	"DiagnosticReport":         func() Resource { return &DiagnosticReport{} },
+	"DocumentReference":        func() Resource { return &DocumentReference{} },
+	"Encounter":                func() Resource { return &Encounter{} },
+	"Immunization":             func() Resource { return &Immunization{} },
+	"Location":                 func() Resource { return &Location{} },
+	"Medication":               func() Resource { return &Medication{} },
+	"MedicationAdministration": func() Resource { return &MedicationAdministration{} },
+	"MedicationDispense":       func() Resource { return &MedicationDispense{} },
+	"MedicationRequest":        func() Resource { return &MedicationRequest{} },
+	"MessageHeader":            func() Resource { return &MessageHeader{} },
+	"Observation":              func() Resource { return &Oberservation{} },
+	"Patient":                  func() Resource { return &Patient{} },
+	"Practitioner":             func() Resource { return &Practitioner{} },
+	"Procedure":                func() Resource { return &Procedure{} },
+	"Provenance":               func() Resource { return &Provenance{} },
+	"Schedule":                 func() Resource { return &Schedule{} },
+	"ServiceRequest":           func() Resource { return &ServiceRequest{} },
+	"Slot":                     func() Resource { return &Slot{} },
+	"Specimen":                 func() Resource { return &Specimen{} },
+}
+
+// NewResource returns an empty resource of the given type, or nil when the type is not modelled
+func NewResource(resourceType string) Resource {
+	create, ok := resourceTypes[resourceType]
+	if !ok {
+		return nil
+	}
+	return create()
+}
+
+func (r *Patient) GetResourceType() string      { return r.ResourceType }
+func (r *Patient) GetID() string                { return r.ID }
+func (r *Patient) SetID(id string)              { r.ID = id }
+func (r *Patient) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Encounter) GetResourceType() string      { return r.ResourceType }
+func (r *Encounter) GetID() string                { return r.ID }
+func (r *Encounter) SetID(id string)              { r.ID = id }
+func (r *Encounter) GetIdentifiers() []Identifier { return nil }
+
+func (r *Condition) GetResourceType() string      { return r.ResourceType }
+func (r *Condition) GetID() string                { return r.ID }
+func (r *Condition) SetID(id string)              { r.ID = id }
+func (r *Condition) GetIdentifiers() []Identifier { return nil }
+
+func (r *AllergyIntolerance) GetResourceType() string      { return r.ResourceType }
+func (r *AllergyIntolerance) GetID() string                { return r.ID }
+func (r *AllergyIntolerance) SetID(id string)              { r.ID = id }
+func (r *AllergyIntolerance) GetIdentifiers() []Identifier { return nil }
+
+func (r *Oberservation) GetResourceType() string      { return r.ResourceType }
+func (r *Oberservation) GetID() string                { return r.ID }
+func (r *Oberservation) SetID(id string)              { r.ID = id }
+func (r *Oberservation) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *DiagnosticReport) GetResourceType() string      { return r.ResourceType }
+func (r *DiagnosticReport) GetID() string                { return r.ID }
+func (r *DiagnosticReport) SetID(id string)              { r.ID = id }
+func (r *DiagnosticReport) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Practitioner) GetResourceType() string      { return r.ResourceType }
+func (r *Practitioner) GetID() string                { return r.ID }
+func (r *Practitioner) SetID(id string)              { r.ID = id }
+func (r *Practitioner) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Procedure) GetResourceType() string      { return r.ResourceType }
+func (r *Procedure) GetID() string                { return r.ID }
+func (r *Procedure) SetID(id string)              { r.ID = id }
+func (r *Procedure) GetIdentifiers() []Identifier { return nil }
+
+func (r *Immunization) GetResourceType() string      { return r.ResourceType }
+func (r *Immunization) GetID() string                { return r.ID }
+func (r *Immunization) SetID(id string)              { r.ID = id }
+func (r *Immunization) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Medication) GetResourceType() string      { return r.ResourceType }
+func (r *Medication) GetID() string                { return r.ID }
+func (r *Medication) SetID(id string)              { r.ID = id }
+func (r *Medication) GetIdentifiers() []Identifier { return nil }
+
+func (r *MedicationRequest) GetResourceType() string      { return r.ResourceType }
+func (r *MedicationRequest) GetID() string                { return r.ID }
+func (r *MedicationRequest) SetID(id string)              { r.ID = id }
+func (r *MedicationRequest) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *MedicationDispense) GetResourceType() string      { return r.ResourceType }
+func (r *MedicationDispense) GetID() string                { return r.ID }
+func (r *MedicationDispense) SetID(id string)              { r.ID = id }
+func (r *MedicationDispense) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *MedicationAdministration) GetResourceType() string      { return r.ResourceType }
+func (r *MedicationAdministration) GetID() string                { return r.ID }
+func (r *MedicationAdministration) SetID(id string)              { r.ID = id }
+func (r *MedicationAdministration) GetIdentifiers() []Identifier { return nil }
+
+func (r *ServiceRequest) GetResourceType() string      { return r.ResourceType }
+func (r *ServiceRequest) GetID() string                { return r.ID }
+func (r *ServiceRequest) SetID(id string)              { r.ID = id }
+func (r *ServiceRequest) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Specimen) GetResourceType() string      { return r.ResourceType }
+func (r *Specimen) GetID() string                { return r.ID }
+func (r *Specimen) SetID(id string)              { r.ID = id }
+func (r *Specimen) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Location) GetResourceType() string      { return r.ResourceType }
+func (r *Location) GetID() string                { return r.ID }
+func (r *Location) SetID(id string)              { r.ID = id }
+func (r *Location) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Appointment) GetResourceType() string      { return r.ResourceType }
+func (r *Appointment) GetID() string                { return r.ID }
+func (r *Appointment) SetID(id string)              { r.ID = id }
+func (r *Appointment) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Schedule) GetResourceType() string      { return r.ResourceType }
+func (r *Schedule) GetID() string                { return r.ID }
+func (r *Schedule) SetID(id string)              { r.ID = id }
+func (r *Schedule) GetIdentifiers() []Identifier { return nil }
+
+func (r *Slot) GetResourceType() string      { return r.ResourceType }
+func (r *Slot) GetID() string                { return r.ID }
+func (r *Slot) SetID(id string)              { r.ID = id }
+func (r *Slot) GetIdentifiers() []Identifier { return nil }
+
+func (r *DocumentReference) GetResourceType() string      { return r.ResourceType }
+func (r *DocumentReference) GetID() string                { return r.ID }
+func (r *DocumentReference) SetID(id string)              { r.ID = id }
+func (r *DocumentReference) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *Binary) GetResourceType() string      { return r.ResourceType }
+func (r *Binary) GetID() string                { return r.ID }
+func (r *Binary) SetID(id string)              { r.ID = id }
+func (r *Binary) GetIdentifiers() []Identifier { return nil }
+
+func (r *Account) GetResourceType() string      { return r.ResourceType }
+func (r *Account) GetID() string                { return r.ID }
+func (r *Account) SetID(id string)              { r.ID = id }
+func (r *Account) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *ChargeItem) GetResourceType() string      { return r.ResourceType }
+func (r *ChargeItem) GetID() string                { return r.ID }
+func (r *ChargeItem) SetID(id string)              { r.ID = id }
+func (r *ChargeItem) GetIdentifiers() []Identifier { return r.Identifier }
+
+func (r *MessageHeader) GetResourceType() string      { return r.ResourceType }
+func (r *MessageHeader) GetID() string                { return r.ID }
+func (r *MessageHeader) SetID(id string)              { r.ID = id }
+func (r *MessageHeader) GetIdentifiers() []Identifier { return nil }
+
+func (r *Provenance) GetResourceType() string      { return r.ResourceType }
+func (r *Provenance) GetID() string                { return r.ID }
+func (r *Provenance) SetID(id string)              { r.ID = id }
+func (r *Provenance) GetIdentifiers() []Identifier { return nil }
+
+func (r *OperationOutcome) GetResourceType() string      { return r.ResourceType }
+func (r *OperationOutcome) GetID() string                { return r.ID }
+func (r *OperationOutcome) SetID(id string)              { r.ID = id }
+func (r *OperationOutcome) GetIdentifiers() []Identifier { return nil }
+
+func (r *Parameters) GetResourceType() string      { return r.ResourceType }
+func (r *Parameters) GetID() string                { return r.ID }
+func (r *Parameters) SetID(id string)              { r.ID = id }
+func (r *Parameters) GetIdentifiers() []Identifier { return nil }
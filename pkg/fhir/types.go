@@ -0,0 +1,682 @@
+// Package fhir holds the FHIR R4 resources and data types the converter builds, and helpers for bundles and references.
+package fhir
+
+//Patient represents a FHIR R4 patient resource
+
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id,omitempty"`
+	Identifier   []Identifier   `json:"identifier,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+	Gender       string         `json:"gender,omitempty"`
+	BirthDate    string         `json:"birthDate,omitempty"`
+	Address      []Address      `json:"address,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	Link         []PatientLink  `json:"link,omitempty"`
+	Extension    []Extension    `json:"extension,omitempty"`
+}
+
+//PatientLink links a Patient to another Patient resource for the same person
+
+type PatientLink struct {
+	Other *Reference `json:"other"`
+	Type  string     `json:"type"` // replaced-by, replaces, refer, seealso
+}
+
+//Identifier represents a FHIR Identifier
+
+type Identifier struct {
+	Use      string           `json:"use,omitempty"`
+	Type     *CodeableConcept `json:"type,omitempty"`
+	System   string           `json:"system,omitempty"`
+	Value    string           `json:"value,omitempty"`
+	Assigner *Reference       `json:"assigner,omitempty"` // the assigning authority, e.g. an EI namespace
+}
+
+// Extension carries data that has no element in the base resource, such as Z-segment fields
+type Extension struct {
+	URL                  string           `json:"url"`
+	ValueString          string           `json:"valueString,omitempty"`
+	ValueCode            string           `json:"valueCode,omitempty"`
+	ValueBoolean         *bool            `json:"valueBoolean,omitempty"`
+	ValueInteger         *int             `json:"valueInteger,omitempty"`
+	ValueDateTime        string           `json:"valueDateTime,omitempty"`
+	ValueCoding          *Coding          `json:"valueCoding,omitempty"`
+	ValueCodeableConcept *CodeableConcept `json:"valueCodeableConcept,omitempty"`
+	ValueIdentifier      *Identifier      `json:"valueIdentifier,omitempty"`
+	ValueReference       *Reference       `json:"valueReference,omitempty"`
+	Extension            []Extension      `json:"extension,omitempty"`
+}
+
+//CodeableConcept represents a FHIR CodeableConcept
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+//Coding represents a FHIR coding
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// HumanName respresents a persons name in FHIR
+type HumanName struct {
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+// ContactPoint represents phone/email
+type ContactPoint struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+// Address represents a FHIR address
+type Address struct {
+	Use        string   `json:"use,omitempty"`  // home, work, temp
+	Type       string   `json:"type,omitempty"` // postal, physical
+	Line       []string `json:"line,omitempty"` // Street address lines
+	City       string   `json:"city,omitempty"`
+	State      string   `json:"state,omitempty"`
+	PostalCode string   `json:"postalCode,omitempty"`
+	Country    string   `json:"country,omitempty"`
+}
+
+//Bundle represents a FHIR Bundle
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type BundleEntry struct {
+	FullURL  string               `json:"fullUrl,omitempty"`
+	Resource Resource             `json:"resource,omitempty"`
+	Request  *BundleEntryRequest  `json:"request,omitempty"`
+	Response *BundleEntryResponse `json:"response,omitempty"`
+}
+
+// BundleEntryRequest tells the server how to process a transaction entry
+type BundleEntryRequest struct {
+	Method      string `json:"method"` // GET, POST, PUT, PATCH, DELETE
+	URL         string `json:"url"`
+	IfNoneExist string `json:"ifNoneExist,omitempty"`
+	IfMatch     string `json:"ifMatch,omitempty"`
+}
+
+// BundleEntryResponse is the server's outcome for a transaction entry
+type BundleEntryResponse struct {
+	Status       string `json:"status"`
+	Location     string `json:"location,omitempty"`
+	Etag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+//Encounter represents a FHIR encounter resource
+
+type Encounter struct {
+	ResourceType  string                   `json:"resourceType"`
+	ID            string                   `json:"id,omitempty"`
+	Status        string                   `json:"status"` // planned, arrived, in-progress, finished
+	Class         *Coding                  `json:"class,omitempty"`
+	Type          []CodeableConcept        `json:"type,omitempty"`
+	Subject       *Reference               `json:"subject,omitempty"` // Reference to Patient
+	Participant   []Participant            `json:"participant,omitempty"`
+	Period        *Period                  `json:"period,omitempty"`
+	Account       []Reference              `json:"account,omitempty"`
+	Location      []EncounterLocation      `json:"location,omitempty"`
+	StatusHistory []EncounterStatusHistory `json:"statusHistory,omitempty"`
+	ClassHistory  []EncounterClassHistory  `json:"classHistory,omitempty"`
+	Extension     []Extension              `json:"extension,omitempty"`
+}
+
+// EncounterStatusHistory records a status the encounter has been in
+type EncounterStatusHistory struct {
+	Status string  `json:"status"`
+	Period *Period `json:"period"`
+}
+
+// EncounterClassHistory records a class the encounter has had, e.g. outpatient before admission
+type EncounterClassHistory struct {
+	Class  *Coding `json:"class"`
+	Period *Period `json:"period"`
+}
+
+// Reference is a FHIR reference to another resource
+type Reference struct {
+	Reference  string      `json:"reference,omitempty"`
+	Identifier *Identifier `json:"identifier,omitempty"`
+	Display    string      `json:"display,omitempty"`
+}
+
+// Period represents a time period
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// Participant represents someone involved in the encounter
+type Participant struct {
+	Type       []CodeableConcept `json:"type,omitempty"`
+	Individual *Reference        `json:"individual,omitempty"`
+}
+
+type EncounterLocation struct {
+	Location *Reference `json:"location,omitempty"`
+	Status   string     `json:"status,omitempty"`
+	Period   *Period    `json:"period,omitempty"`
+}
+
+// Condition represents a FHIR condition
+type Condition struct {
+	ResourceType   string           `json:"resourceType"`
+	ID             string           `json:"id,omitempty"`
+	ClinicalStatus *CodeableConcept `json:"clinicalStatus,omitempty"`
+	Code           *CodeableConcept `json:"code,omitempty"`
+	Subject        *Reference       `json:"subject,omitempty"`
+	RecordedDate   string           `json:"recordedDate,omitempty"`
+	Extension      []Extension      `json:"extension,omitempty"`
+}
+
+//AllergyIntolerance represents a FHIR AllergyIntolerance Resource
+
+type AllergyIntolerance struct {
+	ResourceType   string            `json:"resourceType"`
+	ID             string            `json:"id,omitempty"`
+	ClinicalStatus *CodeableConcept  `json:"clinicalStatus,omitempty"`
+	Type           string            `json:"type,omitempty"`     // allergy | intolerance
+	Category       []string          `json:"category,omitempty"` // food | medication | environment
+	Code           *CodeableConcept  `json:"code,omitempty"`
+	Patient        *Reference        `json:"patient,omitempty"`
+	RecordedDate   string            `json:"recordedDate,omitempty"`
+	Reaction       []AllergyReaction `json:"reaction,omitempty"`
+	Extension      []Extension       `json:"extension,omitempty"`
+}
+
+// AllergyReaction represents a reaction to an allergen
+type AllergyReaction struct {
+	Manifestation []CodeableConcept `json:"manifestation,omitempty"`
+}
+
+// Oberservation represents a FHIR Observation resource
+type Oberservation struct {
+	ResourceType      string           `json:"resourceType"`
+	ID                string           `json:"id,omitempty"`
+	Identifier        []Identifier     `json:"identifier,omitempty"`
+	Status            string           `json:"status"` // final, preliminary
+	Code              *CodeableConcept `json:"code,omitempty"`
+	Subject           *Reference       `json:"subject,omitempty"`
+	EffectiveDateTime string           `json:"effectiveDateTime,omitempty"`
+	Issued            string           `json:"issued,omitempty"`
+	ValueQuantity     *Quantity        `json:"valueQuantity,omitempty"`
+	ValueString       string           `json:"valueString,omitempty"`
+	ValueCodeable     *CodeableConcept `json:"valueCodeableConcept,omitempty"`
+	Specimen          *Reference       `json:"specimen,omitempty"`
+	ReferenceRange    []ReferenceRange `json:"referenceRange,omitempty"`
+	Extension         []Extension      `json:"extension,omitempty"`
+}
+
+// Quanity represents a FHIR Quantity
+type Quantity struct {
+	Value      *float64 `json:"value,omitempty"`
+	Comparator string   `json:"comparator,omitempty"` // <, <=, >=, >
+	Unit       string   `json:"unit,omitempty"`
+	System     string   `json:"system,omitempty"`
+	Code       string   `json:"code,omitempty"`
+}
+
+// ReferenceRange represents normal ranges
+type ReferenceRange struct {
+	Text string `json:"text,omitempty"`
+}
+
+// DiagnosticReport represents a diagnostic report
+type DiagnosticReport struct {
+	ResourceType      string           `json:"resourceType"`
+	ID                string           `json:"id,omitempty"`
+	Identifier        []Identifier     `json:"identifier,omitempty"`
+	BasedOn           []Reference      `json:"basedOn,omitempty"`
+	Status            string           `json:"status"` // final, preliminary
+	Code              *CodeableConcept `json:"code,omitempty"`
+	Subject           *Reference       `json:"subject,omitempty"`
+	EffectiveDateTime string           `json:"effectiveDateTime,omitempty"`
+	Issued            string           `json:"issued,omitempty"`
+	Performer         []Reference      `json:"performer,omitempty"`
+	Specimen          []Reference      `json:"specimen,omitempty"`
+	Result            []Reference      `json:"result,omitempty"`
+	Extension         []Extension      `json:"extension,omitempty"`
+}
+
+// Practitioner represents a FHIR Practitioner resource
+type Practitioner struct {
+	ResourceType string       `json:"resourceType"`
+	ID           string       `json:"id,omitempty"`
+	Identifier   []Identifier `json:"identifier,omitempty"`
+	Name         []HumanName  `json:"name,omitempty"`
+	Extension    []Extension  `json:"extension,omitempty"`
+}
+
+// Procedure represents a FHIR Procedure resource
+type Procedure struct {
+	ResourceType      string               `json:"resourceType"`
+	ID                string               `json:"id,omitempty"`
+	Status            string               `json:"status"` // completed, in-progress, not-done
+	Code              *CodeableConcept     `json:"code,omitempty"`
+	Subject           *Reference           `json:"subject,omitempty"`
+	Encounter         *Reference           `json:"encounter,omitempty"`
+	PerformedDateTime string               `json:"performedDateTime,omitempty"`
+	Performer         []ProcedurePerformer `json:"performer,omitempty"`
+	Extension         []Extension          `json:"extension,omitempty"`
+}
+
+// ProcedurePerformer represents who performed a procedure and in what role
+type ProcedurePerformer struct {
+	Function *CodeableConcept `json:"function,omitempty"`
+	Actor    *Reference       `json:"actor"`
+}
+
+// Immunization represents a FHIR Immunization resource
+type Immunization struct {
+	ResourceType       string                  `json:"resourceType"`
+	ID                 string                  `json:"id,omitempty"`
+	Identifier         []Identifier            `json:"identifier,omitempty"`
+	Status             string                  `json:"status"` // completed, entered-in-error, not-done
+	StatusReason       *CodeableConcept        `json:"statusReason,omitempty"`
+	VaccineCode        *CodeableConcept        `json:"vaccineCode,omitempty"`
+	Patient            *Reference              `json:"patient,omitempty"`
+	OccurrenceDateTime string                  `json:"occurrenceDateTime,omitempty"`
+	PrimarySource      *bool                   `json:"primarySource,omitempty"`
+	Manufacturer       *Reference              `json:"manufacturer,omitempty"`
+	LotNumber          string                  `json:"lotNumber,omitempty"`
+	ExpirationDate     string                  `json:"expirationDate,omitempty"`
+	Site               *CodeableConcept        `json:"site,omitempty"`
+	Route              *CodeableConcept        `json:"route,omitempty"`
+	DoseQuantity       *Quantity               `json:"doseQuantity,omitempty"`
+	Performer          []ImmunizationPerformer `json:"performer,omitempty"`
+	IsSubpotent        *bool                   `json:"isSubpotent,omitempty"`
+	ProgramEligibility []CodeableConcept       `json:"programEligibility,omitempty"`
+	FundingSource      *CodeableConcept        `json:"fundingSource,omitempty"`
+	Extension          []Extension             `json:"extension,omitempty"`
+}
+
+// ImmunizationPerformer represents who administered a vaccine
+type ImmunizationPerformer struct {
+	Function *CodeableConcept `json:"function,omitempty"`
+	Actor    *Reference       `json:"actor"`
+}
+
+// Medication represents a FHIR Medication resource
+type Medication struct {
+	ResourceType string           `json:"resourceType"`
+	ID           string           `json:"id,omitempty"`
+	Code         *CodeableConcept `json:"code,omitempty"`
+	Form         *CodeableConcept `json:"form,omitempty"`
+	Extension    []Extension      `json:"extension,omitempty"`
+}
+
+// MedicationRequest represents a FHIR MedicationRequest resource
+type MedicationRequest struct {
+	ResourceType        string                     `json:"resourceType"`
+	ID                  string                     `json:"id,omitempty"`
+	Identifier          []Identifier               `json:"identifier,omitempty"`
+	Status              string                     `json:"status"` // active, on-hold, cancelled, completed, stopped
+	Intent              string                     `json:"intent"` // order
+	MedicationReference *Reference                 `json:"medicationReference,omitempty"`
+	Subject             *Reference                 `json:"subject,omitempty"`
+	AuthoredOn          string                     `json:"authoredOn,omitempty"`
+	Requester           *Reference                 `json:"requester,omitempty"`
+	DosageInstruction   []Dosage                   `json:"dosageInstruction,omitempty"`
+	DispenseRequest     *MedicationDispenseRequest `json:"dispenseRequest,omitempty"`
+	Extension           []Extension                `json:"extension,omitempty"`
+}
+
+// MedicationDispenseRequest represents the dispensing details of a MedicationRequest
+type MedicationDispenseRequest struct {
+	NumberOfRepeatsAllowed int       `json:"numberOfRepeatsAllowed,omitempty"`
+	Quantity               *Quantity `json:"quantity,omitempty"`
+}
+
+// MedicationDispense represents a FHIR MedicationDispense resource
+type MedicationDispense struct {
+	ResourceType            string            `json:"resourceType"`
+	ID                      string            `json:"id,omitempty"`
+	Identifier              []Identifier      `json:"identifier,omitempty"`
+	Status                  string            `json:"status"` // completed, cancelled, stopped, on-hold
+	MedicationReference     *Reference        `json:"medicationReference,omitempty"`
+	Subject                 *Reference        `json:"subject,omitempty"`
+	Performer               []MedicationActor `json:"performer,omitempty"`
+	AuthorizingPrescription []Reference       `json:"authorizingPrescription,omitempty"`
+	Quantity                *Quantity         `json:"quantity,omitempty"`
+	WhenHandedOver          string            `json:"whenHandedOver,omitempty"`
+	DosageInstruction       []Dosage          `json:"dosageInstruction,omitempty"`
+	Extension               []Extension       `json:"extension,omitempty"`
+}
+
+// MedicationAdministration represents a FHIR MedicationAdministration resource
+type MedicationAdministration struct {
+	ResourceType        string                 `json:"resourceType"`
+	ID                  string                 `json:"id,omitempty"`
+	Status              string                 `json:"status"` // completed, not-done, entered-in-error
+	StatusReason        []CodeableConcept      `json:"statusReason,omitempty"`
+	MedicationReference *Reference             `json:"medicationReference,omitempty"`
+	Subject             *Reference             `json:"subject,omitempty"`
+	EffectiveDateTime   string                 `json:"effectiveDateTime,omitempty"`
+	EffectivePeriod     *Period                `json:"effectivePeriod,omitempty"`
+	Performer           []MedicationActor      `json:"performer,omitempty"`
+	Request             *Reference             `json:"request,omitempty"`
+	Dosage              *MedicationAdminDosage `json:"dosage,omitempty"`
+	Extension           []Extension            `json:"extension,omitempty"`
+}
+
+// MedicationActor represents who dispensed or administered a medication
+type MedicationActor struct {
+	Actor *Reference `json:"actor"`
+}
+
+// MedicationAdminDosage represents the dose actually given
+type MedicationAdminDosage struct {
+	Route *CodeableConcept `json:"route,omitempty"`
+	Dose  *Quantity        `json:"dose,omitempty"`
+}
+
+// Dosage represents how a medication should be taken
+type Dosage struct {
+	Text        string           `json:"text,omitempty"`
+	Timing      *Timing          `json:"timing,omitempty"`
+	AsNeeded    bool             `json:"asNeededBoolean,omitempty"`
+	Route       *CodeableConcept `json:"route,omitempty"`
+	DoseAndRate []DoseAndRate    `json:"doseAndRate,omitempty"`
+}
+
+// DoseAndRate represents the amount of medication per dose
+type DoseAndRate struct {
+	DoseQuantity *Quantity `json:"doseQuantity,omitempty"`
+}
+
+// Timing represents when a medication should be given
+type Timing struct {
+	Repeat *TimingRepeat    `json:"repeat,omitempty"`
+	Code   *CodeableConcept `json:"code,omitempty"`
+}
+
+// TimingRepeat represents a repeating schedule like "twice a day"
+type TimingRepeat struct {
+	BoundsPeriod *Period `json:"boundsPeriod,omitempty"`
+	Frequency    int     `json:"frequency,omitempty"`
+	Period       float64 `json:"period,omitempty"`
+	PeriodUnit   string  `json:"periodUnit,omitempty"` // h, d, wk
+}
+
+// ServiceRequest represents a FHIR ServiceRequest (an order for a test or procedure)
+type ServiceRequest struct {
+	ResourceType       string            `json:"resourceType"`
+	ID                 string            `json:"id,omitempty"`
+	Identifier         []Identifier      `json:"identifier,omitempty"`
+	Status             string            `json:"status"`             // active, on-hold, revoked, completed
+	Intent             string            `json:"intent"`             // order, original-order, filler-order
+	Priority           string            `json:"priority,omitempty"` // routine, urgent, asap, stat
+	Code               *CodeableConcept  `json:"code,omitempty"`
+	Subject            *Reference        `json:"subject,omitempty"`
+	OccurrenceDateTime string            `json:"occurrenceDateTime,omitempty"`
+	AuthoredOn         string            `json:"authoredOn,omitempty"`
+	Requester          *Reference        `json:"requester,omitempty"`
+	ReasonCode         []CodeableConcept `json:"reasonCode,omitempty"`
+	Extension          []Extension       `json:"extension,omitempty"`
+}
+
+// Specimen represents a FHIR Specimen resource
+type Specimen struct {
+	ResourceType        string              `json:"resourceType"`
+	ID                  string              `json:"id,omitempty"`
+	Identifier          []Identifier        `json:"identifier,omitempty"`
+	AccessionIdentifier *Identifier         `json:"accessionIdentifier,omitempty"`
+	Type                *CodeableConcept    `json:"type,omitempty"`
+	Subject             *Reference          `json:"subject,omitempty"`
+	ReceivedTime        string              `json:"receivedTime,omitempty"`
+	Request             []Reference         `json:"request,omitempty"`
+	Collection          *SpecimenCollection `json:"collection,omitempty"`
+	Extension           []Extension         `json:"extension,omitempty"`
+}
+
+// SpecimenCollection represents how and when a specimen was collected
+type SpecimenCollection struct {
+	CollectedDateTime string           `json:"collectedDateTime,omitempty"`
+	CollectedPeriod   *Period          `json:"collectedPeriod,omitempty"`
+	Method            *CodeableConcept `json:"method,omitempty"`
+	BodySite          *CodeableConcept `json:"bodySite,omitempty"`
+}
+
+// Location represents a FHIR Location resource
+type Location struct {
+	ResourceType string            `json:"resourceType"`
+	ID           string            `json:"id,omitempty"`
+	Identifier   []Identifier      `json:"identifier,omitempty"`
+	Status       string            `json:"status,omitempty"` // active, suspended, inactive
+	Name         string            `json:"name,omitempty"`
+	Mode         string            `json:"mode,omitempty"` // instance, kind
+	Type         []CodeableConcept `json:"type,omitempty"`
+	Extension    []Extension       `json:"extension,omitempty"`
+}
+
+// Appointment represents a FHIR Appointment resource
+type Appointment struct {
+	ResourceType    string                   `json:"resourceType"`
+	ID              string                   `json:"id,omitempty"`
+	Identifier      []Identifier             `json:"identifier,omitempty"`
+	Status          string                   `json:"status"` // proposed, pending, booked, arrived, fulfilled, cancelled, noshow, entered-in-error
+	ServiceType     []CodeableConcept        `json:"serviceType,omitempty"`
+	AppointmentType *CodeableConcept         `json:"appointmentType,omitempty"`
+	ReasonCode      []CodeableConcept        `json:"reasonCode,omitempty"`
+	Start           string                   `json:"start,omitempty"`
+	End             string                   `json:"end,omitempty"`
+	MinutesDuration int                      `json:"minutesDuration,omitempty"`
+	Slot            []Reference              `json:"slot,omitempty"`
+	Participant     []AppointmentParticipant `json:"participant"`
+	Extension       []Extension              `json:"extension,omitempty"`
+}
+
+// AppointmentParticipant represents a person, location or resource taking part in an appointment
+type AppointmentParticipant struct {
+	Type   []CodeableConcept `json:"type,omitempty"`
+	Actor  *Reference        `json:"actor,omitempty"`
+	Status string            `json:"status"` // accepted, declined, tentative, needs-action
+}
+
+// Schedule represents a FHIR Schedule resource
+type Schedule struct {
+	ResourceType string      `json:"resourceType"`
+	ID           string      `json:"id,omitempty"`
+	Active       bool        `json:"active"`
+	Actor        []Reference `json:"actor"`
+	Extension    []Extension `json:"extension,omitempty"`
+}
+
+// Slot represents a FHIR Slot resource
+type Slot struct {
+	ResourceType string      `json:"resourceType"`
+	ID           string      `json:"id,omitempty"`
+	Schedule     *Reference  `json:"schedule"`
+	Status       string      `json:"status"` // busy, free
+	Start        string      `json:"start"`
+	End          string      `json:"end"`
+	Extension    []Extension `json:"extension,omitempty"`
+}
+
+// DocumentReference represents a FHIR DocumentReference resource
+type DocumentReference struct {
+	ResourceType     string                     `json:"resourceType"`
+	ID               string                     `json:"id,omitempty"`
+	MasterIdentifier *Identifier                `json:"masterIdentifier,omitempty"`
+	Identifier       []Identifier               `json:"identifier,omitempty"`
+	Status           string                     `json:"status"`              // current, superseded, entered-in-error
+	DocStatus        string                     `json:"docStatus,omitempty"` // preliminary, final, amended
+	Type             *CodeableConcept           `json:"type,omitempty"`
+	Subject          *Reference                 `json:"subject,omitempty"`
+	Date             string                     `json:"date,omitempty"`
+	Author           []Reference                `json:"author,omitempty"`
+	Authenticator    *Reference                 `json:"authenticator,omitempty"`
+	Content          []DocumentReferenceContent `json:"content"`
+	Context          *DocumentReferenceContext  `json:"context,omitempty"`
+	Extension        []Extension                `json:"extension,omitempty"`
+}
+
+// DocumentReferenceContent holds one attachment of a document
+type DocumentReferenceContent struct {
+	Attachment Attachment `json:"attachment"`
+}
+
+// DocumentReferenceContext links a document to the encounter it was written in
+type DocumentReferenceContext struct {
+	Encounter []Reference `json:"encounter,omitempty"`
+}
+
+// Attachment represents inline data or a link to a Binary
+type Attachment struct {
+	ContentType string `json:"contentType,omitempty"`
+	Data        string `json:"data,omitempty"` // base64
+	URL         string `json:"url,omitempty"`
+	Size        int    `json:"size,omitempty"`
+	Hash        string `json:"hash,omitempty"` // base64 SHA-1
+	Title       string `json:"title,omitempty"`
+}
+
+// Binary represents a FHIR Binary resource holding raw document content
+type Binary struct {
+	ResourceType string `json:"resourceType"`
+	ID           string `json:"id,omitempty"`
+	ContentType  string `json:"contentType"`
+	Data         string `json:"data,omitempty"` // base64
+}
+
+// Account represents a FHIR Account resource (the patient account number)
+type Account struct {
+	ResourceType string       `json:"resourceType"`
+	ID           string       `json:"id,omitempty"`
+	Identifier   []Identifier `json:"identifier,omitempty"`
+	Status       string       `json:"status"` // active, inactive
+	Subject      []Reference  `json:"subject,omitempty"`
+	Extension    []Extension  `json:"extension,omitempty"`
+}
+
+// ChargeItem represents a FHIR ChargeItem resource
+type ChargeItem struct {
+	ResourceType       string                `json:"resourceType"`
+	ID                 string                `json:"id,omitempty"`
+	Identifier         []Identifier          `json:"identifier,omitempty"`
+	Status             string                `json:"status"` // billable, aborted
+	Code               *CodeableConcept      `json:"code,omitempty"`
+	Subject            *Reference            `json:"subject,omitempty"`
+	Context            *Reference            `json:"context,omitempty"`
+	OccurrenceDateTime string                `json:"occurrenceDateTime,omitempty"`
+	OccurrencePeriod   *Period               `json:"occurrencePeriod,omitempty"`
+	Performer          []ChargeItemPerformer `json:"performer,omitempty"`
+	Quantity           *Quantity             `json:"quantity,omitempty"`
+	PriceOverride      *Money                `json:"priceOverride,omitempty"`
+	EnteredDate        string                `json:"enteredDate,omitempty"`
+	Account            []Reference           `json:"account,omitempty"`
+	Extension          []Extension           `json:"extension,omitempty"`
+}
+
+// ChargeItemPerformer represents who performed the charged service
+type ChargeItemPerformer struct {
+	Function *CodeableConcept `json:"function,omitempty"`
+	Actor    *Reference       `json:"actor"`
+}
+
+// Money represents an amount of money
+type Money struct {
+	Value    float64 `json:"value"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// MessageHeader represents a FHIR MessageHeader resource
+type MessageHeader struct {
+	ResourceType string               `json:"resourceType"`
+	ID           string               `json:"id,omitempty"`
+	EventCoding  *Coding              `json:"eventCoding,omitempty"`
+	Destination  []MessageDestination `json:"destination,omitempty"`
+	Source       *MessageSource       `json:"source"`
+	Focus        []Reference          `json:"focus,omitempty"`
+	Extension    []Extension          `json:"extension,omitempty"`
+}
+
+// MessageDestination represents the receiving application of a message
+type MessageDestination struct {
+	Name     string `json:"name,omitempty"`
+	Endpoint string `json:"endpoint"`
+}
+
+// MessageSource represents the sending application of a message
+type MessageSource struct {
+	Name     string `json:"name,omitempty"`
+	Software string `json:"software,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Endpoint string `json:"endpoint"`
+}
+
+// Provenance represents a FHIR Provenance resource
+type Provenance struct {
+	ResourceType     string             `json:"resourceType"`
+	ID               string             `json:"id,omitempty"`
+	Target           []Reference        `json:"target"`
+	OccurredDateTime string             `json:"occurredDateTime,omitempty"`
+	Recorded         string             `json:"recorded"`
+	Activity         *CodeableConcept   `json:"activity,omitempty"`
+	Agent            []ProvenanceAgent  `json:"agent"`
+	Entity           []ProvenanceEntity `json:"entity,omitempty"`
+	Extension        []Extension        `json:"extension,omitempty"`
+}
+
+// ProvenanceAgent represents who took part in the activity
+type ProvenanceAgent struct {
+	Type       *CodeableConcept `json:"type,omitempty"`
+	Who        *Reference       `json:"who"`
+	OnBehalfOf *Reference       `json:"onBehalfOf,omitempty"`
+}
+
+// ProvenanceEntity represents an input to the activity
+type ProvenanceEntity struct {
+	Role string     `json:"role"` // derivation, revision, quotation, source, removal
+	What *Reference `json:"what"`
+}
+
+// OperationOutcome reports the issues found while processing a request
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	ID           string                  `json:"id,omitempty"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+	Extension    []Extension             `json:"extension,omitempty"`
+}
+
+// OperationOutcomeIssue is a single error, warning or information message
+type OperationOutcomeIssue struct {
+	Severity    string   `json:"severity"` // fatal, error, warning, information
+	Code        string   `json:"code"`
+	Diagnostics string   `json:"diagnostics,omitempty"`
+	Location    []string `json:"location,omitempty"`
+}
+
+// Parameters carries operation parameters, such as the operations of a FHIRPath Patch
+type Parameters struct {
+	ResourceType string                `json:"resourceType"`
+	ID           string                `json:"id,omitempty"`
+	Parameter    []ParametersParameter `json:"parameter,omitempty"`
+}
+
+// ParametersParameter is one named value, or a group of parts
+type ParametersParameter struct {
+	Name           string                `json:"name"`
+	ValueCode      string                `json:"valueCode,omitempty"`
+	ValueString    string                `json:"valueString,omitempty"`
+	ValueBoolean   *bool                 `json:"valueBoolean,omitempty"`
+	ValueReference *Reference            `json:"valueReference,omitempty"`
+	Part           []ParametersParameter `json:"part,omitempty"`
+}
@@ -0,0 +1,24 @@
+package fhir
+
+import (
+	"crypto/sha1"
+	"fmt"
+)
+
+// NamespaceURL is the RFC 4122 namespace for names that are URLs
+var NamespaceURL = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// UUIDv5 returns the name-based (SHA-1) UUID for name within namespace
+func UUIDv5(namespace [16]byte, name string) string {
+	hash := sha1.New()
+	hash.Write(namespace[:])
+	hash.Write([]byte(name))
+	sum := hash.Sum(nil)
+
+	var uuid [16]byte
+	copy(uuid[:], sum[:16])
+	uuid[6] = (uuid[6] & 0x0f) | 0x50 // version 5
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
@@ -0,0 +1,139 @@
+package hl7
+
+// GetSegment returns the first segment with the given name
+func (m *Message) GetSegment(name string) *Segment {
+	for i := range m.Segments {
+		if m.Segments[i].Name == name {
+			return &m.Segments[i]
+		}
+	}
+	return nil
+}
+
+// GetField returns the field at the given index
+
+func (s *Segment) GetField(index int) *Field {
+	actualIndex := index - 1
+	if actualIndex < 0 || actualIndex >= len(s.Fields) {
+		return nil
+	}
+	return &s.Fields[actualIndex]
+}
+
+//GetRepetition returns a specific repetition
+
+func (f *Field) GetRepetition(index int) *Repetition {
+	actualIndex := index - 1
+	if actualIndex < 0 || actualIndex >= len(f.Repetitions) {
+		return nil
+	}
+	return &f.Repetitions[actualIndex]
+}
+
+// GetCompontent returns the component at the given index
+func (f *Field) GetCompontent(index int) string {
+	if f == nil || len(f.Repetitions) == 0 {
+		return ""
+	}
+	return f.Repetitions[0].GetCompontent(index)
+
+}
+
+func (r *Repetition) GetCompontent(index int) string {
+	actualIndex := index - 1
+	if actualIndex < 0 || actualIndex >= len(r.Components) {
+		return ""
+	}
+	if len(r.Components[actualIndex].Subcomponents) > 0 {
+		return r.Components[actualIndex].Subcomponents[0]
+	}
+	return ""
+}
+
+func (c *Component) GetCompontent(index int) string {
+	actualIndex := index - 1
+	if actualIndex < 0 || actualIndex >= len(c.Subcomponents) {
+		return ""
+	}
+	return c.Subcomponents[actualIndex]
+}
+
+// GetSubcomponent returns a subcomponent of the first repetition, such as the code in OBR-15.1.1
+func (f *Field) GetSubcomponent(component, subcomponent int) string {
+	if f == nil || len(f.Repetitions) == 0 {
+		return ""
+	}
+
+	actualIndex := component - 1
+	if actualIndex < 0 || actualIndex >= len(f.Repetitions[0].Components) {
+		return ""
+	}
+	return f.Repetitions[0].Components[actualIndex].GetCompontent(subcomponent)
+}
+
+// GetSegments returns all segments with a given name
+func (m *Message) GetSegments(name string) []*Segment {
+	var segments []*Segment
+	for i := range m.Segments {
+		if m.Segments[i].Name == name {
+			segments = append(segments, &m.Segments[i])
+		}
+	}
+	return segments
+}
+
+// SegmentGroup is a segment together with the segments that follow it, such as an OBR and its OBXs
+type SegmentGroup struct {
+	Head     *Segment
+	Segments []*Segment
+}
+
+// GetSegment returns the first segment in the group with the given name
+func (g *SegmentGroup) GetSegment(name string) *Segment {
+	if g.Head != nil && g.Head.Name == name {
+		return g.Head
+	}
+	for _, seg := range g.Segments {
+		if seg.Name == name {
+			return seg
+		}
+	}
+	return nil
+}
+
+// GetSegments returns all segments in the group with the given name
+func (g *SegmentGroup) GetSegments(name string) []*Segment {
+	var segments []*Segment
+	for _, seg := range g.Segments {
+		if seg.Name == name {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
+// GetGroups splits the message into groups that start at each head segment.
+// Member segments are collected into the current group, other segments are skipped.
+func (m *Message) GetGroups(head string, members ...string) []*SegmentGroup {
+	var groups []*SegmentGroup
+	var current *SegmentGroup
+
+	for i := range m.Segments {
+		seg := &m.Segments[i]
+		if seg.Name == head {
+			current = &SegmentGroup{Head: seg}
+			groups = append(groups, current)
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		for _, name := range members {
+			if seg.Name == name {
+				current.Segments = append(current.Segments, seg)
+				break
+			}
+		}
+	}
+	return groups
+}
@@ -0,0 +1,23 @@
+package hl7_test
+
+import (
+	"fmt"
+
+	"github.com/mourice12/hl7-to-fhir/pkg/hl7"
+)
+
+func ExampleParse() {
+	msg, err := hl7.Parse("MSH|^~\\&|EPIC|HOSP|EHR|HOSP|20231115120000||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^MRN~67890^^^SSN||Doe^John||19800115|M")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	pid := msg.GetSegment("PID")
+	fmt.Println(pid.GetField(5).GetCompontent(1))
+	fmt.Println(pid.GetField(3).GetRepetition(2).GetCompontent(4))
+	// Output:
+	// Doe
+	// SSN
+}
@@ -0,0 +1,253 @@
+// Package hl7 parses HL7 v2.x messages in ER7 encoding into segments, fields, repetitions and components.
+package hl7
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTooLarge is returned when a message exceeds one of the parse Limits
+var ErrTooLarge = errors.New("message too large")
+
+// ErrTimeout is returned when the context's deadline passes before parsing or converting finishes
+var ErrTimeout = errors.New("timed out")
+
+// Limits bound the messages Parse accepts; zero means no limit
+type Limits struct {
+	MaxMessageSize int // bytes
+	MaxSegments    int
+	MaxFieldLength int // bytes of one field, with all its repetitions
+	MaxRepetitions int // repetitions of one field
+}
+
+// DefaultLimits returns limits suited to a server: large enough for embedded documents, small enough
+// that one message cannot exhaust it
+func DefaultLimits() Limits {
+	return Limits{
+		MaxMessageSize: 16 << 20,
+		MaxSegments:    10000,
+		MaxFieldLength: 8 << 20,
+		MaxRepetitions: 1000,
+	}
+}
+
+// segmentsPerCheck is how many segments are parsed between checks of the context
+const segmentsPerCheck = 100
+
+// Delimiters hold the HL7 separator characters
+type Delimiters struct {
+	Field        string // |
+	Component    string // ^
+	Repetition   string // ~
+	Escape       string // \
+	Subcomponent string // &
+}
+
+//DefaultDelimiters returns standard HL7 delimiters
+
+func DefaultDelimiters() Delimiters {
+	return Delimiters{
+		Field:        "|",
+		Component:    "^",
+		Repetition:   "~",
+		Escape:       "\\",
+		Subcomponent: "&",
+	}
+}
+
+// messageDelimiters reads MSH-1 Field Separator and MSH-2 Encoding Characters (component, repetition, escape and
+// subcomponent), keeping the standard delimiter for any the header leaves out
+func messageDelimiters(msh string) Delimiters {
+	delimiters := DefaultDelimiters()
+	if len(msh) < 4 {
+		return delimiters
+	}
+	delimiters.Field = msh[3:4]
+
+	encoding := msh[4:]
+	if i := strings.Index(encoding, delimiters.Field); i >= 0 {
+		encoding = encoding[:i]
+	}
+	for i, separator := range []*string{&delimiters.Component, &delimiters.Repetition, &delimiters.Escape, &delimiters.Subcomponent} {
+		if i < len(encoding) {
+			*separator = encoding[i : i+1]
+		}
+	}
+	return delimiters
+}
+
+// Parse takes a raw HL7 message string and returns a message struct
+func Parse(raw string) (*Message, error) {
+	return ParseContext(context.Background(), raw, Limits{})
+}
+
+// ParseContext parses like Parse, failing with ErrTooLarge when the message exceeds limits and with
+// ErrTimeout or the context's error when ctx is done first
+func ParseContext(ctx context.Context, raw string, limits Limits) (*Message, error) {
+	if limits.MaxMessageSize > 0 && len(raw) > limits.MaxMessageSize {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, len(raw), limits.MaxMessageSize)
+	}
+	if err := ContextError(ctx); err != nil {
+		return nil, err
+	}
+	original := raw
+
+	//normaline line endings
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	raw = strings.ReplaceAll(raw, "\r", "\n")
+
+	lines := strings.Split(raw, "\n")
+	if len(lines) == 0 {
+		return nil, errors.New("empty message")
+	}
+	if !strings.HasPrefix(lines[0], "MSH") {
+		return nil, errors.New("message must start with MSH segment")
+	}
+
+	delimiters := messageDelimiters(lines[0])
+	message := &Message{Raw: original, Delimiters: delimiters}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if i%segmentsPerCheck == 0 {
+			if err := ContextError(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if limits.MaxSegments > 0 && len(message.Segments) >= limits.MaxSegments {
+			return nil, fmt.Errorf("%w: more than %d segments", ErrTooLarge, limits.MaxSegments)
+		}
+
+		segment, err := parseSegment(line, delimiters, limits)
+		if err != nil {
+			return nil, err
+		}
+		message.Segments = append(message.Segments, segment)
+	}
+	return message, nil
+}
+
+// ContextError returns nil while ctx is live, ErrTimeout wrapping the context's error once its deadline has
+// passed, and the context's error when it was cancelled
+func ContextError(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
+// parseSegment parses a single line
+func parseSegment(line string, delim Delimiters, limits Limits) (Segment, error) {
+	parts := strings.Split(line, delim.Field)
+
+	segmentName := parts[0]
+	segment := Segment{
+		Name:   segmentName,
+		Fields: []Field{},
+	}
+
+	//MSH is special: MSH-1 is the field separator itself
+	if segmentName == "MSH" {
+		//MSH-1 = field separator "|"
+		segment.Fields = append(segment.Fields, Field{
+			Repetitions: []Repetition{{
+				Components: []Component{{
+					Subcomponents: []string{delim.Field},
+				}},
+			}},
+		})
+		//MSH-2 = the encoding characters, kept as they are
+		if len(parts) > 1 {
+			segment.Fields = append(segment.Fields, Field{
+				Repetitions: []Repetition{{
+					Components: []Component{{
+						Subcomponents: []string{parts[1]},
+					}},
+				}},
+			})
+		}
+		//MSH-3 onwards = rest of fields starting at parts[2]
+		for i := 2; i < len(parts); i++ {
+			field, err := parseField(parts[i], delim, limits)
+			if err != nil {
+				return segment, fmt.Errorf("%s-%d: %w", segmentName, i+1, err)
+			}
+			segment.Fields = append(segment.Fields, field)
+		}
+	} else {
+		for i := 1; i < len(parts); i++ {
+			field, err := parseField(parts[i], delim, limits)
+			if err != nil {
+				return segment, fmt.Errorf("%s-%d: %w", segmentName, i, err)
+			}
+			segment.Fields = append(segment.Fields, field)
+		}
+
+	}
+	return segment, nil
+}
+
+func parseField(fieldStr string, delim Delimiters, limits Limits) (Field, error) {
+	field := Field{
+		Repetitions: []Repetition{},
+	}
+	if limits.MaxFieldLength > 0 && len(fieldStr) > limits.MaxFieldLength {
+		return field, fmt.Errorf("%w: field of %d bytes, limit %d", ErrTooLarge, len(fieldStr), limits.MaxFieldLength)
+	}
+
+	//Splits repeition delimiter
+	repParts := strings.Split(fieldStr, delim.Repetition)
+	if limits.MaxRepetitions > 0 && len(repParts) > limits.MaxRepetitions {
+		return field, fmt.Errorf("%w: %d repetitions, limit %d", ErrTooLarge, len(repParts), limits.MaxRepetitions)
+	}
+
+	for _, repStr := range repParts {
+		rep := Repetition{
+			Components: []Component{},
+		}
+
+		//Split the component delimiter
+		compParts := strings.Split(repStr, delim.Component)
+
+		for _, compStr := range compParts {
+			//split by subcomponent delim
+			subParts := strings.Split(compStr, delim.Subcomponent)
+
+			comp := Component{
+				Subcomponents: subParts,
+			}
+			rep.Components = append(rep.Components, comp)
+		}
+		field.Repetitions = append(field.Repetitions, rep)
+	}
+
+	return field, nil
+}
+
+// Unescape replaces HL7 escape sequences (\F\, \S\, \T\, \R\, \E\ and \.br\) with the characters they stand for;
+// the zero Delimiters, as in a Message built without Parse, stand for the standard ones
+func Unescape(value string, delim Delimiters) string {
+	if delim == (Delimiters{}) {
+		delim = DefaultDelimiters()
+	}
+	if !strings.Contains(value, delim.Escape) {
+		return value
+	}
+
+	e := delim.Escape
+	replacer := strings.NewReplacer(
+		e+"F"+e, delim.Field,
+		e+"S"+e, delim.Component,
+		e+"T"+e, delim.Subcomponent,
+		e+"R"+e, delim.Repetition,
+		e+"E"+e, delim.Escape,
+		e+".br"+e, "\n",
+	)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,135 @@
+package hl7
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_BasicMessage(t *testing.T) {
+	raw := `MSH|^~\&|EPIC|FAC1|CERNER|FAC2|20231115||ADT^A01|MSG001|P|2.5
+PID|1||12345^^^MRN||Doe^John||19800115|M`
+
+	msg, err := Parse(raw)
+
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+
+	}
+
+	if len(msg.Segments) != 2 {
+		t.Errorf("Expected 2 segments, got %d", len(msg.Segments))
+	}
+
+	if msg.Segments[0].Name != "MSH" {
+		t.Errorf("Expected first segment MSH, got %s", msg.Segments[0].Name)
+	}
+
+	if msg.Segments[1].Name != "PID" {
+		t.Errorf("Expected second segment PID, got %s", msg.Segments[1].Name)
+	}
+
+}
+
+func TestParse_Repetitions(t *testing.T) {
+	raw := `MSH|^~\&|APP|||FAC|20231115||ADT^A01|1|P|2.5
+PID|1||123||Doe^John||19800115|M|||123 Main||555-1234~555-5678`
+
+	msg, err := Parse(raw)
+
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	pid := msg.GetSegment("PID")
+	phoneField := pid.GetField(13)
+
+	if len(phoneField.Repetitions) != 2 {
+		t.Errorf("Expected 2 phone repetitions, got %d", len(phoneField.Repetitions))
+	}
+}
+
+func TestParse_EmptyMessage(t *testing.T) {
+	_, err := Parse("")
+
+	if err == nil {
+		t.Error("Expected error for empty message, got nil")
+	}
+}
+
+func TestParse_InvalidStart(t *testing.T) {
+	_, err := Parse("PID|1||12345")
+
+	if err == nil {
+		t.Error(("Expected error for message not starting with MSH"))
+	}
+}
+
+func TestParseContext_Limits(t *testing.T) {
+	raw := "MSH|^~\\&|EPIC|HOSP|EHR|HOSP|20231115120000||ADT^A01|MSG001|P|2.5\r" +
+		"PID|1||1^^^MRN~2^^^MRN~3^^^MRN||Doe^John\r" +
+		"NTE|1||" + strings.Repeat("x", 200)
+
+	tests := []struct {
+		name   string
+		limits Limits
+		want   string
+	}{
+		{"size", Limits{MaxMessageSize: 100}, "bytes, limit 100"},
+		{"segments", Limits{MaxSegments: 2}, "more than 2 segments"},
+		{"field length", Limits{MaxFieldLength: 100}, "NTE-3"},
+		{"repetitions", Limits{MaxRepetitions: 2}, "PID-3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseContext(context.Background(), raw, tt.limits)
+			if !errors.Is(err, ErrTooLarge) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected ErrTooLarge mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := ParseContext(context.Background(), raw, DefaultLimits()); err != nil {
+		t.Errorf("Expected the message to fit the default limits, got %v", err)
+	}
+}
+
+func TestParseContext_Deadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err := ParseContext(ctx, "MSH|^~\\&|EPIC|HOSP|EHR|HOSP|20231115120000||ADT^A01|MSG001|P|2.5", Limits{})
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestParse_MessageDelimiters(t *testing.T) {
+	//Component and subcomponent separators swapped, and # as the escape character
+	msg, err := Parse("MSH|&~#^|LAB|FAC1|EHR|FAC2|20231115||ORU&R01|MSG001|P|2.5\r" +
+		"OBX|1|TX|NOTE||Line #F# one&two^three")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	want := Delimiters{Field: "|", Component: "&", Repetition: "~", Escape: "#", Subcomponent: "^"}
+	if msg.Delimiters != want {
+		t.Errorf("Expected delimiters %+v, got %+v", want, msg.Delimiters)
+	}
+	if got := msg.GetSegment("MSH").GetField(2).GetCompontent(1); got != "&~#^" {
+		t.Errorf("Expected MSH-2 to be kept as is, got %q", got)
+	}
+	if got := msg.GetSegment("MSH").GetField(9).GetCompontent(2); got != "R01" {
+		t.Errorf("Expected MSH-9.2 R01, got %q", got)
+	}
+
+	obx := msg.GetSegment("OBX")
+	if got := Unescape(obx.GetField(5).GetCompontent(1), msg.Delimiters); got != "Line | one" {
+		t.Errorf("Expected the escaped field separator to be unescaped, got %q", got)
+	}
+	if got := obx.GetField(5).GetSubcomponent(2, 2); got != "three" {
+		t.Errorf("Expected subcomponent three, got %q", got)
+	}
+}
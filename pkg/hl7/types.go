@@ -2,7 +2,9 @@ package hl7
 
 // Message represents a HL7 message
 type Message struct {
-	Segments []Segment
+	Segments   []Segment
+	Raw        string     // the message as received, in ER7 encoding
+	Delimiters Delimiters // the separators declared in MSH-1 and MSH-2, for Unescape
 }
 
 // Segments respresents a single line like MSH, PID, etc.